@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flagInfo describes a flag of the root command in the output of --list-flags
+type flagInfo struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default"`
+	Usage     string `json:"usage"`
+}
+
+// printFlagList writes every flag of the root command, including the kubectl client flags, with its type and
+// default, as a table or as JSON with -o json, so that wrappers and shell integrations can discover them.
+// It is a flag rather than a subcommand, so that a pod can still be named list-flags.
+func printFlagList(rootCmd *cobra.Command, output string) error {
+	var flags []flagInfo
+	rootCmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		flags = append(flags, flagInfo{
+			Name:      f.Name,
+			Shorthand: f.Shorthand,
+			Type:      f.Value.Type(),
+			Default:   f.DefValue,
+			Usage:     f.Usage,
+		})
+	})
+	switch output {
+	case "text":
+		return printFlags(rootCmd.OutOrStdout(), flags)
+	case "json":
+		encoder := json.NewEncoder(rootCmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(flags)
+	default:
+		return fmt.Errorf("--list-flags can only be used with -o text or json, not %q", output)
+	}
+}
+
+// printFlags writes a table of the flags
+func printFlags(out io.Writer, flags []flagInfo) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSHORTHAND\tTYPE\tDEFAULT")
+	for _, f := range flags {
+		shorthand := "-"
+		if f.Shorthand != "" {
+			shorthand = "-" + f.Shorthand
+		}
+		fmt.Fprintf(w, "--%s\t%s\t%s\t%s\n", f.Name, shorthand, f.Type, f.Default)
+	}
+	return w.Flush()
+}
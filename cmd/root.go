@@ -1,42 +1,151 @@
 package cmd
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/tae2089/kubectl-like/pkg/kubernetes"
 	kube "github.com/tae2089/kubectl-like/pkg/kubernetes"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
 )
 
+const (
+	// defaultConfigFile is read from the home directory when --config is not given
+	defaultConfigFile = ".kubectl-like.yaml"
+	// envPrefix prefixes the environment variables setting flags, e.g. KUBECTL_LIKE_PATTERN for --pattern
+	envPrefix = "KUBECTL_LIKE"
+	// shortEnvPrefix is a shorter alias of envPrefix, e.g. KL_PATTERN for --pattern
+	shortEnvPrefix = "KL"
+)
+
+var likeExample = templates.Examples(`
+	# Print the lines of pod nginx matching error
+	kubectl like nginx --pattern error
+
+	# Follow the matching lines of every pod of a deployment
+	kubectl like deployments/api --all-pods -f --pattern error
+
+	# Print the matching lines of a job, or of the latest job of a cronjob, also when it has completed
+	kubectl like jobs/migrate --pattern error
+	kubectl like cronjobs/nightly-backup --all-pods --pattern error`)
+
 func CreateRootCmd() *cobra.Command {
+	var configFile string
+	var listFlags, doctor bool
 	ioStreams := genericiooptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
 	l := kube.NewLikeOptions(ioStreams)
 	rootCmd := &cobra.Command{
-		Use:                   "kubectl like [-f] [-p] (POD | TYPE/NAME) --pattern [-c CONTAINER] [options]",
+		Use:                   "kubectl like [-f] [-p] (POD | TYPE/NAME)... --pattern [-c CONTAINER] [options]",
 		Short:                 "logging pods using regex pattern",
 		Long:                  "logging pods using regex pattern",
+		Example:               likeExample,
 		DisableFlagsInUseLine: true,
 		SilenceErrors:         true,
 		SilenceUsage:          true,
+		// the arguments are pods or objects, not subcommands
+		Args: cobra.ArbitraryArgs,
 		PreRun: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(initConfig(configFile))
 			viper.BindPFlags(cmd.Flags())
+			cmdutil.CheckErr(bindShortEnv(cmd))
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if l.ListPresets {
+				cmdutil.CheckErr(l.PrintPresets())
+				return nil
+			}
+			if listFlags {
+				cmdutil.CheckErr(printFlagList(cmd, l.Output))
+				return nil
+			}
+			if doctor {
+				cmdutil.CheckErr(l.Doctor())
+				return nil
+			}
+
+			// the first Ctrl-C stops the streams, then the output is flushed and the summaries are printed
+			// and an interrupted command that does not end in time exits like a command killed by SIGINT
+			ctx, stop := kube.NotifyInterrupt(cmd.Context(), func() { os.Exit(130) })
+			defer stop()
+			cmd.SetContext(ctx)
+			// a closed stdout, e.g. piped to head, stops the streams instead of killing the command
+			kube.IgnoreBrokenPipe()
 
 			cmdutil.CheckErr(l.Complete(args, cmd))
 			cmdutil.CheckErr(l.Vaildate())
-			cmdutil.CheckErr(l.Run())
+			err := l.Run()
+			if errors.Is(err, kube.ErrInterrupted) {
+				// like a command killed by SIGINT
+				os.Exit(130)
+			}
+			if errors.Is(err, kube.ErrBrokenPipe) {
+				// like a command killed by SIGPIPE, with --pipefail-exit
+				os.Exit(141)
+			}
+			var exitErr *kube.ContainerExitError
+			if errors.As(err, &exitErr) {
+				// like the followed container, whose exit is already written to stderr
+				os.Exit(exitErr.ExitCode)
+			}
+			cmdutil.CheckErr(err)
 			return nil
 		},
 	}
 	// Add flags
 	l.AddFlags(rootCmd)
+	rootCmd.Flags().StringVar(&configFile, "config", "", "Path to a config file setting flag defaults. Defaults to ~/"+defaultConfigFile+" if it exists.")
+	rootCmd.Flags().BoolVar(&doctor, "doctor", false, "If true, check that the kubeconfig can be loaded, that the cluster is reachable and that the pods and their logs can be read, e.g. with --context and -n, and exit.")
+	rootCmd.Flags().BoolVar(&listFlags, "list-flags", false, "If true, print every flag, including the kubectl client flags, with its type and default, and exit. Add -o json for scripting.")
 	// Add completion
 	l.RegisterCompletionFunc(rootCmd)
+	// completion is provided by kubectl_complete-like, not by a completion subcommand
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	//setting help templates
 	kubernetes.ActsAsRootCommand(rootCmd)
 	return rootCmd
 }
+
+// initConfig reads the config file given with --config, or the default one if it exists,
+// and reads flags from the environment
+func initConfig(configFile string) error {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+		return viper.ReadInConfig()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	viper.SetConfigFile(filepath.Join(home, defaultConfigFile))
+	if err := viper.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// bindShortEnv reads every flag from its KL_ environment variable too.
+// The KUBECTL_LIKE_ variable wins when both are set: it is bound first, and viper reads the
+// variables of a key in the order they are bound.
+func bindShortEnv(cmd *cobra.Command) error {
+	var errs []error
+	replacer := strings.NewReplacer("-", "_")
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		name := strings.ToUpper(replacer.Replace(f.Name))
+		if err := viper.BindEnv(f.Name, envPrefix+"_"+name, shortEnvPrefix+"_"+name); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// effectivePattern parses the flags like the root command and returns the value of --pattern read from viper
+func effectivePattern(t *testing.T, args ...string) string {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	cmd := CreateRootCmd()
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	cmd.PreRun(cmd, nil)
+	return viper.GetString("pattern")
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("pattern: from-config\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := effectivePattern(t); got != "" {
+		t.Errorf("default: got %q, want an empty pattern", got)
+	}
+	if got := effectivePattern(t, "--config", config); got != "from-config" {
+		t.Errorf("config file: got %q, want from-config", got)
+	}
+	t.Setenv("KL_PATTERN", "from-short-env")
+	if got := effectivePattern(t, "--config", config); got != "from-short-env" {
+		t.Errorf("KL_ env: got %q, want from-short-env", got)
+	}
+	t.Setenv("KUBECTL_LIKE_PATTERN", "from-env")
+	if got := effectivePattern(t, "--config", config); got != "from-env" {
+		t.Errorf("KUBECTL_LIKE_ env: got %q, want from-env", got)
+	}
+	if got := effectivePattern(t, "--config", config, "--pattern", "from-flag"); got != "from-flag" {
+		t.Errorf("flag: got %q, want from-flag", got)
+	}
+}
+
+func TestLongEnvWinsOverShortEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KUBECTL_LIKE_PATTERN", "from-env")
+	t.Setenv("KL_PATTERN", "from-short-env")
+	if got := effectivePattern(t); got != "from-env" {
+		t.Errorf("got %q, want the KUBECTL_LIKE_ variable from-env", got)
+	}
+
+	// an empty variable is ignored like an unset one
+	t.Setenv("KUBECTL_LIKE_PATTERN", "")
+	if got := effectivePattern(t); got != "from-short-env" {
+		t.Errorf("got %q, want the KL_ variable from-short-env", got)
+	}
+}
+
+func TestListFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	cmd := CreateRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--list-flags", "-o", "json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	var flags []flagInfo
+	if err := json.Unmarshal(out.Bytes(), &flags); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	found := map[string]flagInfo{}
+	for _, f := range flags {
+		found[f.Name] = f
+	}
+	if f, ok := found["pattern"]; !ok || f.Type != "string" {
+		t.Errorf("got pattern %+v, want a string flag", f)
+	}
+	// the kubectl client flags are listed too
+	if _, ok := found["context"]; !ok {
+		t.Errorf("the --context flag is not listed: %q", out.String())
+	}
+	// --list-flags and --doctor are flags, so that pods named list-flags or doctor can still be selected
+	if len(cmd.Commands()) > 0 {
+		t.Errorf("got subcommands %v, which hide the pods of the same name", cmd.Commands())
+	}
+}
@@ -0,0 +1,231 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+const (
+	// bellInterval is the shortest time between two bells of --bell
+	bellInterval = 3 * time.Second
+	bel          = "\a"
+	// notifyMessageLength is the number of characters of the line shown by --notify
+	notifyMessageLength = 200
+)
+
+// modes of --bell and --notify
+const (
+	alertAuto   = "auto"
+	alertAlways = "always"
+	alertNever  = "never"
+)
+
+var alertModes = []string{alertAuto, alertAlways, alertNever}
+
+// alertEnabled tells whether the alert of flag is given with mode: auto only alerts when out is a terminal,
+// so that scripts and pipelines are not disturbed
+func alertEnabled(mode, flag string, out io.Writer) (bool, error) {
+	switch mode {
+	case alertAlways:
+		return true, nil
+	case alertNever:
+		return false, nil
+	case alertAuto:
+		return term.TTY{Out: out}.IsTerminalOut(), nil
+	}
+	return false, fmt.Errorf("unknown %s %q, must be one of %s", flag, mode, strings.Join(alertModes, ", "))
+}
+
+// bell writes the BEL character on matching lines, at most once per interval
+type bell struct {
+	out      io.Writer
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newBell(out io.Writer, interval time.Duration) *bell {
+	return &bell{out: out, interval: interval}
+}
+
+func (b *bell) ring() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if !b.last.IsZero() && now.Sub(b.last) < b.interval {
+		return
+	}
+	b.last = now
+	io.WriteString(b.out, bel)
+}
+
+// defaultNotifyCommand returns the desktop notifier of the platform, or an empty command if it has none
+func defaultNotifyCommand() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return `osascript -e {{printf "display notification %q with title %q" .Message .Title}}`
+	case "windows":
+		return ""
+	}
+	return "notify-send {{.Title}} {{.Message}}"
+}
+
+// desktopNotification is the data of the template of --notify-command
+type desktopNotification struct {
+	notification
+	Title   string
+	Message string
+	// Count is the number of matching lines so far
+	Count int
+}
+
+// desktopNotifier runs the command of --notify-command on the first matching line, then on every
+// --notify-every matching lines
+type desktopNotifier struct {
+	command []*template.Template
+	every   int
+	errOut  io.Writer
+
+	mu    sync.Mutex
+	count int
+	wg    sync.WaitGroup
+}
+
+func newDesktopNotifier(command string, every int, errOut io.Writer) (*desktopNotifier, error) {
+	if len(strings.TrimSpace(command)) == 0 {
+		return nil, fmt.Errorf("--notify has no default command on %s, set one with --notify-command", runtime.GOOS)
+	}
+	var args []*template.Template
+	for _, arg := range splitCommandTemplate(command) {
+		t, err := template.New("notify").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --notify-command: %w", err)
+		}
+		args = append(args, t)
+	}
+	return &desktopNotifier{command: args, every: every, errOut: errOut}, nil
+}
+
+// splitCommandTemplate splits the template of a command into the templates of its arguments at the spaces
+// outside of the actions, so that the values are passed as they are, without a shell
+func splitCommandTemplate(command string) []string {
+	var args []string
+	var arg strings.Builder
+	depth := 0
+	for i := 0; i < len(command); i++ {
+		switch {
+		case strings.HasPrefix(command[i:], "{{"):
+			depth++
+			arg.WriteString("{{")
+			i++
+		case strings.HasPrefix(command[i:], "}}") && depth > 0:
+			depth--
+			arg.WriteString("}}")
+			i++
+		case depth == 0 && unicode.IsSpace(rune(command[i])):
+			if arg.Len() > 0 {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+		default:
+			arg.WriteByte(command[i])
+		}
+	}
+	if arg.Len() > 0 {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// args returns the arguments of the command for the notification
+func (n *desktopNotifier) args(data desktopNotification) ([]string, error) {
+	args := make([]string, 0, len(n.command))
+	for _, t := range n.command {
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("error executing --notify-command: %w", err)
+		}
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+// match counts a matching line and runs the command in the background if it is due
+func (n *desktopNotifier) match(source notification, line string) {
+	n.mu.Lock()
+	n.count++
+	count := n.count
+	n.mu.Unlock()
+	if count > 1 && (n.every == 0 || (count-1)%n.every != 0) {
+		return
+	}
+
+	message := []rune(line)
+	if len(message) > notifyMessageLength {
+		message = message[:notifyMessageLength]
+	}
+	source.Line = line
+	data := desktopNotification{
+		notification: source,
+		Title:        fmt.Sprintf("kubectl like: %s/%s", source.Pod, source.Container),
+		Message:      string(message),
+		Count:        count,
+	}
+	args, err := n.args(data)
+	if err != nil {
+		fmt.Fprintf(n.errOut, "error: %v\n", err)
+		return
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			fmt.Fprintf(n.errOut, "error: --notify: %v: %s\n", err, bytes.TrimSpace(out))
+		}
+	}()
+}
+
+// Close waits for the running commands
+func (n *desktopNotifier) Close() {
+	n.wg.Wait()
+}
+
+// alertWriter rings the bell and notifies the desktop for every line written to w
+type alertWriter struct {
+	bell       *bell
+	notifier   *desktopNotifier
+	source     notification
+	timestamps bool
+	writer     io.Writer
+}
+
+func (aw *alertWriter) Write(p []byte) (int, error) {
+	n, err := aw.writer.Write(p)
+	for _, line := range bytes.SplitAfter(p[:n], []byte("\n")) {
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		if aw.bell != nil {
+			aw.bell.ring()
+		}
+		if aw.notifier != nil {
+			if aw.timestamps {
+				_, line, _ = splitTimestamp(line)
+			}
+			aw.notifier.match(aw.source, string(line))
+		}
+	}
+	return n, err
+}
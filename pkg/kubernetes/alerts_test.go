@@ -0,0 +1,167 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBellRateLimit(t *testing.T) {
+	var out bytes.Buffer
+	b := newBell(&out, time.Hour)
+	w := &alertWriter{bell: b, writer: &bytes.Buffer{}}
+	if _, err := w.Write([]byte("ERROR one\nERROR two\n")); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("ERROR three\n"))
+	if got := out.String(); got != "\a" {
+		t.Errorf("got %q, want a single BEL within the interval", got)
+	}
+
+	b.last = time.Now().Add(-2 * time.Hour)
+	w.Write([]byte("ERROR four\n"))
+	if got := out.String(); got != "\a\a" {
+		t.Errorf("got %q, want another BEL once the interval elapsed", got)
+	}
+}
+
+func TestAlertEnabled(t *testing.T) {
+	var out bytes.Buffer
+	for mode, want := range map[string]bool{alertAuto: false, alertAlways: true, alertNever: false} {
+		got, err := alertEnabled(mode, "--bell", &out)
+		if err != nil || got != want {
+			t.Errorf("%s to a buffer: got %v, %v, want %v", mode, got, err, want)
+		}
+	}
+	if _, err := alertEnabled("loud", "--bell", &out); err == nil || !strings.Contains(err.Error(), `unknown --bell "loud"`) {
+		t.Errorf("expected an unknown mode error, got %v", err)
+	}
+}
+
+func TestSplitCommandTemplate(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{command: "notify-send {{.Title}} {{.Message}}", want: []string{"notify-send", "{{.Title}}", "{{.Message}}"}},
+		{
+			command: `osascript -e {{printf "display notification %q with title %q" .Message .Title}}`,
+			want:    []string{"osascript", "-e", `{{printf "display notification %q with title %q" .Message .Title}}`},
+		},
+		{command: "  say  pod-{{.Pod}}:{{ .Line }} ", want: []string{"say", "pod-{{.Pod}}:{{ .Line }}"}},
+	}
+	for _, test := range tests {
+		if got := splitCommandTemplate(test.command); !slices.Equal(got, test.want) {
+			t.Errorf("splitCommandTemplate(%q) = %q, want %q", test.command, got, test.want)
+		}
+	}
+}
+
+func TestDesktopNotifierArgs(t *testing.T) {
+	data := desktopNotification{
+		notification: notification{Namespace: "test", Pod: "api-1", Container: "app", Line: `ERROR "quoted"; rm -rf /`},
+		Title:        "kubectl like: api-1/app",
+		Message:      `ERROR "quoted"; rm -rf /`,
+		Count:        3,
+	}
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{command: "notify-send {{.Title}} {{.Message}}", want: []string{"notify-send", "kubectl like: api-1/app", `ERROR "quoted"; rm -rf /`}},
+		{
+			command: `osascript -e {{printf "display notification %q with title %q" .Message .Title}}`,
+			want:    []string{"osascript", "-e", `display notification "ERROR \"quoted\"; rm -rf /" with title "kubectl like: api-1/app"`},
+		},
+		{command: "say {{.Count}} in {{.Namespace}}/{{.Pod}}", want: []string{"say", "3", "in", "test/api-1"}},
+	}
+	for _, test := range tests {
+		n, err := newDesktopNotifier(test.command, 0, &bytes.Buffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := n.args(data)
+		if err != nil || !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, %v, want %q", test.command, got, err, test.want)
+		}
+	}
+
+	for _, command := range []string{"", "notify-send {{.Title", "notify-send {{.Nope}}"} {
+		n, err := newDesktopNotifier(command, 0, &bytes.Buffer{})
+		if err == nil {
+			_, err = n.args(data)
+		}
+		if err == nil {
+			t.Errorf("expected %q to be rejected", command)
+		}
+	}
+}
+
+func TestDesktopNotifierEvery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the helper script needs sh")
+	}
+	dir := t.TempDir()
+	received := filepath.Join(dir, "received")
+	script := filepath.Join(dir, "notify.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $2\" >> "+received+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for every, want := range map[int]string{
+		0: "1 ERROR 1\n",
+		2: "1 ERROR 1\n3 ERROR 3\n5 ERROR 5\n",
+	} {
+		os.Remove(received)
+		n, err := newDesktopNotifier(script+" {{.Count}} {{.Message}}", every, &bytes.Buffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 5; i++ {
+			n.match(notification{Pod: "api-1"}, fmt.Sprintf("ERROR %d", i))
+			// the commands run in the background, wait for each one to keep their order
+			n.Close()
+		}
+		if got := readFile(t, received); got != want {
+			t.Errorf("--notify-every %d: got %q, want %q", every, got, want)
+		}
+	}
+}
+
+func TestNotifyValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--notify-every", "2"},
+		{"--notify=always", "--notify-every", "-1"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+	for _, flags := range [][]string{
+		{"--bell=loud"},
+		{"--notify=always", "--notify-command", " "},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+
+	// --bell without a value rings only on a terminal
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--bell"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.Bell != alertAuto || l.bell != nil {
+		t.Errorf("got --bell %q and a bell %v, want auto and no bell to a buffer", l.Bell, l.bell)
+	}
+}
@@ -0,0 +1,285 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// backlogLine is a line of the backlog of a container, with the timestamp added by the server
+type backlogLine struct {
+	time time.Time
+	ref  corev1.ObjectReference
+	line []byte
+}
+
+// backlogMark is the last line of the backlog of a container, after which its live stream resumes
+type backlogMark struct {
+	time time.Time
+	// seen is the number of lines of the backlog with exactly that timestamp
+	seen int
+}
+
+// splitTimestamp splits the RFC3339 timestamp added by the server with timestamps=true from the line
+func splitTimestamp(line []byte) (time.Time, []byte, bool) {
+	timestamp, rest, found := bytes.Cut(line, []byte(" "))
+	if !found {
+		return time.Time{}, line, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(timestamp))
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return t, rest, true
+}
+
+// printOrderedBacklog reads the backlog of every container without following, then prints the matching lines
+// of all of them ordered by timestamp. When following, it returns the requests resuming each container
+// right after the last line of its backlog, so that no line is printed twice or dropped.
+func (l LikeOptions) printOrderedBacklog(requests map[corev1.ObjectReference]rest.ResponseWrapper) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+	logOptions, ok := l.Options.(*corev1.PodLogOptions)
+	if !ok {
+		return nil, errors.New("unexpected logs options object")
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	start := metav1.Now()
+	var lines []backlogLine
+	marks := map[corev1.ObjectReference]backlogMark{}
+	for ref := range requests {
+		opts := logOptions.DeepCopy()
+		_, opts.Container = l.containerFromRef(ref)
+		opts.Follow = false
+		opts.Timestamps = true
+		l.logger.Debug("reading backlog", "namespace", ref.Namespace, "pod", ref.Name, "container", opts.Container)
+		sourceLines, mark, err := l.readBacklog(l.captured(ref, clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts)), ref)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, sourceLines...)
+		marks[ref] = mark
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].time.Before(lines[j].time)
+	})
+	writers := map[corev1.ObjectReference]io.Writer{}
+	for _, line := range lines {
+		w, ok := writers[line.ref]
+		if !ok {
+			w = l.writerFor(line.ref, l.Out)
+			writers[line.ref] = w
+		}
+		if _, err := w.Write(line.line); err != nil {
+			return nil, err
+		}
+	}
+	if !l.Follow {
+		return nil, nil
+	}
+
+	live := make(map[corev1.ObjectReference]rest.ResponseWrapper, len(marks))
+	for ref, mark := range marks {
+		opts := logOptions.DeepCopy()
+		_, opts.Container = l.containerFromRef(ref)
+		opts.Timestamps = true
+		opts.TailLines = nil
+		opts.SinceSeconds = nil
+		opts.LimitBytes = nil
+		// the server only keeps the seconds of sinceTime, the lines up to the mark are dropped by resumedRequest
+		opts.SinceTime = &start
+		if !mark.time.IsZero() {
+			opts.SinceTime = &metav1.Time{Time: mark.time}
+		}
+		live[ref] = &resumedRequest{
+			ResponseWrapper: clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts),
+			mark:            mark,
+			// followWithReattach needs the timestamps to resume the stream if it is reopened, and removes them
+			timestamps: logOptions.Timestamps || l.Follow && !l.NoReattach,
+		}
+	}
+	return live, nil
+}
+
+// readBacklog returns the matching lines of the backlog of a container and the mark of its last line
+func (l LikeOptions) readBacklog(request rest.ResponseWrapper, ref corev1.ObjectReference) ([]backlogLine, backlogMark, error) {
+	var lines []backlogLine
+	var mark backlogMark
+	readCloser, err := (&interruptibleRequest{ResponseWrapper: request, ctx: l.runContext()}).Stream(l.runContext())
+	if err != nil {
+		return nil, mark, err
+	}
+	defer readCloser.Close()
+
+	watchdog := l.startMatchWatchdog()
+	defer watchdog.Close()
+	r := bufio.NewReader(readCloser)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			t, rest, ok := splitTimestamp(line)
+			if ok {
+				if t.Equal(mark.time) {
+					mark.seen++
+				} else {
+					mark = backlogMark{time: t, seen: 1}
+				}
+			}
+			if !l.Timestamps {
+				line = rest
+			}
+			if l.StripANSI {
+				line = ansiRegexp.ReplaceAll(line, nil)
+			}
+			matched, _ := l.matchLineWithin(watchdog, line)
+			l.tally.add(matched)
+			if matched {
+				// lines without a timestamp keep their place after the previous line
+				for _, out := range l.outputLines(line) {
+					lines = append(lines, backlogLine{time: mark.time, ref: ref, line: out})
+				}
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return nil, mark, err
+			}
+			return lines, mark, nil
+		}
+	}
+}
+
+// lastMark is the mark of the last line read from the streams of a container, from which a reopened stream
+// resumes. It is updated by the goroutine copying the stream.
+type lastMark struct {
+	mu   sync.Mutex
+	mark backlogMark
+}
+
+// see records a line read with the timestamp t
+func (m *lastMark) see(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.Equal(m.mark.time) {
+		m.mark.seen++
+		return
+	}
+	m.mark = backlogMark{time: t, seen: 1}
+}
+
+func (m *lastMark) get() backlogMark {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mark
+}
+
+func (m *lastMark) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mark = backlogMark{}
+}
+
+// since returns the time a reopened stream starts from, the timestamp of the last line read or fallback if
+// no line with a timestamp was read
+func (m *lastMark) since(fallback metav1.Time) *metav1.Time {
+	if mark := m.get(); !mark.time.IsZero() {
+		return &metav1.Time{Time: mark.time}
+	}
+	return &fallback
+}
+
+// resumedRequest is the live request of a container whose backlog was already printed, or the reopened
+// request of a followed container. The lines up to the mark are dropped and the timestamps are removed
+// unless they were asked for.
+type resumedRequest struct {
+	rest.ResponseWrapper
+	mark       backlogMark
+	timestamps bool
+	// last, if set, records the mark of every line copied
+	last *lastMark
+	// stats, if set, counts the lines dropped up to the mark and the gaps of source for --stats
+	stats  *matchCounts
+	source string
+}
+
+func (r *resumedRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		defer stream.Close()
+		writer.CloseWithError(r.copyAfterMark(stream, writer))
+	}()
+	return reader, nil
+}
+
+func (r *resumedRequest) copyAfterMark(stream io.Reader, out io.Writer) error {
+	seen := 0
+	resumed := r.mark.time.IsZero()
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			t, rest, ok := splitTimestamp(line)
+			if !resumed && ok {
+				switch {
+				case t.Before(r.mark.time):
+					r.countDuplicate()
+				case t.Equal(r.mark.time) && seen < r.mark.seen:
+					seen++
+					r.countDuplicate()
+				default:
+					resumed = true
+					if seen < r.mark.seen {
+						// the lines up to the mark are not in the stream anymore, e.g. the log was rotated,
+						// so lines may have been lost in between
+						r.countGap()
+					}
+				}
+			}
+			if resumed {
+				if ok && r.last != nil {
+					r.last.see(t)
+				}
+				if !r.timestamps {
+					line = rest
+				}
+				if _, err := out.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+func (r *resumedRequest) countDuplicate() {
+	if r.stats != nil {
+		r.stats.dropDuplicate(r.source)
+	}
+}
+
+func (r *resumedRequest) countGap() {
+	if r.stats != nil {
+		r.stats.gap(r.source)
+	}
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestPrintOrderedBacklog(t *testing.T) {
+	api := &fakeAPI{logs: map[string]string{
+		"/namespaces/test/pods/api-1/log": "2024-06-12T10:00:01Z ERROR a1\n2024-06-12T10:00:03Z ERROR a3\n2024-06-12T10:00:03Z INFO a3\n",
+		"/namespaces/test/pods/api-2/log": "2024-06-12T10:00:02Z ERROR b2\n2024-06-12T10:00:04Z ERROR b4\n",
+	}}
+	l, out, _ := newFakeOptions(t, api)
+	l.Options = &corev1.PodLogOptions{Follow: true}
+	l.Follow = true
+	l.Prefix = true
+	l.patternRegexp = regexp.MustCompile("ERROR")
+	requests := map[corev1.ObjectReference]rest.ResponseWrapper{
+		{Namespace: "test", Name: "api-1", FieldPath: "spec.containers{app}"}: nil,
+		{Namespace: "test", Name: "api-2", FieldPath: "spec.containers{app}"}: nil,
+	}
+
+	live, err := l.printOrderedBacklog(requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[pod/api-1/app] ERROR a1\n[pod/api-2/app] ERROR b2\n[pod/api-1/app] ERROR a3\n[pod/api-2/app] ERROR b4\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// the live streams start again from the second of the mark, the lines up to the mark are dropped
+	for ref, request := range live {
+		stream, err := request.Stream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed, err := io.ReadAll(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resumed) > 0 {
+			t.Errorf("%s printed the lines of its backlog again: %q", ref.Name, resumed)
+		}
+	}
+	for _, name := range []string{"api-1", "api-2"} {
+		queries := api.requests("/namespaces/test/pods/" + name + "/log")
+		if len(queries) != 2 || !strings.Contains(queries[1], "sinceTime=2024-06-12T10%3A00%3A0") {
+			t.Errorf("the live request of %s does not start at its mark: %q", name, queries)
+		}
+	}
+}
+
+func TestCopyAfterMarkHasNoGapNorDuplicate(t *testing.T) {
+	mark := backlogMark{time: time.Date(2024, 6, 12, 10, 0, 3, 0, time.UTC), seen: 2}
+	r := &resumedRequest{mark: mark}
+	stream := strings.Join([]string{
+		"2024-06-12T10:00:03Z seen 1",
+		"2024-06-12T10:00:03Z seen 2",
+		// same second as the mark, but after the lines already printed
+		"2024-06-12T10:00:03Z new 1",
+		"2024-06-12T10:00:04Z new 2",
+		"continuation of new 2",
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	if err := r.copyAfterMark(strings.NewReader(stream), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "new 1\nnew 2\ncontinuation of new 2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	r.timestamps = true
+	out.Reset()
+	if err := r.copyAfterMark(strings.NewReader(stream), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "2024-06-12T10:00:03Z new 1\n") {
+		t.Errorf("the timestamps asked for were removed: %q", out.String())
+	}
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	ts, rest, ok := splitTimestamp([]byte("2024-06-12T10:04:05.123456789Z hello world\n"))
+	if !ok || string(rest) != "hello world\n" || ts.Nanosecond() != 123456789 {
+		t.Errorf("got %v %q %t", ts, rest, ok)
+	}
+	if _, rest, ok := splitTimestamp([]byte("no timestamp here\n")); ok || string(rest) != "no timestamp here\n" {
+		t.Errorf("a line without timestamp was split: %q", rest)
+	}
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	bannerText = "text"
+	bannerJSON = "json"
+)
+
+var bannerFormats = []string{bannerText, bannerJSON}
+
+// banner is the line written to ErrOut before streaming, so that the cluster, the namespace and the pattern
+// can be checked at a glance before a pattern meant for one cluster is run against another
+type banner struct {
+	Contexts  []string `json:"contexts"`
+	Namespace string   `json:"namespace"`
+	// NamespaceFrom tells whether the namespace comes from --namespace, the context or is the default one
+	NamespaceFrom string   `json:"namespaceFrom,omitempty"`
+	Pods          int      `json:"pods"`
+	Containers    int      `json:"containers"`
+	Pattern       string   `json:"pattern"`
+	Exclude       []string `json:"exclude,omitempty"`
+}
+
+// currentContext returns the context of --context, or the current context of the kubeconfig
+func (l LikeOptions) currentContext() string {
+	if l.KubernetesConfigFlags != nil && l.KubernetesConfigFlags.Context != nil && len(*l.KubernetesConfigFlags.Context) > 0 {
+		return *l.KubernetesConfigFlags.Context
+	}
+	config, err := l.factory.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// printBanner writes the banner of the targets to ErrOut, unless --no-banner is given
+func (l LikeOptions) printBanner(targets []logTarget) error {
+	if l.NoBanner {
+		return nil
+	}
+	b := banner{Namespace: l.Namespace, NamespaceFrom: l.namespaceFrom, Containers: len(targets), Pattern: l.Pattern, Exclude: l.Exclude}
+	namespace := l.namespaceDescription()
+	if l.AllNamespaces {
+		b.Namespace, b.NamespaceFrom, namespace = "*", "", "*"
+	}
+	pods := map[string]bool{}
+	for _, target := range targets {
+		pods[target.Context+"|"+target.Namespace+"/"+target.Pod] = true
+	}
+	b.Pods = len(pods)
+	var namespaces []string
+	for _, c := range l.contextOptions {
+		b.Contexts = append(b.Contexts, c.contextName)
+		namespaces = append(namespaces, c.namespaceDescription())
+	}
+	// every context has its own namespace
+	if len(namespaces) > 0 && !l.AllNamespaces {
+		namespace = strings.Join(namespaces, ", ")
+	}
+	if len(b.Contexts) == 0 {
+		b.Contexts = []string{l.currentContext()}
+	}
+
+	if l.BannerFormat == bannerJSON {
+		return json.NewEncoder(l.ErrOut).Encode(b)
+	}
+	pattern := strconv.Quote(b.Pattern)
+	if len(b.Pattern) == 0 {
+		pattern = "(none)"
+	}
+	line := fmt.Sprintf("context: %s | namespace: %s | targets: %d pod(s), %d container(s) | pattern: %s",
+		strings.Join(b.Contexts, ","), namespace, b.Pods, b.Containers, pattern)
+	for _, exclude := range b.Exclude {
+		line += " | exclude: " + strconv.Quote(exclude)
+	}
+	if l.colorizeErr {
+		line = sgrBold + line + sgrReset
+	}
+	_, err := fmt.Fprintln(l.ErrOut, line)
+	return err
+}
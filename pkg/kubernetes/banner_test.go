@@ -0,0 +1,77 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+// runWithBanner runs the command in the prod context of a kubeconfig and returns what it wrote to stderr
+func runWithBanner(t *testing.T, flags []string, args ...string) string {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	for _, name := range []string{"prod", "dev"} {
+		config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name + ".example.com"}
+		config.AuthInfos[name] = &clientcmdapi.AuthInfo{}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: "test"}
+	}
+	config.CurrentContext = "prod"
+	api := newPodAPI("api-1")
+	pod := testPod("api-2", corev1.PodRunning, nil)
+	api.objects["/namespaces/test/pods/api-2"] = &pod
+	api.logs = map[string]string{"/namespaces/test/pods/api-1/log": "ERROR boom\n", "/namespaces/test/pods/api-2/log": "INFO ok\n"}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	l.factory.(*cmdtesting.TestFactory).WithClientConfig(clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}))
+	if err := completeFlags(l, cmd, flags, args...); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return errOut.String()
+}
+
+func TestBanner(t *testing.T) {
+	got := runWithBanner(t, []string{"--pattern", "ERROR", "--exclude", "healthz"}, "api-1", "api-2")
+	want := `context: prod | namespace: test (from context prod) | targets: 2 pod(s), 2 container(s) | pattern: "ERROR" | exclude: "healthz"` + "\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = runWithBanner(t, []string{"--pattern", "ERROR", "--banner-format", "json"}, "api-1")
+	var b banner
+	if err := json.Unmarshal([]byte(got), &b); err != nil {
+		t.Fatalf("invalid banner %q: %v", got, err)
+	}
+	if want := (banner{Contexts: []string{"prod"}, Namespace: "test", NamespaceFrom: namespaceFromContext, Pods: 1, Containers: 1, Pattern: "ERROR"}); !reflect.DeepEqual(b, want) {
+		t.Errorf("got %+v, want %+v", b, want)
+	}
+
+	got = runWithBanner(t, []string{"--context", "dev"}, "api-1")
+	if want := "context: dev | namespace: test (from context dev) | targets: 1 pod(s), 1 container(s) | pattern: (none)\n"; got != want {
+		t.Errorf("with --context got %q, want %q", got, want)
+	}
+
+	if got := runWithBanner(t, []string{"--pattern", "ERROR", "--no-banner"}, "api-1"); got != "" {
+		t.Errorf("got %q with --no-banner, want nothing", got)
+	}
+}
+
+func TestBannerFormatValidation(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--banner-format", "yaml"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected --banner-format yaml to be rejected")
+	}
+}
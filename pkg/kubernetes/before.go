@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"io"
+)
+
+// contextSeparator is printed between the groups of --before-lines that are not contiguous, like grep does
+const contextSeparator = "--\n"
+
+// beforeLines keeps the last lines of a stream that did not match, to print them before the next match
+type beforeLines struct {
+	max   int
+	lines [][]byte
+	// gap tells whether lines were dropped since the last printed one, and printed whether one was
+	gap     bool
+	printed bool
+}
+
+func (b *beforeLines) add(line []byte) {
+	if len(b.lines) == b.max {
+		b.lines = b.lines[1:]
+		b.gap = true
+	}
+	b.lines = append(b.lines, line)
+}
+
+// flush writes the kept lines to out before a matching line, after a separator if lines were dropped
+func (b *beforeLines) flush(out io.Writer, truncate func([]byte) []byte) error {
+	if b.gap && b.printed {
+		if _, err := io.WriteString(out, contextSeparator); err != nil {
+			return err
+		}
+	}
+	for _, line := range b.lines {
+		if _, err := out.Write(truncate(line)); err != nil {
+			return err
+		}
+	}
+	b.lines, b.gap, b.printed = b.lines[:0], false, true
+	return nil
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBeforeLines(t *testing.T) {
+	logs := map[string]string{"api-1": "one\ntwo\nthree\nERROR first\nfour\nERROR second\nfive\nsix\nseven\nERROR third\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.BeforeLines = 2
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	want := "two\nthree\nERROR first\nfour\nERROR second\n--\nsix\nseven\nERROR third\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// pipeRequest is a followed log request, whose lines are written by the test while it is streamed
+type pipeRequest struct {
+	reader *io.PipeReader
+}
+
+func (r pipeRequest) DoRaw(context.Context) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r pipeRequest) Stream(context.Context) (io.ReadCloser, error) {
+	return r.reader, nil
+}
+
+func TestBeforeLinesWhileFollowing(t *testing.T) {
+	l, _ := newOutputOptions(t, outputText, "ERROR", nil)
+	l.Follow = true
+	l.BeforeLines = 2
+	out := &lockedBuffer{}
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- l.DefaultConsumeRequest(pipeRequest{reader: reader}, out) }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for out.String() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %q, want %q", out.String(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// the lines before the match streamed by long before it arrives
+	io.WriteString(writer, "one\ntwo\nthree\n")
+	time.Sleep(10 * time.Millisecond)
+	if got := out.String(); got != "" {
+		t.Fatalf("got %q before the match, want nothing", got)
+	}
+	io.WriteString(writer, "ERROR boom\n")
+	waitFor("two\nthree\nERROR boom\n")
+	io.WriteString(writer, "four\nfive\nsix\nERROR again\n")
+	waitFor("two\nthree\nERROR boom\n--\nfive\nsix\nERROR again\n")
+
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBeforeLinesValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"-B", "-1"},
+		{"-B", "2", "--only-matching", "--pattern", "ERROR"},
+		{"-B", "2", "-o", "json"},
+		{"-B", "2", "--split-streams"},
+		{"-B", "2", "--exec", "cat"},
+		{"-B", "2", "--webhook", "http://127.0.0.1:1/hook"},
+		{"-B", "2", "--bell=always"},
+		{"-B", "2", "--stats"},
+		{"-B", "2", "--group-by", "replicaset"},
+		{"-B", "2", "--dedup"},
+		{"-B", "2", "-f", "--heartbeat", "30s"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
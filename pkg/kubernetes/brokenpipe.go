@@ -0,0 +1,38 @@
+package kubernetes
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ErrBrokenPipe is returned by Run with --pipefail-exit when the reader of the output went away, e.g. head
+// exited, and the streams were stopped
+var ErrBrokenPipe = errors.New("broken pipe")
+
+// IgnoreBrokenPipe keeps a write to a closed stdout from killing the process with SIGPIPE, so that it fails
+// with EPIPE instead and the streams are stopped and the summaries printed like when --max-bytes is reached
+func IgnoreBrokenPipe() {
+	signal.Ignore(syscall.SIGPIPE)
+}
+
+// brokenPipeWriter stops the streams with ErrBrokenPipe once the reader of the output is gone, instead of an
+// error for every line still read
+type brokenPipeWriter struct {
+	writer io.Writer
+}
+
+func (w *brokenPipeWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if isBrokenPipe(err) {
+		return n, ErrBrokenPipe
+	}
+	return n, err
+}
+
+// isBrokenPipe tells whether err is the error of a write to an output without reader
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, os.ErrClosed)
+}
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// runClosingOutput runs the command with an output whose reader exits after reading lines lines, like head
+func runClosingOutput(t *testing.T, lines int, flags ...string) (string, error) {
+	t.Helper()
+	api1, api2 := testPod("api-1", corev1.PodRunning, nil), testPod("api-2", corev1.PodRunning, nil)
+	var log strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&log, "ERROR line %d\n", i)
+	}
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": &api1, "/namespaces/test/pods/api-2": &api2},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": log.String(), "/namespaces/test/pods/api-2/log": log.String()},
+	}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, append(flags, "--pattern", "ERROR", "--no-banner"), "api-1", "api-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	r, w := io.Pipe()
+	l.Out = w
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for range lines {
+			if !scanner.Scan() {
+				break
+			}
+		}
+		r.Close()
+	}()
+	err := l.Run()
+	return errOut.String(), err
+}
+
+func TestBrokenPipeStopsQuietly(t *testing.T) {
+	for _, flags := range [][]string{{}, {"--ignore-errors"}, {"--follow", "--no-reattach"}} {
+		errOut, err := runClosingOutput(t, 5, flags...)
+		if err != nil {
+			t.Errorf("%v: got %v, want a clean exit", flags, err)
+		}
+		if len(errOut) > 0 {
+			t.Errorf("%v: got %q on stderr, want nothing", flags, errOut)
+		}
+	}
+}
+
+func TestBrokenPipeWithPipefailExit(t *testing.T) {
+	errOut, err := runClosingOutput(t, 5, "--pipefail-exit")
+	if !errors.Is(err, ErrBrokenPipe) {
+		t.Errorf("got %v, want ErrBrokenPipe", err)
+	}
+	if len(errOut) > 0 {
+		t.Errorf("got %q on stderr, want nothing", errOut)
+	}
+}
+
+func TestBrokenPipeWriter(t *testing.T) {
+	for _, err := range []error{syscall.EPIPE, fmt.Errorf("write /dev/stdout: %w", syscall.EPIPE), io.ErrClosedPipe} {
+		w := &brokenPipeWriter{writer: failingWriter{err}}
+		if _, got := w.Write([]byte("line\n")); !errors.Is(got, ErrBrokenPipe) {
+			t.Errorf("%v: got %v, want ErrBrokenPipe", err, got)
+		}
+	}
+	w := &brokenPipeWriter{writer: failingWriter{syscall.ENOSPC}}
+	if _, got := w.Write([]byte("line\n")); !errors.Is(got, syscall.ENOSPC) {
+		t.Errorf("got %v, want the error of the writer kept", got)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
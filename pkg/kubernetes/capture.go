@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// rawCapture writes every line read from the containers, before any filtering, to a single gzip file.
+// The lines are prefixed with their source like with --prefix.
+type rawCapture struct {
+	mu   sync.Mutex
+	file *os.File
+	gz   *gzip.Writer
+	done chan struct{}
+}
+
+// newRawCapture creates the gzip file at path
+func newRawCapture(path string) (*rawCapture, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &rawCapture{file: file, gz: gzip.NewWriter(file), done: make(chan struct{})}
+	flushUntilClosed(c.done, outputFlushInterval, c.flush)
+	return c, nil
+}
+
+func (c *rawCapture) write(line []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gz == nil {
+		return os.ErrClosed
+	}
+	_, err := c.gz.Write(line)
+	return err
+}
+
+func (c *rawCapture) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gz != nil {
+		c.gz.Flush()
+	}
+}
+
+// Close writes the end of the gzip stream and closes the file, once
+func (c *rawCapture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gz == nil {
+		return nil
+	}
+	close(c.done)
+	gz := c.gz
+	c.gz = nil
+	if err := gz.Close(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
+
+// captured returns the request of the container referenced by ref, copying everything it streams to --capture-raw
+func (l LikeOptions) captured(ref corev1.ObjectReference, request rest.ResponseWrapper) rest.ResponseWrapper {
+	if l.capture == nil {
+		return request
+	}
+	return &capturedRequest{ResponseWrapper: request, capture: l.capture, prefix: []byte("[" + l.sourceName(ref) + "] ")}
+}
+
+// capturedRequest is a request whose stream is copied line by line to a rawCapture
+type capturedRequest struct {
+	rest.ResponseWrapper
+	capture *rawCapture
+	prefix  []byte
+}
+
+func (r *capturedRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := &captureWriter{capture: r.capture, prefix: r.prefix}
+	return &capturedStream{Reader: io.TeeReader(stream, w), stream: stream, writer: w}, nil
+}
+
+type capturedStream struct {
+	io.Reader
+	stream io.Closer
+	writer *captureWriter
+}
+
+func (s *capturedStream) Close() error {
+	s.writer.Flush()
+	return s.stream.Close()
+}
+
+// captureWriter writes the complete lines written to it, with their prefix, to the capture
+type captureWriter struct {
+	capture *rawCapture
+	prefix  []byte
+	partial []byte
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.capture.write(append(append([]byte(nil), w.prefix...), w.partial[:i+1]...)); err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line when the stream does not end with a newline
+func (w *captureWriter) Flush() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := append(append(append([]byte(nil), w.prefix...), w.partial...), '\n')
+	w.partial = nil
+	return w.capture.write(line)
+}
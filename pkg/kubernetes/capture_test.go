@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readCapture(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("the archive is not readable: %v", err)
+	}
+	return string(b)
+}
+
+// linesOf returns the lines of the capture prefixed with the source
+func linesOf(capture, source string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(capture, "\n") {
+		if rest, ok := strings.CutPrefix(line, "["+source+"] "); ok {
+			b.WriteString(rest)
+		}
+	}
+	return b.String()
+}
+
+func TestCaptureRawKeepsTheUnfilteredStream(t *testing.T) {
+	logs := map[string]string{
+		"api-1": "INFO starting\nERROR boom\nINFO done\n",
+		"api-2": "DEBUG tick\nERROR failed, retrying\n\nWARN no newline at the end",
+	}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	path := filepath.Join(t.TempDir(), "raw.log.gz")
+	capture, err := newRawCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.capture = capture
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.parallelConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if err := capture.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readCapture(t, path)
+	for pod, log := range logs {
+		want := log
+		if !strings.HasSuffix(want, "\n") {
+			want += "\n"
+		}
+		if lines := linesOf(got, "test/"+pod+"/app"); lines != want {
+			t.Errorf("%s: got %q, want %q", pod, lines, want)
+		}
+	}
+	if strings.Count(out.String(), "\n") != 2 {
+		t.Errorf("the filtered view is not filtered: %q", out.String())
+	}
+}
+
+func TestCaptureRawCloseKeepsArchiveReadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.log.gz")
+	capture, err := newRawCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &captureWriter{capture: capture, prefix: []byte("[test/api-1/app] ")}
+	if _, err := w.Write([]byte("ERROR boom\npartial")); err != nil {
+		t.Fatal(err)
+	}
+	// closed while the stream is still open, like on Ctrl-C
+	if err := capture.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readCapture(t, path), "[test/api-1/app] ERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := w.Flush(); err != os.ErrClosed {
+		t.Errorf("expected a write after Close to fail with ErrClosed, got %v", err)
+	}
+	if err := capture.Close(); err != nil {
+		t.Errorf("a second Close failed: %v", err)
+	}
+}
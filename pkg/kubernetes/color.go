@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+const (
+	colorByMatch = "match"
+	colorByLevel = "level"
+	colorByPod   = "pod"
+)
+
+var colorByValues = []string{colorByMatch, colorByLevel, colorByPod}
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var colorModes = []string{colorAuto, colorAlways, colorNever}
+
+const (
+	sgrReset     = "\x1b[0m"
+	sgrMatch     = "\x1b[1;31m"
+	sgrLevelErr  = "\x1b[31m"
+	sgrLevelWarn = "\x1b[33m"
+	sgrDim       = "\x1b[2m"
+	sgrBold      = "\x1b[1m"
+)
+
+// ansiRegexp matches the ANSI escape sequences some applications color their own logs with, for --strip-ansi
+var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// prefixPalette are the colors of the line prefixes, picked by pod so that every pod keeps its color
+var prefixPalette = []string{"\x1b[32m", "\x1b[33m", "\x1b[34m", "\x1b[35m", "\x1b[36m", "\x1b[92m", "\x1b[94m", "\x1b[95m", "\x1b[96m"}
+
+// podColor returns the color of the pod in the prefix palette
+func podColor(namespace, pod string) string {
+	h := fnv.New32a()
+	h.Write([]byte(namespace + "/" + pod))
+	return prefixPalette[h.Sum32()%uint32(len(prefixPalette))]
+}
+
+// completeColor decides once whether ANSI colors are written, for every feature emitting them.
+// With --color=auto, colors are written when Out is a terminal, NO_COLOR is not set and there is no --output-file or --tee.
+// The status lines written to ErrOut only need ErrOut to be a terminal. --no-color is --color=never.
+func (l *LikeOptions) completeColor() error {
+	if l.NoColor {
+		l.Color = colorNever
+	}
+	var err error
+	if l.colorize, err = colorEnabled(l.Color, l.Out); err != nil {
+		return err
+	}
+	// the lines written to Out are also written to --output-file or --tee, which must stay plain
+	if l.Color == colorAuto && (len(l.OutputFile) > 0 || len(l.Tee) > 0) {
+		l.colorize = false
+	}
+	l.colorizeErr, err = colorEnabled(l.Color, l.ErrOut)
+	return err
+}
+
+// colorEnabled tells whether colors are written to out with the --color mode
+func colorEnabled(mode string, out io.Writer) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		return !noColor && term.TTY{Out: out}.IsTerminalOut(), nil
+	}
+	return false, fmt.Errorf("unknown --color %q, must be one of %s", mode, strings.Join(colorModes, ", "))
+}
+
+// sgr wraps text in the ANSI escape codes of the SGR code if colors are written
+func (l LikeOptions) sgr(code, text string) string {
+	if !l.colorize {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + sgrReset
+}
+
+// colorWriter colors the lines written to it according to --color-by
+type colorWriter struct {
+	l      LikeOptions
+	pod    string
+	writer io.Writer
+}
+
+func (cw *colorWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	line := bytes.TrimRight(p, "\r\n")
+	colored := cw.l.colorLine(line, cw.pod)
+	colored = append(colored, p[len(line):]...)
+	if _, err := cw.writer.Write(colored); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// colorLine returns the line with the SGR codes of --color-by. The pod color is used by --color-by=pod.
+func (l LikeOptions) colorLine(line []byte, pod string) []byte {
+	switch l.ColorBy {
+	case colorByLevel:
+		switch s := l.severityOf(line); {
+		case s >= severityError:
+			return wrapSGR(sgrLevelErr, line)
+		case s == severityWarning:
+			return wrapSGR(sgrLevelWarn, line)
+		}
+		return append([]byte(nil), line...)
+	case colorByPod:
+		return wrapSGR(pod, line)
+	default:
+		return l.highlightMatches(line)
+	}
+}
+
+// highlightMatches colors every match of the pattern in the line
+func (l LikeOptions) highlightMatches(line []byte) []byte {
+	colored := make([]byte, 0, len(line))
+	last := 0
+	for _, match := range l.matchRanges(line) {
+		if match.Start < last {
+			continue
+		}
+		colored = append(colored, line[last:match.Start]...)
+		colored = append(colored, wrapSGR(sgrMatch, line[match.Start:match.End])...)
+		last = match.End
+	}
+	return append(colored, line[last:]...)
+}
+
+func wrapSGR(code string, text []byte) []byte {
+	colored := make([]byte, 0, len(code)+len(text)+len(sgrReset))
+	colored = append(colored, code...)
+	colored = append(colored, text...)
+	return append(colored, sgrReset...)
+}
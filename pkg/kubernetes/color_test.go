@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func newColorOptions(t *testing.T, colorBy string) LikeOptions {
+	t.Helper()
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.ColorBy = colorBy
+	l.colorize = true
+	severityOf, err := newSeverityParser("auto", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.severityOf = severityOf
+	return l
+}
+
+func TestColorLineByLevel(t *testing.T) {
+	l := newColorOptions(t, colorByLevel)
+	tests := []struct {
+		line string
+		sgr  string
+	}{
+		{line: `{"level":"error","msg":"boom"}`, sgr: sgrLevelErr},
+		{line: `level=fatal msg="out of memory"`, sgr: sgrLevelErr},
+		{line: "E0612 10:04:05.123456       1 main.go:12] boom", sgr: sgrLevelErr},
+		{line: "2024-06-12T10:04:05Z [warn] disk is almost full", sgr: sgrLevelWarn},
+		{line: `{"severity":"WARNING","msg":"slow"}`, sgr: sgrLevelWarn},
+		{line: "INFO starting"},
+		{line: "no level at all"},
+	}
+	for _, test := range tests {
+		want := test.line
+		if test.sgr != "" {
+			want = test.sgr + test.line + sgrReset
+		}
+		if got := string(l.colorLine([]byte(test.line), "")); got != want {
+			t.Errorf("%s: got %q, want %q", test.line, got, want)
+		}
+	}
+}
+
+func TestColorLineByMatch(t *testing.T) {
+	l := newColorOptions(t, colorByMatch)
+	l.patternRegexp = regexp.MustCompile("ERR")
+	got := string(l.colorLine([]byte("ERROR, then ERR again"), ""))
+	if want := sgrMatch + "ERR" + sgrReset + "OR, then " + sgrMatch + "ERR" + sgrReset + " again"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestColorByPodUsesPrefixPalette(t *testing.T) {
+	l := newColorOptions(t, colorByPod)
+	l.Prefix = true
+	var out bytes.Buffer
+	ref := corev1.ObjectReference{Namespace: "test", Name: "api-1", FieldPath: "spec.containers{app}"}
+	if _, err := l.writerFor(ref, &out).Write([]byte("INFO starting\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	color := podColor("test", "api-1")
+	if !slices.Contains(prefixPalette, color) {
+		t.Fatalf("%q is not in the prefix palette", color)
+	}
+	want := color + "[pod/api-1/app]" + sgrReset + " " + color + "INFO starting" + sgrReset + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestColorWriterWithoutColors(t *testing.T) {
+	l := newColorOptions(t, colorByLevel)
+	l.colorize = false
+	var out bytes.Buffer
+	ref := corev1.ObjectReference{Namespace: "test", Name: "api-1", FieldPath: "spec.containers{app}"}
+	if _, err := l.writerFor(ref, &out).Write([]byte("ERROR boom\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "\x1b[") {
+		t.Errorf("got colors without --color: %q", got)
+	}
+}
+
+func TestCompleteColor(t *testing.T) {
+	tests := []struct {
+		name       string
+		color      string
+		noColor    bool
+		noColorEnv bool
+		outputFile string
+		want       bool
+	}{
+		{name: "auto to a buffer", color: colorAuto},
+		{name: "auto with NO_COLOR", color: colorAuto, noColorEnv: true},
+		{name: "always to a buffer", color: colorAlways, want: true},
+		{name: "always with NO_COLOR", color: colorAlways, noColorEnv: true, want: true},
+		{name: "always with an output file", color: colorAlways, outputFile: "out.log", want: true},
+		{name: "never", color: colorNever},
+		{name: "--no-color overrides always", color: colorAlways, noColor: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.noColorEnv {
+				t.Setenv("NO_COLOR", "1")
+			}
+			streams, _, _, _ := genericiooptions.NewTestIOStreams()
+			l := NewLikeOptions(streams)
+			l.Color = test.color
+			l.NoColor = test.noColor
+			l.OutputFile = test.outputFile
+			if err := l.completeColor(); err != nil {
+				t.Fatal(err)
+			}
+			if l.colorize != test.want || l.colorizeErr != test.want {
+				t.Errorf("got colorize %v and colorizeErr %v, want %v", l.colorize, l.colorizeErr, test.want)
+			}
+		})
+	}
+
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.Color = "sometimes"
+	if err := l.completeColor(); err == nil || !strings.Contains(err.Error(), `unknown --color "sometimes"`) {
+		t.Errorf("expected an unknown --color error, got %v", err)
+	}
+}
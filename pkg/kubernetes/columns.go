@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// columnRange is the 1-based, inclusive range of columns of --match-columns. An end of 0 means the end of the line.
+type columnRange struct {
+	start int
+	end   int
+}
+
+// parseColumnRange parses START:END, where either side can be omitted, e.g. 20:40, 20: or :40
+func parseColumnRange(expr string) (*columnRange, error) {
+	start, end, found := strings.Cut(expr, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid --match-columns %q, must be START:END", expr)
+	}
+	r := &columnRange{start: 1}
+	var err error
+	if start != "" {
+		if r.start, err = strconv.Atoi(start); err != nil || r.start < 1 {
+			return nil, fmt.Errorf("invalid --match-columns %q, START must be a column number starting at 1", expr)
+		}
+	}
+	if end != "" {
+		if r.end, err = strconv.Atoi(end); err != nil || r.end < r.start {
+			return nil, fmt.Errorf("invalid --match-columns %q, END must be a column number not before START", expr)
+		}
+	}
+	return r, nil
+}
+
+// columns returns the part of the line within the range, counting bytes or runes.
+// Lines shorter than the start of the range have nothing to match.
+func (r columnRange) columns(line []byte, runes bool) []byte {
+	line = bytes.TrimRight(line, "\r\n")
+	start, end := r.start-1, r.end
+	if runes {
+		start, end = runeOffset(line, start), runeOffset(line, end)
+	}
+	if start >= len(line) {
+		return nil
+	}
+	if end == 0 || end > len(line) {
+		end = len(line)
+	}
+	return line[start:end]
+}
+
+// runeOffset returns the byte offset of the n-th rune of the line, or the length of the line if it is shorter
+func runeOffset(line []byte, n int) int {
+	if n == 0 {
+		return 0
+	}
+	offset := 0
+	for i := 0; i < n; i++ {
+		if offset >= len(line) {
+			return len(line) + 1
+		}
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+	return offset
+}
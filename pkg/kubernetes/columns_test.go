@@ -0,0 +1,51 @@
+package kubernetes
+
+import "testing"
+
+func TestParseColumnRange(t *testing.T) {
+	for expr, want := range map[string]columnRange{
+		"20:40": {start: 20, end: 40},
+		"20:":   {start: 20},
+		":40":   {start: 1, end: 40},
+		":":     {start: 1},
+		"5:5":   {start: 5, end: 5},
+	} {
+		r, err := parseColumnRange(expr)
+		if err != nil {
+			t.Errorf("%q: %v", expr, err)
+			continue
+		}
+		if *r != want {
+			t.Errorf("%q: got %+v, want %+v", expr, *r, want)
+		}
+	}
+	for _, expr := range []string{"20", "0:10", "a:10", "10:5", "1:x"} {
+		if _, err := parseColumnRange(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestColumnRangeColumns(t *testing.T) {
+	tests := []struct {
+		r     columnRange
+		line  string
+		runes bool
+		want  string
+	}{
+		{r: columnRange{start: 3, end: 5}, line: "abcdefg\n", want: "cde"},
+		{r: columnRange{start: 3}, line: "abcdefg\r\n", want: "cdefg"},
+		{r: columnRange{start: 1, end: 2}, line: "abcdefg\n", want: "ab"},
+		{r: columnRange{start: 5, end: 40}, line: "abcdefg\n", want: "efg"},
+		{r: columnRange{start: 8}, line: "abcdefg\n", want: ""},
+		// bytes split the characters, runes do not
+		{r: columnRange{start: 2, end: 3}, line: "ééabc\n", want: "\xa9\xc3"},
+		{r: columnRange{start: 2, end: 3}, line: "ééabc\n", runes: true, want: "éa"},
+		{r: columnRange{start: 6}, line: "ééabc\n", runes: true, want: ""},
+	}
+	for _, test := range tests {
+		if got := string(test.r.columns([]byte(test.line), test.runes)); got != test.want {
+			t.Errorf("%+v of %q (runes %v): got %q, want %q", test.r, test.line, test.runes, got, test.want)
+		}
+	}
+}
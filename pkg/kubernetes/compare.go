@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// compareGroup is one of the two groups of pods of --compare
+type compareGroup struct {
+	options  *LikeOptions
+	requests map[corev1.ObjectReference]rest.ResponseWrapper
+	pods     int
+}
+
+// forSelector returns a copy of the options selecting the pods matching the label selector
+func (l *LikeOptions) forSelector(selector string) *LikeOptions {
+	logsOptions := *l.LogsOptions
+	c := *l
+	c.LogsOptions = &logsOptions
+	c.Selector = selector
+	c.ResourceArgs = nil
+	c.Object = nil
+	c.objects = nil
+	return &c
+}
+
+// resolveCompareGroups resolves the pods of the two selectors given with --compare
+func (l *LikeOptions) resolveCompareGroups() ([]*LikeOptions, error) {
+	groups := make([]*LikeOptions, 0, len(l.compareSelectors))
+	for _, selector := range l.compareSelectors {
+		c := l.forSelector(selector)
+		objects, err := c.resolveObjects()
+		if err != nil {
+			return nil, err
+		}
+		c.objects = objects
+		c.Object = objects[0]
+		groups = append(groups, c)
+	}
+	return groups, nil
+}
+
+// runCompare streams the pods of both groups, for --for if set, then prints the match rate per pod
+// of each group. An error is returned when the rate of the second group is more than
+// --compare-threshold percent above the rate of the first one.
+func (l LikeOptions) runCompare() error {
+	counts := newMatchCounts("group", l.ErrOut)
+	// the comparison replaces the counts, also when interrupted
+	counts.done = true
+	groups := make([]compareGroup, 0, len(l.compareOptions))
+	total := 0
+	for _, c := range l.compareOptions {
+		requests, err := c.logRequests()
+		if err != nil {
+			return err
+		}
+		pods := map[string]bool{}
+		for ref := range requests {
+			pods[ref.Namespace+"/"+ref.Name] = true
+		}
+		c.Out = l.Out
+		c.outputs = l.outputs
+		c.capture = l.capture
+		c.heartbeat = l.heartbeat
+		c.lineExec = l.lineExec
+		c.notifySinks = l.notifySinks
+		c.stats = l.stats
+		c.streamMetrics = l.streamMetrics
+		c.snapshot = l.snapshot
+		c.matchCounts = l.matchCounts
+		c.tally = l.tally
+		groups = append(groups, compareGroup{options: c, requests: requests, pods: len(pods)})
+		total += len(requests)
+	}
+
+	var targets []logTarget
+	for _, group := range groups {
+		targets = append(targets, group.options.logTargets(group.requests)...)
+	}
+	if l.DryRun {
+		return l.printDryRun(targets)
+	}
+	if err := l.printBanner(targets); err != nil {
+		return err
+	}
+	if err := l.printRecordHeader(); err != nil {
+		return err
+	}
+	if l.Follow {
+		if err := l.checkFollowConcurrency(total); err != nil {
+			return err
+		}
+	}
+	l.idle.start()
+	l.deadline.start()
+	l.noMatch.start()
+
+	var streams []logStream
+	for _, group := range groups {
+		selector := group.options.Selector
+		for _, s := range group.options.logStreams(group.requests) {
+			s.wrap = func(w io.Writer) io.Writer { return counts.writer(selector, w) }
+			streams = append(streams, s)
+		}
+	}
+	// without --for the comparison is printed when interrupted, and still decides the exit code
+	l.onInterrupt(func() { cmdutil.CheckErr(l.printComparison(groups, counts)) })
+	// --for stops the streams like an interrupt
+	mux := newMultiplexer(l.Out, l.ErrOut, l.PerSourceBuffer, l.stats)
+	err := consumeStreams(mux, streams)
+	if err != nil && !errors.Is(err, ErrInterrupted) {
+		return err
+	}
+	// an interrupted comparison is printed too, and still decides the exit code
+	if compareErr := l.printComparison(groups, counts); compareErr != nil {
+		return compareErr
+	}
+	return err
+}
+
+// printComparison writes the match rate per pod of both groups and fails if the second one is too high
+func (l LikeOptions) printComparison(groups []compareGroup, counts *matchCounts) error {
+	rates := make([]float64, len(groups))
+	fmt.Fprintln(l.Out, "Comparison of matching lines:")
+	for i, group := range groups {
+		lines := counts.matched.get(group.options.Selector)
+		if group.pods > 0 {
+			rates[i] = float64(lines) / float64(group.pods)
+		}
+		fmt.Fprintf(l.Out, "  %s: %d line(s) from %d pod(s), %.2f per pod\n", group.options.Selector, lines, group.pods, rates[i])
+	}
+
+	stable, canary := groups[0].options.Selector, groups[1].options.Selector
+	var difference float64
+	switch {
+	case rates[0] > 0:
+		difference = (rates[1] - rates[0]) / rates[0] * 100
+	case rates[1] > 0:
+		difference = math.Inf(1)
+	}
+	fmt.Fprintf(l.Out, "  %s: %+.1f%% per pod compared to %s (threshold %g%%)\n", canary, difference, stable, l.CompareThreshold)
+	if difference > l.CompareThreshold {
+		return fmt.Errorf("the match rate of %s is %+.1f%% compared to %s, above --compare-threshold=%g", canary, difference, stable, l.CompareThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// newCompareGroup returns the resolved options of a group of pods of the ReplicaSet hash, every pod logging log
+func newCompareGroup(t *testing.T, selector, hash, log string, pods ...string) *LikeOptions {
+	t.Helper()
+	api := &fakeAPI{objects: map[string]runtime.Object{}, logs: map[string]string{}}
+	list := &corev1.PodList{}
+	for _, name := range pods {
+		pod := testPod(name, corev1.PodRunning, map[string]string{"pod-template-hash": hash})
+		list.Items = append(list.Items, pod)
+		api.objects["/namespaces/test/pods/"+name] = &pod
+		api.logs["/namespaces/test/pods/"+name+"/log"] = log
+	}
+	c, _, _ := newFakeOptions(t, api)
+	useFakeLogs(t, &c)
+	c.Selector = selector
+	c.Options = &corev1.PodLogOptions{}
+	c.objects = []runtime.Object{list}
+	c.Object = list
+	c.patternRegexp = regexp.MustCompile("ERROR")
+	c.ConsumeRequestFn = c.DefaultConsumeRequest
+	return &c
+}
+
+func newCompareOptions(t *testing.T, stableLog, canaryLog string) (LikeOptions, *bytes.Buffer) {
+	t.Helper()
+	l, out, _ := newFakeOptions(t, &fakeAPI{})
+	l.PerSourceBuffer = defaultPerSourceBuffer
+	l.CompareThreshold = 10
+	l.compareOptions = []*LikeOptions{
+		newCompareGroup(t, "track=stable", "aaa", stableLog, "stable-1", "stable-2"),
+		newCompareGroup(t, "track=canary", "bbb", canaryLog, "canary-1"),
+	}
+	return l, out
+}
+
+func TestRunCompareWithinThreshold(t *testing.T) {
+	l, out := newCompareOptions(t, "ERROR a\nINFO b\nERROR c\n", "ERROR a\nERROR c\n")
+	if err := l.runCompare(); err != nil {
+		t.Fatal(err)
+	}
+	want := "Comparison of matching lines:\n" +
+		"  track=stable: 4 line(s) from 2 pod(s), 2.00 per pod\n" +
+		"  track=canary: 2 line(s) from 1 pod(s), 2.00 per pod\n" +
+		"  track=canary: +0.0% per pod compared to track=stable (threshold 10%)\n"
+	if got := out.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got\n%s\nwant it to end with\n%s", got, want)
+	}
+}
+
+func TestRunCompareAboveThreshold(t *testing.T) {
+	l, out := newCompareOptions(t, "ERROR a\nINFO b\n", "ERROR a\nERROR b\n")
+	err := l.runCompare()
+	if err == nil || !strings.Contains(err.Error(), "+100.0%") {
+		t.Errorf("expected the canary rate to be above the threshold, got %v", err)
+	}
+	if !strings.Contains(out.String(), "track=canary: 2 line(s) from 1 pod(s), 2.00 per pod") {
+		t.Errorf("the comparison was not printed: %q", out.String())
+	}
+}
+
+func TestRunCompareGroupsByReplicaSet(t *testing.T) {
+	l, _ := newCompareOptions(t, "ERROR a\n", "ERROR a\nERROR b\n")
+	var summary bytes.Buffer
+	l.matchCounts = newMatchCounts("ReplicaSet", &summary)
+	for _, c := range l.compareOptions {
+		c.byReplicaSet = true
+	}
+	if err := l.runCompare(); err == nil {
+		t.Fatal("expected the canary rate to be above the threshold")
+	}
+	l.matchCounts.Print()
+	if got, want := summary.String(), "Matching lines per ReplicaSet:\n  rs:aaa: 2\n  rs:bbb: 2\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilcomp "k8s.io/kubectl/pkg/util/completion"
+)
+
+// loggableKinds are the kinds whose pods can be streamed, by the logs helpers of kubectl or, for a CronJob,
+// through its latest Job
+var loggableKinds = []schema.GroupKind{
+	{Kind: "Pod"},
+	{Kind: "ReplicationController"},
+	{Kind: "Service"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "ReplicaSet"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "batch", Kind: "Job"},
+	{Group: "batch", Kind: "CronJob"},
+}
+
+// loggableResourceTypes returns the resource of every loggable kind known to the REST mapper, e.g. deployments
+func (l *LikeOptions) loggableResourceTypes() []string {
+	mapper, err := l.factory.ToRESTMapper()
+	if err != nil {
+		return nil
+	}
+	var resources []string
+	for _, kind := range loggableKinds {
+		// every version of a kind is served by the same resource
+		mappings, err := mapper.RESTMappings(kind)
+		if err != nil || len(mappings) == 0 {
+			continue
+		}
+		resources = append(resources, mappings[0].Resource.Resource)
+	}
+	return resources
+}
+
+// completeResourceArg completes a POD or TYPE/NAME argument: the pods and the TYPE/ prefixes of the loggable
+// resource types before a slash, then the names of that type after it
+func (l *LikeOptions) completeResourceArg(toComplete string) ([]string, cobra.ShellCompDirective) {
+	directive := cobra.ShellCompDirectiveNoFileComp
+	if resourceType, name, found := strings.Cut(toComplete, "/"); found {
+		var comps []string
+		for _, comp := range utilcomp.CompGetResource(l.factory, resourceType, name) {
+			comps = append(comps, resourceType+"/"+comp)
+		}
+		return comps, directive
+	}
+
+	comps := utilcomp.CompGetResource(l.factory, "pod", toComplete)
+	if len(comps) == 0 {
+		// only TYPE/ is completed, the name follows without a space
+		directive |= cobra.ShellCompDirectiveNoSpace
+	}
+	for _, resource := range l.loggableResourceTypes() {
+		if strings.HasPrefix(resource, toComplete) {
+			comps = append(comps, resource+"/")
+		}
+	}
+	return comps, directive
+}
@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestResourceArgCompletion(t *testing.T) {
+	deployments := &appsv1.DeploymentList{Items: []appsv1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "api"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker"}},
+	}}
+	pods := &corev1.PodList{Items: []corev1.Pod{testPod("api-1", corev1.PodRunning, nil)}}
+	api := &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/deployments": deployments, "/namespaces/test/pods": pods}}
+	l, cmd, _, _ := newFakeCommand(t, api)
+	// completion gets the resources like kubectl get, as unstructured objects
+	l.factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+		Client:               fake.CreateHTTPClient(api.roundTrip),
+	}
+	l.RegisterCompletionFunc(cmd)
+
+	for _, test := range []struct {
+		toComplete string
+		want       []string
+		directive  cobra.ShellCompDirective
+	}{
+		{"deploy", []string{"deployments/"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"c", []string{"cronjobs/"}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace},
+		{"", []string{"api-1", "cronjobs/", "daemonsets/", "deployments/", "jobs/", "pods/", "replicasets/", "replicationcontrollers/", "services/", "statefulsets/"}, cobra.ShellCompDirectiveNoFileComp},
+		{"deployments/w", []string{"deployments/worker"}, cobra.ShellCompDirectiveNoFileComp},
+	} {
+		comps, directive := cmd.ValidArgsFunction(cmd, []string{"api-2"}, test.toComplete)
+		slices.Sort(comps)
+		if !slices.Equal(comps, test.want) {
+			t.Errorf("completing %q got %q, want %q", test.toComplete, strings.Join(comps, " "), strings.Join(test.want, " "))
+		}
+		if directive != test.directive {
+			t.Errorf("completing %q got directive %v, want %v", test.toComplete, directive, test.directive)
+		}
+	}
+}
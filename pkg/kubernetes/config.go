@@ -0,0 +1,31 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// applyConfig sets the flags that were not given on the command line to the values viper read
+// from the environment or the config file. Flags given on the command line always win.
+func (l *LikeOptions) applyConfig(cmd *cobra.Command) error {
+	var errs []error
+	cmd.Flags().VisitAll(func(f *flag.Flag) {
+		if f.Changed || !viper.IsSet(f.Name) {
+			return
+		}
+		if slice, ok := f.Value.(flag.SliceValue); ok {
+			if err := slice.Replace(viper.GetStringSlice(f.Name)); err != nil {
+				errs = append(errs, fmt.Errorf("invalid config value for %s: %w", f.Name, err))
+			}
+			return
+		}
+		if err := cmd.Flags().Set(f.Name, viper.GetString(f.Name)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid config value for %s: %w", f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func TestApplyConfig(t *testing.T) {
+	readConfig(t, "pattern: from-config\nignore-case: true\nexclude: [DEBUG, TRACE]\n")
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	cmd := &cobra.Command{Use: "like"}
+	l.AddFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--exclude", "INFO"}); err != nil {
+		t.Fatal(err)
+	}
+	viper.BindPFlags(cmd.Flags())
+
+	if err := l.applyConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if l.Pattern != "from-config" || !l.IgnoreCase {
+		t.Errorf("got --pattern %q --ignore-case %t, want the values of the config file", l.Pattern, l.IgnoreCase)
+	}
+	if len(l.Exclude) != 1 || l.Exclude[0] != "INFO" {
+		t.Errorf("got --exclude %q, want the flag to win over the config file", l.Exclude)
+	}
+}
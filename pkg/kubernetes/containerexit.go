@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerExitError is returned by Run with --exit-with-container when a followed container exited with a
+// non-zero code, so that the command exits with the same code
+type ContainerExitError struct {
+	Source   string
+	ExitCode int
+	Reason   string
+}
+
+func (e *ContainerExitError) Error() string {
+	if len(e.Reason) == 0 {
+		return fmt.Sprintf("container %s exited with code %d", e.Source, e.ExitCode)
+	}
+	return fmt.Sprintf("container %s exited with code %d (%s)", e.Source, e.ExitCode, e.Reason)
+}
+
+// containerExits keeps the first non-zero exit of the followed containers for --exit-with-container
+type containerExits struct {
+	mu   sync.Mutex
+	exit *ContainerExitError
+}
+
+// add records the exit of a container. It is a no-op on a nil recorder.
+func (e *containerExits) add(source string, terminated *corev1.ContainerStateTerminated) {
+	if e == nil || terminated.ExitCode == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exit == nil {
+		e.exit = &ContainerExitError{Source: source, ExitCode: int(terminated.ExitCode), Reason: terminated.Reason}
+	}
+}
+
+// Err returns the first non-zero exit recorded, nil otherwise or on a nil recorder
+func (e *containerExits) Err() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.exit == nil {
+		return nil
+	}
+	return e.exit
+}
+
+// reportExit writes why the followed container ended once its stream is over, and records its exit code for
+// --exit-with-container. Nothing is written when the container is not terminated, e.g. the pod was deleted.
+func (l LikeOptions) reportExit(ref corev1.ObjectReference) error {
+	status, err := l.containerStatus(ref)
+	if err != nil {
+		l.logger.Debug("no status of the ended container", "namespace", ref.Namespace, "pod", ref.Name, "error", err)
+		return nil
+	}
+	terminated := status.State.Terminated
+	if terminated == nil {
+		return nil
+	}
+	l.exits.add(l.sourceName(ref), terminated)
+	// the marker is not a line of the container, see noticeWriter
+	_, err = fmt.Fprintf(l.noticeWriter(), "=== container exited (%s) ===\n", exitDescription(terminated))
+	return err
+}
+
+// exitDescription describes the termination of a container, e.g. exit code 137, reason OOMKilled, finished at
+// 2024-06-12T10:04:05Z
+func exitDescription(terminated *corev1.ContainerStateTerminated) string {
+	parts := []string{fmt.Sprintf("exit code %d", terminated.ExitCode)}
+	if len(terminated.Reason) > 0 {
+		parts = append(parts, "reason "+terminated.Reason)
+	}
+	if !terminated.FinishedAt.IsZero() {
+		parts = append(parts, "finished at "+terminated.FinishedAt.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(parts, ", ")
+}
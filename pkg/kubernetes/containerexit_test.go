@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// exitedPod returns a pod whose app container exited and is not restarted
+func exitedPod(phase corev1.PodPhase, exitCode int32, reason string, finishedAt time.Time) *corev1.Pod {
+	pod := testPod("api-1", phase, nil)
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "app",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: reason, FinishedAt: metav1.NewTime(finishedAt)}},
+	}}
+	return &pod
+}
+
+func TestFollowReportsContainerExit(t *testing.T) {
+	finishedAt := time.Date(2024, 6, 12, 10, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		wantMarker string
+		wantCode   int
+	}{
+		{
+			name:       "OOMKilled",
+			pod:        exitedPod(corev1.PodFailed, 137, "OOMKilled", finishedAt),
+			wantMarker: "=== container exited (exit code 137, reason OOMKilled, finished at 2024-06-12T10:04:05Z) ===\n",
+			wantCode:   137,
+		},
+		{
+			name:       "Completed",
+			pod:        exitedPod(corev1.PodSucceeded, 0, "Completed", finishedAt),
+			wantMarker: "=== container exited (exit code 0, reason Completed, finished at 2024-06-12T10:04:05Z) ===\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shortReattachInterval(t)
+			api := &fakeAPI{
+				sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {restartedPod(0, time.Now()), test.pod}},
+				logs:      map[string]string{"/namespaces/test/pods/api-1/log": "INFO serving\nERROR crashed\n"},
+			}
+			l, errOut := newRestartOptions(t, api)
+			l.PreviousAndCurrent = false
+			l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+			l.exits = &containerExits{}
+			request, err := l.instanceRequest(appRef, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := l.followWithReattach(appRef, request, &out); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := out.String(), "ERROR crashed\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if got := errOut.String(); got != test.wantMarker {
+				t.Errorf("got marker %q, want %q", got, test.wantMarker)
+			}
+			err = l.exits.Err()
+			var exitErr *ContainerExitError
+			switch {
+			case test.wantCode == 0 && err != nil:
+				t.Errorf("got %v, want no error for a container exiting with code 0", err)
+			case test.wantCode != 0 && (!errors.As(err, &exitErr) || exitErr.ExitCode != test.wantCode):
+				t.Errorf("got %v, want the exit code %d", err, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestFollowWithoutReattachReportsContainerExit(t *testing.T) {
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": exitedPod(corev1.PodFailed, 1, "Error", time.Time{})},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR crashed\n"},
+	}
+	l, errOut := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	l.Follow = true
+	l.NoReattach = true
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.consumeRequest(appRef, request, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := errOut.String(), "=== container exited (exit code 1, reason Error) ===\n"; got != want {
+		t.Errorf("got marker %q, want %q", got, want)
+	}
+}
+
+func TestExitWithContainerRequiresFollow(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--exit-with-container", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected --exit-with-container without --follow to be rejected")
+	}
+}
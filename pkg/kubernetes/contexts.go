@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	utilcomp "k8s.io/kubectl/pkg/util/completion"
+)
+
+// contextRequests are the log requests resolved in one of the --contexts
+type contextRequests struct {
+	options  *LikeOptions
+	requests map[corev1.ObjectReference]rest.ResponseWrapper
+}
+
+// forContext returns a copy of the options talking to the cluster of the given kubeconfig context.
+// The other client flags are shared, except the cluster and user which are defined by the context.
+func (l *LikeOptions) forContext(context string) *LikeOptions {
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.CacheDir = l.KubernetesConfigFlags.CacheDir
+	flags.KubeConfig = l.KubernetesConfigFlags.KubeConfig
+	flags.Context = &context
+	flags.Namespace = l.KubernetesConfigFlags.Namespace
+	flags.APIServer = l.KubernetesConfigFlags.APIServer
+	flags.TLSServerName = l.KubernetesConfigFlags.TLSServerName
+	flags.Insecure = l.KubernetesConfigFlags.Insecure
+	flags.CertFile = l.KubernetesConfigFlags.CertFile
+	flags.KeyFile = l.KubernetesConfigFlags.KeyFile
+	flags.CAFile = l.KubernetesConfigFlags.CAFile
+	flags.BearerToken = l.KubernetesConfigFlags.BearerToken
+	flags.Impersonate = l.KubernetesConfigFlags.Impersonate
+	flags.ImpersonateUID = l.KubernetesConfigFlags.ImpersonateUID
+	flags.ImpersonateGroup = l.KubernetesConfigFlags.ImpersonateGroup
+	flags.Username = l.KubernetesConfigFlags.Username
+	flags.Password = l.KubernetesConfigFlags.Password
+	flags.Timeout = l.KubernetesConfigFlags.Timeout
+	flags.DisableCompression = l.KubernetesConfigFlags.DisableCompression
+
+	logsOptions := *l.LogsOptions
+	c := *l
+	c.LogsOptions = &logsOptions
+	c.KubernetesConfigFlags = flags
+	c.factory = cmdutil.NewFactory(flags)
+	c.contextName = context
+	c.Object = nil
+	c.objects = nil
+	c.contextOptions = nil
+	return &c
+}
+
+// checkContext fails early when --context names a context missing from the kubeconfig, listing the available
+// ones, instead of the obscure error of the first request
+func (l LikeOptions) checkContext() error {
+	if l.KubernetesConfigFlags == nil || l.KubernetesConfigFlags.Context == nil || len(*l.KubernetesConfigFlags.Context) == 0 {
+		return nil
+	}
+	context := *l.KubernetesConfigFlags.Context
+	utilcomp.SetFactoryForCompletion(l.factory)
+	contexts := utilcomp.ListContextsInConfig("")
+	// an empty list is also returned when the kubeconfig cannot be loaded, whose error is reported by the loader
+	if len(contexts) == 0 || slices.Contains(contexts, context) {
+		return nil
+	}
+	sort.Strings(contexts)
+	return fmt.Errorf("context %q not found in the kubeconfig, available contexts: %s", context, strings.Join(contexts, ", "))
+}
+
+// the sources of the namespace of the logs, from the most to the least specific
+const (
+	namespaceFromFlag    = "flag"
+	namespaceFromContext = "context"
+	namespaceFromDefault = "default"
+)
+
+// resolveNamespace records the namespace of the logs and where it comes from: --namespace, the namespace of the
+// context, or default when the context has none
+func (l *LikeOptions) resolveNamespace() error {
+	namespace, explicit, err := l.factory.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	l.Namespace = namespace
+	switch {
+	case explicit:
+		l.namespaceFrom = namespaceFromFlag
+	case l.contextNamespace() == namespace:
+		l.namespaceFrom = namespaceFromContext
+	default:
+		l.namespaceFrom = namespaceFromDefault
+	}
+	return nil
+}
+
+// contextNamespace returns the namespace of the current context in the kubeconfig, if any
+func (l LikeOptions) contextNamespace() string {
+	config, err := l.factory.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	if context, ok := config.Contexts[l.currentContext()]; ok {
+		return context.Namespace
+	}
+	return ""
+}
+
+// namespaceDescription returns the namespace followed by where it comes from, e.g. payments (from context prod)
+func (l LikeOptions) namespaceDescription() string {
+	switch l.namespaceFrom {
+	case namespaceFromFlag:
+		return l.Namespace + " (from --namespace)"
+	case namespaceFromContext:
+		return fmt.Sprintf("%s (from context %s)", l.Namespace, l.currentContext())
+	case namespaceFromDefault:
+		return l.Namespace + " (default)"
+	}
+	return l.Namespace
+}
+
+// resolveContexts resolves the objects in every context of --contexts.
+// A context that cannot be resolved, e.g. because its cluster is unreachable, is skipped with a warning.
+func (l *LikeOptions) resolveContexts() ([]*LikeOptions, error) {
+	var resolved []*LikeOptions
+	for _, context := range l.Contexts {
+		c := l.forContext(context)
+		err := c.resolveNamespace()
+		if err == nil {
+			err = c.useFactory()
+		}
+		if err == nil {
+			c.objects, err = c.resolveObjects()
+		}
+		if err != nil {
+			fmt.Fprintf(l.ErrOut, "warning: skipping context %s: %v\n", context, err)
+			continue
+		}
+		c.Object = c.objects[0]
+		resolved = append(resolved, c)
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("none of the contexts %v could be resolved", l.Contexts)
+	}
+	return resolved, nil
+}
+
+// runContexts streams the logs of the resolved objects of every context
+func (l LikeOptions) runContexts() error {
+	var all []contextRequests
+	var targets []logTarget
+	total := 0
+	for _, c := range l.contextOptions {
+		requests, err := c.logRequests()
+		if err != nil {
+			fmt.Fprintf(l.ErrOut, "warning: skipping context %s: %v\n", c.contextName, err)
+			continue
+		}
+		c.Out = l.Out
+		c.outputs = l.outputs
+		c.capture = l.capture
+		c.heartbeat = l.heartbeat
+		c.lineExec = l.lineExec
+		c.notifySinks = l.notifySinks
+		c.stats = l.stats
+		c.streamMetrics = l.streamMetrics
+		c.snapshot = l.snapshot
+		c.matchCounts = l.matchCounts
+		c.tally = l.tally
+		all = append(all, contextRequests{options: c, requests: requests})
+		targets = append(targets, c.logTargets(requests)...)
+		total += len(requests)
+	}
+
+	if l.DryRun {
+		return l.printDryRun(targets)
+	}
+	if err := l.printBanner(targets); err != nil {
+		return err
+	}
+	if l.Verbose {
+		l.printTargets(targets)
+	}
+	if err := l.printRecordHeader(); err != nil {
+		return err
+	}
+	l.idle.start()
+	l.deadline.start()
+	l.noMatch.start()
+
+	if l.InitContainers {
+		for _, cr := range all {
+			terminated, err := cr.options.terminatedInitContainerRequests(cr.requests)
+			if err != nil {
+				return err
+			}
+			total -= len(terminated)
+			if err := cr.options.sequentialConsumeRequest(terminated); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !l.Follow {
+		for _, cr := range all {
+			if err := cr.options.sequentialConsumeRequest(cr.requests); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := l.checkFollowConcurrency(total); err != nil {
+		return err
+	}
+	var streams []logStream
+	for _, cr := range all {
+		streams = append(streams, cr.options.logStreams(cr.requests)...)
+	}
+	return consumeStreams(newMultiplexer(l.Out, l.ErrOut, l.PerSourceBuffer, l.stats), streams)
+}
@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+// newContextOptions returns the resolved options of a context whose api-1 pod logs the given lines
+func newContextOptions(t *testing.T, context, log string) *LikeOptions {
+	t.Helper()
+	pod := testPod("api-1", corev1.PodRunning, nil)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": &pod},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": log},
+	}
+	c, _, _ := newFakeOptions(t, api)
+	c.contextName = context
+	useFakeLogs(t, &c)
+	c.Options = &corev1.PodLogOptions{Follow: true}
+	c.objects = []runtime.Object{&pod}
+	c.Object = &pod
+	c.Follow = true
+	c.NoReattach = true
+	c.Prefix = true
+	c.MaxFollowConcurrency = 5
+	c.patternRegexp = regexp.MustCompile("ERROR")
+	c.ConsumeRequestFn = c.DefaultConsumeRequest
+	return &c
+}
+
+func TestRunContextsStreamsEveryContext(t *testing.T) {
+	var out bytes.Buffer
+	l, _, _ := newFakeOptions(t, &fakeAPI{})
+	l.Out = &out
+	l.Follow = true
+	l.MaxFollowConcurrency = 5
+	l.PerSourceBuffer = defaultPerSourceBuffer
+	l.contextOptions = []*LikeOptions{
+		newContextOptions(t, "eu", "INFO ok\nERROR eu failed\n"),
+		newContextOptions(t, "us", "ERROR us failed\nINFO ok\n"),
+	}
+
+	if err := l.runContexts(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	slices.Sort(lines)
+	want := []string{
+		"[eu|test/api-1/app] ERROR eu failed",
+		"[us|test/api-1/app] ERROR us failed",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestRunContextsChecksFollowConcurrency(t *testing.T) {
+	l, _, _ := newFakeOptions(t, &fakeAPI{})
+	l.Follow = true
+	l.MaxFollowConcurrency = 1
+	l.contextOptions = []*LikeOptions{
+		newContextOptions(t, "eu", ""),
+		newContextOptions(t, "us", ""),
+	}
+	if err := l.runContexts(); err == nil || !strings.Contains(err.Error(), "you are attempting to follow 2 log streams") {
+		t.Errorf("expected a concurrency error, got %v", err)
+	}
+}
+
+func TestCompleteChecksContext(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	for _, name := range []string{"prod", "dev"} {
+		config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name + ".example.com"}
+		config.AuthInfos[name] = &clientcmdapi.AuthInfo{}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: "test"}
+	}
+	config.CurrentContext = "dev"
+	for context, wantErr := range map[string]string{
+		"prod": "",
+		"prdo": `context "prdo" not found in the kubeconfig, available contexts: dev, prod`,
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		l.factory.(*cmdtesting.TestFactory).WithClientConfig(clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}))
+		err := completeFlags(l, cmd, []string{"--context", context}, "api-1")
+		switch {
+		case wantErr == "" && err != nil:
+			t.Errorf("--context %s: %v", context, err)
+		case wantErr != "" && (err == nil || err.Error() != wantErr):
+			t.Errorf("--context %s: got %v, want %s", context, err, wantErr)
+		}
+	}
+}
+
+func TestResolveNamespace(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod.example.com"}
+	config.AuthInfos["prod"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod", Namespace: "payments"}
+	config.Contexts["bare"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod"}
+	config.CurrentContext = "prod"
+	for _, test := range []struct {
+		flags []string
+		want  string
+	}{
+		{nil, "payments (from context prod)"},
+		{[]string{"--context", "bare"}, "default (default)"},
+		{[]string{"-n", "checkout"}, "checkout (from --namespace)"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		overrides := &clientcmd.ConfigOverrides{}
+		if err := cmd.ParseFlags(test.flags); err != nil {
+			t.Fatal(err)
+		}
+		overrides.CurrentContext = *l.KubernetesConfigFlags.Context
+		overrides.Context.Namespace = *l.KubernetesConfigFlags.Namespace
+		l.factory.(*cmdtesting.TestFactory).WithClientConfig(clientcmd.NewDefaultClientConfig(*config, overrides))
+		if err := l.resolveNamespace(); err != nil {
+			t.Fatal(err)
+		}
+		if got := l.namespaceDescription(); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.flags, got, test.want)
+		}
+	}
+}
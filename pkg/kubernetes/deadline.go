@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// deadlineAfterFunc calls f once d elapsed and returns the function stopping it, replaced by the tests with a
+// fake clock
+var deadlineAfterFunc = func(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// runDeadline cancels its context once --for elapsed, whatever the state of the streams. The context replaces
+// the one of the command, so that the streams, the watches and the sinks stop like when the command is
+// interrupted: the buffered output is flushed and the summaries are printed, but the run ends without error.
+type runDeadline struct {
+	duration time.Duration
+	out      io.Writer
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	stop    func() bool
+	expired bool
+}
+
+func newRunDeadline(parent context.Context, duration time.Duration, out io.Writer) *runDeadline {
+	ctx, cancel := context.WithCancel(parent)
+	return &runDeadline{
+		duration: duration,
+		out:      out,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// start starts the clock when the streams are about to be read, so that the time spent resolving the targets
+// is not counted. It is a no-op on a nil or started deadline.
+func (d *runDeadline) start() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop == nil {
+		d.stop = deadlineAfterFunc(d.duration, d.expire)
+	}
+}
+
+func (d *runDeadline) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// an interrupt came first
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.expired = true
+	fmt.Fprintf(d.out, "stopping after --for=%s\n", d.duration)
+	d.cancel()
+}
+
+// Stop stops the clock once the run is over. It is a no-op on a nil or unstarted deadline.
+func (d *runDeadline) Stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		d.stop()
+	}
+}
+
+// Expired tells whether the streams were stopped by the deadline. It is false on a nil deadline.
+func (d *runDeadline) Expired() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.expired
+}
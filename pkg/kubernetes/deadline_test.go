@@ -0,0 +1,138 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// fakeDeadline is a deadline of --for started on the fake clock of fakeDeadlineClock
+type fakeDeadline struct {
+	duration time.Duration
+	expire   func()
+}
+
+// fakeDeadlineClock replaces the clock of --for, the deadlines started on it only expire when the test says so
+func fakeDeadlineClock(t *testing.T) chan fakeDeadline {
+	t.Helper()
+	started := make(chan fakeDeadline, 1)
+	previous := deadlineAfterFunc
+	deadlineAfterFunc = func(d time.Duration, f func()) func() bool {
+		started <- fakeDeadline{duration: d, expire: f}
+		return func() bool { return true }
+	}
+	t.Cleanup(func() { deadlineAfterFunc = previous })
+	return started
+}
+
+// pipeLogs makes the logs of every container of l the followed stream read from the returned writer
+func pipeLogs(t *testing.T, l *LikeOptions) *io.PipeWriter {
+	t.Helper()
+	reader, writer := io.Pipe()
+	useFakeLogs(t, l)
+	logsForObject := l.LogsForObject
+	l.LogsForObject = func(getter genericclioptions.RESTClientGetter, object, options runtime.Object, timeout time.Duration, allContainers bool) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+		requests, err := logsForObject(getter, object, options, timeout, allContainers)
+		for ref := range requests {
+			requests[ref] = pipeRequest{reader: reader}
+		}
+		return requests, err
+	}
+	return writer
+}
+
+func TestForStopsTheRunAtTheDeadline(t *testing.T) {
+	started := fakeDeadlineClock(t)
+	l, cmd, out, errOut := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--no-reattach", "--pattern", "ERROR", "--for", "10m", "--stats", "--flush-interval", "1h", "--no-banner"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	// the followed stream never ends by itself
+	writer := pipeLogs(t, l)
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+
+	var deadline fakeDeadline
+	select {
+	case deadline = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the deadline was not started")
+	}
+	if deadline.duration != 10*time.Minute {
+		t.Errorf("got a deadline of %s, want 10m", deadline.duration)
+	}
+	io.WriteString(writer, "ERROR one\nINFO skipped\n")
+	deadline.expire()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("got %v, want the run to end without error at the deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run did not end at the deadline")
+	}
+	// the buffered line and the summary are written before the run ends
+	if got, want := out.String(), "ERROR one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, want := range []string{"stopping after --for=10m0s\n", "Matching lines per container:\n  test/api-1/app: 1\n"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("got %q, want %q", errOut.String(), want)
+		}
+	}
+}
+
+func TestForAfterAnInterrupt(t *testing.T) {
+	started := fakeDeadlineClock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var out strings.Builder
+	d := newRunDeadline(ctx, time.Minute, &out)
+	d.start()
+	d.start()
+	deadline := <-started
+
+	// the command was interrupted before the deadline, it exits like an interrupted one
+	cancel()
+	deadline.expire()
+	if d.Expired() || out.Len() > 0 {
+		t.Errorf("the deadline expired after an interrupt: %q", out.String())
+	}
+	var unstarted *runDeadline
+	unstarted.start()
+	unstarted.Stop()
+	if unstarted.Expired() {
+		t.Error("a nil deadline expired")
+	}
+}
+
+func TestForValidation(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--for", "-1s"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected a negative --for to be rejected")
+	}
+
+	// --for is no longer limited to --compare
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--for", "1m"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Error(err)
+	}
+	if l.runContext().Err() != nil {
+		t.Error("the run context is canceled before the deadline")
+	}
+}
@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// defaultDedupWindow is the number of distinct lines --dedup compares a line with
+const defaultDedupWindow = 4
+
+// lineDeduper drops the lines identical to one of the last distinct lines written, so that a line repeated
+// between a few other ones is collapsed too. Once a line leaves the window or the stream ends, a
+// "(repeated N times)" summary of its repeats is written to the notices, like syslog's "last message repeated".
+type lineDeduper struct {
+	out io.Writer
+	// notices receives the summaries, which are not lines of the container
+	notices io.Writer
+	// key returns the part of a line compared to the previous ones
+	key    func(line []byte) []byte
+	window int
+	recent []dedupEntry
+}
+
+// dedupEntry is a line of the window of the deduper and the number of times it was dropped since
+type dedupEntry struct {
+	key     []byte
+	repeats int
+}
+
+// dedupKey returns the part of a line compared by --dedup. The timestamp added by --timestamps and the
+// klog header are left out, so that lines that only differ by when they were logged are collapsed too.
+func (l LikeOptions) dedupKey(line []byte) []byte {
+	if l.Klog {
+		if entry, ok := parseKlogLine(line, l.Timestamps); ok {
+			return entry.message
+		}
+	}
+	if l.Timestamps {
+		if _, rest, found := bytes.Cut(line, []byte(" ")); found {
+			return rest
+		}
+	}
+	return line
+}
+
+// Write writes the line unless it repeats one of the window
+func (d *lineDeduper) Write(line []byte) (int, error) {
+	key := bytes.TrimRight(d.key(line), "\r\n")
+	for i := range d.recent {
+		if bytes.Equal(d.recent[i].key, key) {
+			d.recent[i].repeats++
+			return len(line), nil
+		}
+	}
+	if len(d.recent) >= max(d.window, 1) {
+		if err := d.summarize(d.recent[0]); err != nil {
+			return 0, err
+		}
+		d.recent = d.recent[1:]
+	}
+	d.recent = append(d.recent, dedupEntry{key: bytes.Clone(key)})
+	return d.out.Write(line)
+}
+
+// Flush writes the summaries of the repeats of the lines of the window, if any
+func (d *lineDeduper) Flush() error {
+	recent := d.recent
+	d.recent = nil
+	for _, entry := range recent {
+		if err := d.summarize(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarize writes the number of times the line of entry was dropped, if it was
+func (d *lineDeduper) summarize(entry dedupEntry) error {
+	if entry.repeats == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(d.notices, "(repeated %d times) %s\n", entry.repeats, entry.key)
+	return err
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func dedupLines(t *testing.T, d *lineDeduper, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if _, err := d.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedupConsecutiveLines(t *testing.T) {
+	var out, notices bytes.Buffer
+	d := &lineDeduper{out: &out, notices: &notices, key: func(line []byte) []byte { return line }, window: 1}
+	dedupLines(t, d, "ERROR boom\n", "ERROR boom\n", "ERROR boom\n", "INFO done\n", "ERROR boom\n")
+
+	if got, want := out.String(), "ERROR boom\nINFO done\nERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := notices.String(), "(repeated 2 times) ERROR boom\n"; got != want {
+		t.Errorf("got notices %q, want %q", got, want)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	var out, notices bytes.Buffer
+	d := &lineDeduper{out: &out, notices: &notices, key: func(line []byte) []byte { return line }, window: 2}
+	dedupLines(t, d, "ERROR boom\n", "retrying\n", "ERROR boom\n", "retrying\n", "ERROR boom\n", "giving up\n", "ERROR boom\n")
+
+	// the first ERROR boom leaves the window when giving up is written, so the last one is written again
+	if got, want := out.String(), "ERROR boom\nretrying\ngiving up\nERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := notices.String(), "(repeated 2 times) ERROR boom\n(repeated 1 times) retrying\n"; got != want {
+		t.Errorf("got notices %q, want %q", got, want)
+	}
+}
+
+func TestDedupKeyIgnoresTimestamps(t *testing.T) {
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.Timestamps = true
+	var out, notices bytes.Buffer
+	d := &lineDeduper{out: &out, notices: &notices, key: l.dedupKey, window: defaultDedupWindow}
+	dedupLines(t, d,
+		"2024-06-12T10:04:05.000000001Z ERROR boom\n",
+		"2024-06-12T10:04:06.000000001Z ERROR boom\n",
+	)
+
+	if got, want := out.String(), "2024-06-12T10:04:05.000000001Z ERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := notices.String(), "(repeated 1 times) ERROR boom\n"; got != want {
+		t.Errorf("got notices %q, want %q", got, want)
+	}
+}
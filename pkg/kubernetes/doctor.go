@@ -0,0 +1,122 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// doctorCheck is a check of the doctor command. It returns what it found, or an error and a hint to fix it.
+type doctorCheck struct {
+	name string
+	run  func() (detail string, hint string, err error)
+	// required skips the next checks when it fails
+	required bool
+}
+
+// Doctor checks that the kubeconfig can be loaded, that the cluster of the current context is reachable and
+// that the user may list the pods and read their logs, and writes PASS, FAIL or SKIP for every check to Out.
+// The checks after a failed kubeconfig or connection check are skipped. It returns an error if any check failed.
+func (l LikeOptions) Doctor() error {
+	var namespace, server string
+	var clientset *kubernetes.Clientset
+	checks := []doctorCheck{
+		{name: "kubeconfig", run: func() (string, string, error) {
+			hint := "check --kubeconfig or KUBECONFIG, and that the context given with --context exists"
+			raw, err := l.factory.ToRawKubeConfigLoader().RawConfig()
+			if err != nil {
+				return "", hint, err
+			}
+			config, err := l.factory.ToRESTConfig()
+			if err != nil {
+				return "", hint, err
+			}
+			namespace, _, err = l.factory.ToRawKubeConfigLoader().Namespace()
+			if err != nil {
+				return "", hint, err
+			}
+			server = config.Host
+			contextName := raw.CurrentContext
+			if l.KubernetesConfigFlags != nil && l.KubernetesConfigFlags.Context != nil && len(*l.KubernetesConfigFlags.Context) > 0 {
+				contextName = *l.KubernetesConfigFlags.Context
+			}
+			return fmt.Sprintf("context %q, namespace %q, server %s", contextName, namespace, server), "", nil
+		}, required: true},
+		{name: "connection", run: func() (string, string, error) {
+			var err error
+			clientset, err = l.factory.KubernetesClientSet()
+			if err != nil {
+				return "", "check the client settings of the context", err
+			}
+			version, err := clientset.Discovery().ServerVersion()
+			switch {
+			case apierrors.IsUnauthorized(err):
+				return "", "the credentials of the context were rejected, log in again or renew them", err
+			case err != nil:
+				return "", fmt.Sprintf("check that %s is reachable from here, e.g. through a VPN or a proxy", server), err
+			}
+			return "server version " + version.GitVersion, "", nil
+		}, required: true},
+		{name: "list pods", run: func() (string, string, error) {
+			return l.checkAccess(clientset, namespace, "list", "")
+		}},
+		{name: "read logs", run: func() (string, string, error) {
+			return l.checkAccess(clientset, namespace, "get", "log")
+		}},
+	}
+
+	failed, skip := 0, false
+	for _, check := range checks {
+		if skip {
+			fmt.Fprintf(l.Out, "SKIP  %s\n", check.name)
+			continue
+		}
+		detail, hint, err := check.run()
+		if err != nil {
+			failed++
+			skip = check.required
+			fmt.Fprintf(l.Out, "FAIL  %s: %v\n", check.name, err)
+			fmt.Fprintf(l.Out, "      hint: %s\n", hint)
+			continue
+		}
+		fmt.Fprintf(l.Out, "PASS  %s: %s\n", check.name, detail)
+	}
+	if failed > 0 {
+		return errors.New("the checks failed, kubectl like cannot read the logs with this configuration")
+	}
+	return nil
+}
+
+// checkAccess asks the server whether the user may do verb on the pods of namespace, or on their subresource
+func (l LikeOptions) checkAccess(clientset *kubernetes.Clientset, namespace, verb, subresource string) (string, string, error) {
+	resource := "pods"
+	if len(subresource) > 0 {
+		resource += "/" + subresource
+	}
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Resource:    "pods",
+				Subresource: subresource,
+			},
+		},
+	}
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(l.runContext(), review, metav1.CreateOptions{})
+	if err != nil {
+		return "", "check that the server is reachable and that the user may create selfsubjectaccessreviews", err
+	}
+	if !review.Status.Allowed {
+		err := fmt.Errorf("forbidden to %s %s in namespace %q", verb, resource, namespace)
+		if len(review.Status.Reason) > 0 {
+			err = fmt.Errorf("%w: %s", err, review.Status.Reason)
+		}
+		return "", fmt.Sprintf("ask a cluster admin for a Role granting %s on %s in the namespace, or use -n with another namespace", verb, resource), err
+	}
+	return fmt.Sprintf("allowed to %s %s in namespace %q", verb, resource, namespace), "", nil
+}
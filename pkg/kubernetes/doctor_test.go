@@ -0,0 +1,71 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func accessReview(allowed bool, reason string) *authorizationv1.SelfSubjectAccessReview {
+	return &authorizationv1.SelfSubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed, Reason: reason}}
+}
+
+func TestDoctor(t *testing.T) {
+	api := &fakeAPI{
+		raw: map[string]string{"/version": `{"gitVersion": "v1.31.1"}`},
+		sequences: map[string][]runtime.Object{
+			"/selfsubjectaccessreviews": {accessReview(true, ""), accessReview(true, "")},
+		},
+	}
+	l, out, _ := newFakeOptions(t, api)
+	if err := l.Doctor(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"PASS  kubeconfig: ",
+		"PASS  connection: server version v1.31.1\n",
+		`PASS  list pods: allowed to list pods in namespace "test"`,
+		`PASS  read logs: allowed to get pods/log in namespace "test"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got\n%s\nwant %q", out.String(), want)
+		}
+	}
+}
+
+func TestDoctorForbidden(t *testing.T) {
+	api := &fakeAPI{
+		raw: map[string]string{"/version": `{"gitVersion": "v1.31.1"}`},
+		sequences: map[string][]runtime.Object{
+			"/selfsubjectaccessreviews": {accessReview(false, "no RBAC policy matched"), accessReview(false, "")},
+		},
+	}
+	l, out, _ := newFakeOptions(t, api)
+	if err := l.Doctor(); err == nil {
+		t.Fatal("expected the checks to fail")
+	}
+	for _, want := range []string{
+		`FAIL  list pods: forbidden to list pods in namespace "test": no RBAC policy matched`,
+		// a forbidden check does not skip the next ones
+		`FAIL  read logs: forbidden to get pods/log in namespace "test"`,
+		"hint: ask a cluster admin for a Role granting get on pods/log",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got\n%s\nwant %q", out.String(), want)
+		}
+	}
+}
+
+func TestDoctorUnreachable(t *testing.T) {
+	l, out, _ := newFakeOptions(t, &fakeAPI{})
+	if err := l.Doctor(); err == nil {
+		t.Fatal("expected the checks to fail")
+	}
+	for _, want := range []string{"FAIL  connection: ", "hint: check that", "SKIP  list pods\n", "SKIP  read logs\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got\n%s\nwant %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// logTarget is a container whose logs are streamed
+type logTarget struct {
+	Context   string `json:"context,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// dryRunPlan is what --dry-run prints instead of streaming the logs
+type dryRunPlan struct {
+	Targets           []logTarget `json:"targets"`
+	Pattern           string      `json:"pattern"`
+	IgnoreCase        bool        `json:"ignoreCase"`
+	Exclude           []string    `json:"exclude,omitempty"`
+	ExcludeContainers []string    `json:"excludeContainers,omitempty"`
+	Klog              bool        `json:"klog"`
+	MinSeverity       string      `json:"minSeverity,omitempty"`
+	SeverityFormat    string      `json:"severityFormat,omitempty"`
+	Follow            bool        `json:"follow"`
+	Previous          bool        `json:"previous"`
+	Prefix            bool        `json:"prefix"`
+}
+
+// logTargets returns the containers of the requests sorted by namespace, pod and container
+func (l LikeOptions) logTargets(requests map[corev1.ObjectReference]rest.ResponseWrapper) []logTarget {
+	targets := make([]logTarget, 0, len(requests))
+	for ref := range requests {
+		_, container := l.containerFromRef(ref)
+		targets = append(targets, logTarget{Context: l.contextName, Namespace: ref.Namespace, Pod: ref.Name, Container: container})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Namespace != targets[j].Namespace {
+			return targets[i].Namespace < targets[j].Namespace
+		}
+		if targets[i].Pod != targets[j].Pod {
+			return targets[i].Pod < targets[j].Pod
+		}
+		return targets[i].Container < targets[j].Container
+	})
+	return targets
+}
+
+// String returns the target as [CONTEXT|]NAMESPACE/POD/CONTAINER
+func (t logTarget) String() string {
+	target := fmt.Sprintf("%s/%s/%s", t.Namespace, t.Pod, t.Container)
+	if t.Context != "" {
+		target = t.Context + "|" + target
+	}
+	return target
+}
+
+// printDryRun writes the containers that would be streamed and the effective filter options to Out
+func (l LikeOptions) printDryRun(targets []logTarget) error {
+	plan := dryRunPlan{
+		Targets:           targets,
+		Pattern:           l.Pattern,
+		IgnoreCase:        l.IgnoreCase,
+		Exclude:           l.Exclude,
+		ExcludeContainers: l.ExcludeContainers,
+		Klog:              l.Klog,
+		Follow:            l.Follow,
+		Previous:          l.Previous,
+		Prefix:            l.Prefix,
+	}
+	if l.minSeverity != severityUnknown {
+		plan.MinSeverity = l.MinSeverity
+		plan.SeverityFormat = l.SeverityFormat
+	}
+
+	if l.Output == outputJSON {
+		encoder := json.NewEncoder(l.Out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	fmt.Fprintf(l.Out, "Would stream %d container(s):\n", len(plan.Targets))
+	for _, target := range plan.Targets {
+		fmt.Fprintf(l.Out, "  %s\n", target)
+	}
+	pattern := plan.Pattern
+	if l.patternRegexp == nil {
+		pattern = "(none, every line is printed)"
+	}
+	if l.patternRegexp != nil && plan.IgnoreCase {
+		pattern += " (ignoring case)"
+	}
+	fmt.Fprintf(l.Out, "Pattern: %s\n", pattern)
+	for _, exclude := range plan.Exclude {
+		fmt.Fprintf(l.Out, "Exclude: %s\n", exclude)
+	}
+	for _, exclude := range plan.ExcludeContainers {
+		fmt.Fprintf(l.Out, "Exclude container: %s\n", exclude)
+	}
+	if plan.Klog {
+		fmt.Fprintln(l.Out, "Klog: matching the message only")
+	}
+	if plan.MinSeverity != "" {
+		fmt.Fprintf(l.Out, "Minimum severity: %s (format %s)\n", plan.MinSeverity, plan.SeverityFormat)
+	}
+	fmt.Fprintf(l.Out, "Follow: %t, Previous: %t, Prefix: %t\n", plan.Follow, plan.Previous, plan.Prefix)
+	return nil
+}
@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newMeshAPI() *fakeAPI {
+	var pods []corev1.Pod
+	for _, name := range []string{"api-2", "api-1"} {
+		pod := testPod(name, corev1.PodRunning, map[string]string{"app": "api"})
+		pod.Spec.Containers = []corev1.Container{{Name: "app"}, {Name: "istio-proxy"}}
+		pods = append(pods, pod)
+	}
+	return &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods": &corev1.PodList{Items: pods}}}
+}
+
+func TestDryRunListsResolvedContainers(t *testing.T) {
+	api := newMeshAPI()
+	l, cmd, out, _ := newFakeCommand(t, api)
+	flags := []string{"--dry-run", "-l", "app=api", "--all-containers", "--exclude-container", "istio-proxy", "--pattern", "ERROR"}
+	if err := completeFlags(l, cmd, flags); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := "Would stream 2 container(s):\n  test/api-1/app\n  test/api-2/app\nPattern: ERROR\nExclude container: istio-proxy\n"
+	if got := out.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("got\n%s\nwant it to start with\n%s", got, want)
+	}
+	if logs := api.requests("/namespaces/test/pods/api-1/log"); len(logs) > 0 {
+		t.Errorf("--dry-run requested the logs: %q", logs)
+	}
+}
+
+func TestDryRunJSON(t *testing.T) {
+	l, cmd, out, _ := newFakeCommand(t, newMeshAPI())
+	flags := []string{"--dry-run", "-o", "json", "-l", "app=api", "--all-containers", "--exclude-container", "istio-proxy"}
+	if err := completeFlags(l, cmd, flags); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var plan dryRunPlan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	var targets []string
+	for _, target := range plan.Targets {
+		targets = append(targets, target.String())
+	}
+	if got, want := strings.Join(targets, ","), "test/api-1/app,test/api-2/app"; got != want {
+		t.Errorf("got targets %q, want %q", got, want)
+	}
+	if got := strings.Join(plan.ExcludeContainers, ","); got != "istio-proxy" {
+		t.Errorf("got excluded containers %q, want istio-proxy", got)
+	}
+}
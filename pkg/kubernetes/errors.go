@@ -0,0 +1,39 @@
+package kubernetes
+
+import "errors"
+
+var (
+	// ErrInvalidOptions is the kind of the errors of Vaildate, a flag value or a combination of flags rejected
+	ErrInvalidOptions = errors.New("invalid options")
+	// ErrInvalidPattern is the kind of the errors of Complete compiling --pattern or another regex flag
+	ErrInvalidPattern = errors.New("invalid pattern")
+	// ErrTargetNotFound is the kind of the errors of Complete resolving no pod or object to read the logs of
+	ErrTargetNotFound = errors.New("target not found")
+	// ErrNoMatches is the kind of the error of Run when no line matched in time, e.g. with --no-match-timeout
+	ErrNoMatches = errors.New("no matches")
+)
+
+// Error is an error of Complete, Vaildate or Run whose Kind, one of the Err variables, tells the cause, so that
+// embedders can test it with errors.Is instead of matching the message, which is the one of Err
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// newError returns err with kind, nil when err is nil
+func newError(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns both the kind and the underlying error, so that errors.Is and errors.As find either of them,
+// e.g. the status of an error of the API server
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
@@ -0,0 +1,43 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestErrorKinds(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	err := completeFlags(l, cmd, []string{"--pattern", "ERROR("}, "api-1")
+	if !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("got %v, want an ErrInvalidPattern", err)
+	}
+	if got, want := err.Error(), "error parsing regexp: missing closing ): `ERROR(`"; got != want {
+		t.Errorf("got message %q, want it unchanged %q", got, want)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	err = completeFlags(l, cmd, []string{"--pattern", "ERROR", "--exclude", "[a-"}, "api-1")
+	if !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("got %v, want an ErrInvalidPattern", err)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, &fakeAPI{})
+	err = completeFlags(l, cmd, []string{"--pattern", "ERROR"}, "api-1")
+	var kindErr *Error
+	if !errors.Is(err, ErrTargetNotFound) || !errors.As(err, &kindErr) || kindErr.Kind != ErrTargetNotFound {
+		t.Errorf("got %v, want an ErrTargetNotFound", err)
+	}
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("got %v, want the NotFound status to be kept", err)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--no-match-timeout", "1m"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); !errors.Is(err, ErrInvalidOptions) || err.Error() != "--no-match-timeout can only be used with --follow" {
+		t.Errorf("got %v, want an ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// execQueueSize is the number of matching lines waiting for --exec before the next ones are dropped
+const execQueueSize = 100
+
+// lineExec runs a shell command for every matching line, one at a time and at most once per throttle.
+// The lines are queued so that a slow command does not block the streams, and dropped when the queue is full.
+type lineExec struct {
+	command  string
+	throttle time.Duration
+	errOut   io.Writer
+	queue    chan execLine
+	done     chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// execLine is a matching line and the environment of its command
+type execLine struct {
+	line []byte
+	env  []string
+}
+
+func newLineExec(command string, throttle time.Duration, errOut io.Writer) *lineExec {
+	e := &lineExec{
+		command:  command,
+		throttle: throttle,
+		errOut:   errOut,
+		queue:    make(chan execLine, execQueueSize),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *lineExec) run() {
+	defer close(e.done)
+	var last time.Time
+	for line := range e.queue {
+		if wait := e.throttle - time.Since(last); e.throttle > 0 && wait > 0 {
+			time.Sleep(wait)
+		}
+		last = time.Now()
+		e.exec(line)
+	}
+}
+
+// exec runs the command with the line on stdin. Its output goes to errOut to keep the matching lines apart.
+func (e *lineExec) exec(line execLine) {
+	cmd := shellCommand(e.command)
+	cmd.Env = append(os.Environ(), line.env...)
+	cmd.Stdin = bytes.NewReader(line.line)
+	cmd.Stdout = e.errOut
+	cmd.Stderr = e.errOut
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(e.errOut, "error: --exec %q: %v\n", e.command, err)
+	}
+}
+
+// shellCommand returns the command running command with the shell of the platform
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// enqueue queues the line, or drops it when the queue is full
+func (e *lineExec) enqueue(line execLine) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- line:
+	default:
+		if e.dropped == 0 {
+			fmt.Fprintf(e.errOut, "warning: --exec is too slow, dropping the matching lines beyond the %d waiting for it\n", execQueueSize)
+		}
+		e.dropped++
+	}
+}
+
+// Close stops accepting lines and waits for the commands of the queued lines to finish
+func (e *lineExec) Close() {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	dropped := e.dropped
+	e.mu.Unlock()
+	<-e.done
+	if dropped > 0 {
+		fmt.Fprintf(e.errOut, "--exec skipped %d matching lines\n", dropped)
+	}
+}
+
+// writer returns a writer running the command for every line written to w.
+// The command gets the pod, container, namespace and context of the lines as LIKE_ variables.
+func (e *lineExec) writer(contextName, namespace, pod, container string, w io.Writer) io.Writer {
+	env := []string{
+		"LIKE_POD=" + pod,
+		"LIKE_CONTAINER=" + container,
+		"LIKE_NAMESPACE=" + namespace,
+		"LIKE_CONTEXT=" + contextName,
+	}
+	return &execWriter{exec: e, env: env, writer: w}
+}
+
+type execWriter struct {
+	exec   *lineExec
+	env    []string
+	writer io.Writer
+}
+
+func (ew *execWriter) Write(p []byte) (int, error) {
+	n, err := ew.writer.Write(p)
+	for _, line := range bytes.SplitAfter(p[:n], []byte("\n")) {
+		if len(line) > 0 {
+			ew.exec.enqueue(execLine{line: append([]byte(nil), line...), env: ew.env})
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// execScript returns a command appending the line and the LIKE_ variables it receives to a file
+func execScript(t *testing.T) (command, received string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the helper script needs sh")
+	}
+	dir := t.TempDir()
+	received = filepath.Join(dir, "received")
+	script := filepath.Join(dir, "record.sh")
+	content := "#!/bin/sh\nread -r line\necho \"$LIKE_NAMESPACE/$LIKE_POD/$LIKE_CONTAINER $line\" >> " + received + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, received
+}
+
+func TestExecRunsForEveryMatchingLine(t *testing.T) {
+	command, received := execScript(t)
+	logs := map[string]string{
+		"api-1": "INFO starting\nERROR boom\nERROR again\n",
+		"api-2": "ERROR failed\nINFO done\n",
+	}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	var errOut bytes.Buffer
+	l.lineExec = newLineExec(command, 0, &errOut)
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.parallelConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	l.lineExec.Close()
+
+	lines := strings.Split(strings.TrimSuffix(readFile(t, received), "\n"), "\n")
+	slices.Sort(lines)
+	want := []string{"test/api-1/app ERROR again", "test/api-1/app ERROR boom", "test/api-2/app ERROR failed"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+	if strings.Count(out.String(), "\n") != 3 {
+		t.Errorf("the matching lines are not printed: %q", out.String())
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("unexpected stderr %q", errOut.String())
+	}
+}
+
+func TestExecFailureKeepsStreaming(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command needs sh")
+	}
+	var errOut bytes.Buffer
+	e := newLineExec("echo oops >&2; exit 3", 0, &errOut)
+	var out bytes.Buffer
+	w := e.writer("", "test", "api-1", "app", &out)
+	for _, line := range []string{"ERROR boom\n", "ERROR again\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.Close()
+
+	if got, want := out.String(), "ERROR boom\nERROR again\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := strings.Count(errOut.String(), "oops\n"); got != 2 {
+		t.Errorf("got the output of the command %d times, want 2: %q", got, errOut.String())
+	}
+	if got := strings.Count(errOut.String(), "exit status 3"); got != 2 {
+		t.Errorf("got %d failures, want 2: %q", got, errOut.String())
+	}
+}
+
+// lockedBuffer is a buffer safe for concurrent writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestExecSlowCommandDoesNotBlock(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command needs sh")
+	}
+	var errOut lockedBuffer
+	e := newLineExec("sleep 1", 0, &errOut)
+	w := e.writer("", "test", "api-1", "app", &bytes.Buffer{})
+	start := time.Now()
+	for i := 0; i < execQueueSize+10; i++ {
+		if _, err := w.Write([]byte("ERROR boom\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the stream was blocked for %s by the command", elapsed)
+	}
+	// skip the queued commands
+	e.mu.Lock()
+	for len(e.queue) > 0 {
+		<-e.queue
+	}
+	e.mu.Unlock()
+	e.Close()
+
+	// the first line may have been taken by the running command already
+	got := errOut.String()
+	if !strings.Contains(got, "warning: --exec is too slow") || !strings.Contains(got, "--exec skipped 9 matching lines") && !strings.Contains(got, "--exec skipped 10 matching lines") {
+		t.Errorf("got %q, want a warning and the number of skipped lines", got)
+	}
+}
+
+func TestExecThrottle(t *testing.T) {
+	command, received := execScript(t)
+	e := newLineExec(command, 100*time.Millisecond, &bytes.Buffer{})
+	w := e.writer("", "test", "api-1", "app", &bytes.Buffer{})
+	start := time.Now()
+	w.Write([]byte("one\ntwo\nthree\n"))
+	e.Close()
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("3 runs took %s, want at least 2 throttle intervals", elapsed)
+	}
+	if got, want := readFile(t, received), "test/api-1/app one\ntest/api-1/app two\ntest/api-1/app three\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExecValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--exec-throttle", "1s"},
+		{"--exec", "true", "--exec-throttle", "-1s"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
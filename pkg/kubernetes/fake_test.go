@@ -0,0 +1,204 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// fakeAPI answers the requests of the builder and of the clientset from objects keyed by their path,
+// e.g. /namespaces/test/pods/foo, without the /api/v1 or /apis/GROUP/VERSION prefix of the clientset.
+// The query of every request is recorded.
+type fakeAPI struct {
+	mu      sync.Mutex
+	objects map[string]runtime.Object
+	// sequences answers the successive requests of a path with successive objects, the last one repeating
+	sequences map[string][]runtime.Object
+	// logs answers the log requests, keyed by their path with ?previous appended for the previous instance
+	logs map[string]string
+	// drops answers the first log requests of a path with successive logs whose stream then fails, like a
+	// stream dropped by a restart of the API server, before the log of logs
+	drops map[string][]string
+	// raw answers the requests of a path with a JSON body, e.g. /version
+	raw map[string]string
+	// watches answers the first watch request of a path with its events, the next ones with none
+	watches map[string][]watch.Event
+	// failures answers as many first requests of a path with 503 Service Unavailable, like a flaky API server
+	failures map[string]int
+	queries  map[string][]string
+}
+
+func (a *fakeAPI) roundTrip(req *http.Request) (*http.Response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	path := req.URL.Path
+	if rest, ok := strings.CutPrefix(path, "/api/v1"); ok {
+		path = rest
+	} else if rest, ok := strings.CutPrefix(path, "/apis/"); ok {
+		// drop GROUP/VERSION
+		parts := strings.SplitN(rest, "/", 3)
+		if len(parts) == 3 {
+			path = "/" + parts[2]
+		}
+	}
+	if a.queries == nil {
+		a.queries = map[string][]string{}
+	}
+	a.queries[path] = append(a.queries[path], req.URL.RawQuery)
+	if a.failures[path] > 0 {
+		a.failures[path]--
+		status := &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonServiceUnavailable, Code: http.StatusServiceUnavailable, Message: "the server is currently unable to handle the request"}
+		return a.response(http.StatusServiceUnavailable, status), nil
+	}
+	if req.URL.Query().Get("watch") == "true" {
+		events := a.watches[path]
+		delete(a.watches, path)
+		return a.watchResponse(events), nil
+	}
+	if strings.HasSuffix(path, "/log") {
+		key := path
+		if req.URL.Query().Get("previous") == "true" {
+			key += "?previous"
+		}
+		if drops := a.drops[key]; len(drops) > 0 {
+			a.drops[key] = drops[1:]
+			body := io.MultiReader(strings.NewReader(drops[0]), iotest.ErrReader(io.ErrUnexpectedEOF))
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"text/plain"}}, Body: io.NopCloser(body)}, nil
+		}
+		if log, ok := a.logs[key]; ok {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"text/plain"}}, Body: io.NopCloser(strings.NewReader(log))}, nil
+		}
+	}
+	if body, ok := a.raw[path]; ok {
+		return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	if sequence := a.sequences[path]; len(sequence) > 0 {
+		object := sequence[0]
+		if len(sequence) > 1 {
+			a.sequences[path] = sequence[1:]
+		}
+		return a.response(http.StatusOK, object), nil
+	}
+	object, ok := a.objects[path]
+	if !ok {
+		status := &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound, Message: path + " not found"}
+		return a.response(http.StatusNotFound, status), nil
+	}
+	return a.response(http.StatusOK, object), nil
+}
+
+// requests returns the queries of the requests of path
+func (a *fakeAPI) requests(path string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.queries[path]...)
+}
+
+func (a *fakeAPI) response(code int, object runtime.Object) *http.Response {
+	codec := scheme.Codecs.LegacyCodec(scheme.Scheme.PrioritizedVersionsAllGroups()...)
+	body := io.NopCloser(bytes.NewReader([]byte(runtime.EncodeOrDie(codec, object))))
+	return &http.Response{StatusCode: code, Header: cmdtesting.DefaultHeader(), Body: body}
+}
+
+// watchResponse streams the events like the watch of the API server
+func (a *fakeAPI) watchResponse(events []watch.Event) *http.Response {
+	codec := scheme.Codecs.LegacyCodec(scheme.Scheme.PrioritizedVersionsAllGroups()...)
+	var body bytes.Buffer
+	for _, event := range events {
+		fmt.Fprintf(&body, "{\"type\":%q,\"object\":%s}\n", event.Type, bytes.TrimSpace([]byte(runtime.EncodeOrDie(codec, event.Object))))
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(), Body: io.NopCloser(&body)}
+}
+
+// newFakeOptions returns options whose factory is backed by api, in the namespace test
+func newFakeOptions(t *testing.T, api *fakeAPI) (LikeOptions, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	tf := cmdtesting.NewTestFactory().WithNamespace("test")
+	t.Cleanup(tf.Cleanup)
+	tf.Client = &fake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client:               fake.CreateHTTPClient(api.roundTrip),
+	}
+	tf.ClientConfigVal = cmdtesting.DefaultClientConfig()
+
+	streams, _, out, errOut := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.factory = tf
+	l.Namespace = "test"
+	podPhases, err := parsePodPhases(l.PodStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.podPhases = podPhases
+	return l, out, errOut
+}
+
+// newFakeCommand returns options backed by api and the command their flags are added to,
+// so that the tests can parse flags and call Complete like the plugin does
+func newFakeCommand(t *testing.T, api *fakeAPI) (*LikeOptions, *cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	l := new(LikeOptions)
+	var out, errOut *bytes.Buffer
+	*l, out, errOut = newFakeOptions(t, api)
+	cmd := &cobra.Command{Use: "like"}
+	l.AddFlags(cmd)
+	return l, cmd, out, errOut
+}
+
+// completeFlags parses the flags and completes the options with the arguments
+func completeFlags(l *LikeOptions, cmd *cobra.Command, flags []string, args ...string) error {
+	if err := cmd.ParseFlags(flags); err != nil {
+		return err
+	}
+	return l.Complete(args, cmd)
+}
+
+// useFakeLogs makes the logs helpers of l request the logs through the fake clientset of its factory,
+// the helpers of kubectl building their own client from the REST config
+func useFakeLogs(t *testing.T, l *LikeOptions) {
+	t.Helper()
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.RESTClientGetter = l.factory
+	l.LogsForObject = func(getter genericclioptions.RESTClientGetter, object, options runtime.Object, timeout time.Duration, allContainers bool) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+		requests, err := polymorphichelpers.LogsForObjectFn(getter, object, options, timeout, allContainers)
+		if err != nil {
+			return nil, err
+		}
+		for ref := range requests {
+			opts := options.(*corev1.PodLogOptions).DeepCopy()
+			_, opts.Container = l.containerFromRef(ref)
+			requests[ref] = clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts)
+		}
+		return requests, nil
+	}
+}
+
+func testPod(name string, phase corev1.PodPhase, labels map[string]string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
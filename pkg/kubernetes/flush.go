@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultFlushInterval is the longest time a line waits in the buffer of the output
+	defaultFlushInterval = 200 * time.Millisecond
+	// flushBufferSize is the size of the buffer of the output, flushed whenever it is full
+	flushBufferSize = 64 * 1024
+)
+
+// flushTimer is the timer of a pending flush, a *time.Timer outside of the tests
+type flushTimer interface {
+	Stop() bool
+}
+
+func timeAfterFunc(d time.Duration, f func()) flushTimer {
+	return time.AfterFunc(d, f)
+}
+
+// bufferedOutput buffers the lines written to out, to save a write per line, and flushes them at most
+// interval after the first line not flushed yet, so that followers see every line in time, also when the
+// streams go idle. Once closed, e.g. on interrupt, the lines are written to out directly.
+type bufferedOutput struct {
+	interval  time.Duration
+	afterFunc func(time.Duration, func()) flushTimer
+	out       io.Writer
+
+	mu     sync.Mutex
+	buffer *bufio.Writer
+	timer  flushTimer
+	closed bool
+}
+
+func newBufferedOutput(out io.Writer, interval time.Duration) *bufferedOutput {
+	return &bufferedOutput{
+		interval:  interval,
+		afterFunc: timeAfterFunc,
+		out:       out,
+		buffer:    bufio.NewWriterSize(out, flushBufferSize),
+	}
+}
+
+func (b *bufferedOutput) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return b.out.Write(p)
+	}
+	n, err := b.buffer.Write(p)
+	if b.buffer.Buffered() > 0 && b.timer == nil {
+		b.timer = b.afterFunc(b.interval, b.flushDue)
+	}
+	return n, err
+}
+
+// flushDue flushes the buffer when the interval of its first line is over. An error is kept by the
+// buffer and returned by the next write.
+func (b *bufferedOutput) flushDue() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timer = nil
+	b.buffer.Flush()
+}
+
+// Flush writes the buffered lines to out now
+func (b *bufferedOutput) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush()
+}
+
+func (b *bufferedOutput) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return b.buffer.Flush()
+}
+
+// Close flushes the buffered lines and stops buffering
+func (b *bufferedOutput) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return b.flush()
+}
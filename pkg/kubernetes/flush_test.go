@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock runs the functions of the flush timers when the test advances it
+type fakeClock struct {
+	now    time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+func (c *fakeClock) afterFunc(d time.Duration, f func()) flushTimer {
+	t := &fakeTimer{at: c.now + d, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// advance moves the clock by d, running the timers due in the meantime
+func (c *fakeClock) advance(d time.Duration) {
+	c.now += d
+	for _, t := range c.timers {
+		if !t.stopped && t.at <= c.now {
+			t.stopped = true
+			t.f()
+		}
+	}
+}
+
+func newTestBufferedOutput(out io.Writer, interval time.Duration) (*bufferedOutput, *fakeClock) {
+	clock := &fakeClock{}
+	b := newBufferedOutput(out, interval)
+	b.afterFunc = clock.afterFunc
+	return b, clock
+}
+
+func TestBufferedOutputFlushesWithinInterval(t *testing.T) {
+	var out bytes.Buffer
+	b, clock := newTestBufferedOutput(&out, 200*time.Millisecond)
+
+	io.WriteString(b, "one\n")
+	clock.advance(150 * time.Millisecond)
+	io.WriteString(b, "two\n")
+	if out.Len() != 0 {
+		t.Fatalf("got %q before the interval, want nothing", out.String())
+	}
+	// the interval counts from the first line waiting in the buffer
+	clock.advance(50 * time.Millisecond)
+	if got, want := out.String(), "one\ntwo\n"; got != want {
+		t.Fatalf("got %q after the interval, want %q", got, want)
+	}
+
+	// no line is held longer than the interval while the streams are idle
+	io.WriteString(b, "three\n")
+	clock.advance(199 * time.Millisecond)
+	if got, want := out.String(), "one\ntwo\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	clock.advance(time.Millisecond)
+	if got, want := out.String(), "one\ntwo\nthree\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestBufferedOutputClose(t *testing.T) {
+	var out bytes.Buffer
+	b, clock := newTestBufferedOutput(&out, time.Hour)
+	io.WriteString(b, "one\n")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "one\n"; got != want {
+		t.Fatalf("got %q on close, want %q", got, want)
+	}
+	// the summaries printed after an interrupt are not buffered anymore
+	io.WriteString(b, "summary\n")
+	if got, want := out.String(), "one\nsummary\n"; got != want {
+		t.Errorf("got %q after close, want %q", got, want)
+	}
+	clock.advance(time.Hour)
+	if got, want := out.String(), "one\nsummary\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlushIntervalFlushesOnExit(t *testing.T) {
+	logs := map[string]string{"api-1": "INFO starting\nERROR boom\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	var out bytes.Buffer
+	b := newBufferedOutput(&out, time.Hour)
+	l.Out = b
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("got %q before the flush, want nothing", out.String())
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlushIntervalValidation(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--flush-interval", "-1s"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected a negative --flush-interval to be rejected")
+	}
+}
+
+func BenchmarkOutput(b *testing.B) {
+	line := []byte(strings.Repeat("x", 100) + "\n")
+	for _, interval := range []time.Duration{0, defaultFlushInterval} {
+		b.Run(fmt.Sprintf("flush-interval=%s", interval), func(b *testing.B) {
+			f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+			var out io.Writer = f
+			if interval > 0 {
+				buffered := newBufferedOutput(f, interval)
+				defer buffered.Close()
+				out = buffered
+			}
+			b.SetBytes(int64(len(line)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := out.Write(line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const groupByReplicaSet = "replicaset"
+
+var groupByValues = []string{groupByReplicaSet}
+
+// replicaSetGroup returns the rs:HASH source of a pod created by a Deployment, or "" for other pods.
+// The pod-template-hash label is set by the Deployment controller and is the suffix of the ReplicaSet name.
+func (l LikeOptions) replicaSetGroup(ref corev1.ObjectReference) string {
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return ""
+	}
+	pod, err := clientset.CoreV1().Pods(ref.Namespace).Get(l.runContext(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		l.logger.Debug("cannot get the ReplicaSet of the pod", "namespace", ref.Namespace, "pod", ref.Name, "error", err)
+		return ""
+	}
+	hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	if !ok {
+		return ""
+	}
+	return "rs:" + hash
+}
+
+// matchCounts counts the matching lines written per group, e.g. per ReplicaSet with --group-by=replicaset.
+// The counts are kept in the counters of a metrics registry, labeled with the group as source.
+type matchCounts struct {
+	title string
+	// out is nil when the counts are only served by --metrics-addr
+	out io.Writer
+
+	matched  *counterVec
+	dropped  *counterVec
+	unparsed *counterVec
+	// duplicates and gaps count the lines dropped and the gaps detected when a stream is reopened
+	duplicates *counterVec
+	gaps       *counterVec
+	// unsent counts the lines not sent by every notifier, e.g. --webhook
+	unsent *counterVec
+
+	mu   sync.Mutex
+	done bool
+}
+
+func newMatchCounts(title string, out io.Writer) *matchCounts {
+	return newRegisteredMatchCounts(newMetricsRegistry(), title, out)
+}
+
+// newRegisteredMatchCounts returns counts kept in registry, so that they can also be served by --metrics-addr
+func newRegisteredMatchCounts(registry *metricsRegistry, title string, out io.Writer) *matchCounts {
+	c := &matchCounts{
+		title:      title,
+		out:        out,
+		matched:    registry.counter("kubectl_like_lines_matched_total", "Matching lines written per source.", "source"),
+		dropped:    registry.counter("kubectl_like_lines_dropped_total", "Matching lines dropped instead of written, e.g. by a full --per-source-buffer.", "source"),
+		unparsed:   registry.counter("kubectl_like_unparsed_timestamps_total", "Timestamps that could not be reformatted by --timestamp-format.", "source"),
+		unsent:     registry.counter("kubectl_like_lines_unsent_total", "Matching lines that could not be sent by a notifier, e.g. --webhook.", "source", "flag"),
+		duplicates: registry.counter("kubectl_like_duplicate_lines_dropped_total", "Lines already read that were dropped from a reopened stream.", "source"),
+		gaps:       registry.counter("kubectl_like_stream_gaps_total", "Reopened streams that did not continue right after the last line read, so lines may have been lost.", "source"),
+	}
+	return c
+}
+
+// writer returns a writer counting the lines written to w in group
+func (c *matchCounts) writer(group string, w io.Writer) io.Writer {
+	c.matched.add(0, group)
+	return &countingWriter{counts: c, group: group, writer: w}
+}
+
+func (c *matchCounts) add(group string, lines int) {
+	c.matched.add(lines, group)
+}
+
+// drop counts lines of the group that were dropped instead of written, e.g. by the multiplexer
+func (c *matchCounts) drop(group string, lines int) {
+	c.dropped.add(lines, group)
+}
+
+// unparsedTimestamp counts a line of the group whose timestamp could not be reformatted
+func (c *matchCounts) unparsedTimestamp(group string) {
+	c.unparsed.add(1, group)
+}
+
+// dropDuplicate counts a line of the group already read that was dropped from a reopened stream
+func (c *matchCounts) dropDuplicate(group string) {
+	c.duplicates.add(1, group)
+}
+
+// gap counts a reopened stream of the group that did not continue right after the last line read
+func (c *matchCounts) gap(group string) {
+	c.gaps.add(1, group)
+}
+
+// dropUnsent counts lines of the group that could not be sent by the notifier of flag, e.g. --webhook
+func (c *matchCounts) dropUnsent(group, flag string, lines int) {
+	c.unsent.add(lines, group, flag)
+}
+
+// Print writes the line counts of every group once
+func (c *matchCounts) Print() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done || c.out == nil {
+		return
+	}
+	c.done = true
+
+	unsent := map[string][]string{}
+	c.unsent.each(func(values []string, lines int) {
+		unsent[values[0]] = append(unsent[values[0]], fmt.Sprintf("%d not sent to %s", lines, values[1]))
+	})
+	fmt.Fprintf(c.out, "Matching lines per %s:\n", c.title)
+	c.matched.each(func(values []string, lines int) {
+		group := values[0]
+		name := group
+		if name == "" {
+			name = "(none)"
+		}
+		var notes []string
+		if dropped := c.dropped.get(group); dropped > 0 {
+			notes = append(notes, fmt.Sprintf("%d dropped", dropped))
+		}
+		if unparsed := c.unparsed.get(group); unparsed > 0 {
+			notes = append(notes, fmt.Sprintf("%d unparsed timestamps", unparsed))
+		}
+		if duplicates := c.duplicates.get(group); duplicates > 0 {
+			notes = append(notes, fmt.Sprintf("%d duplicates dropped", duplicates))
+		}
+		if gaps := c.gaps.get(group); gaps > 0 {
+			notes = append(notes, fmt.Sprintf("%d gaps detected", gaps))
+		}
+		notes = append(notes, unsent[group]...)
+		if len(notes) > 0 {
+			fmt.Fprintf(c.out, "  %s: %d (%s)\n", name, lines, strings.Join(notes, ", "))
+			return
+		}
+		fmt.Fprintf(c.out, "  %s: %d\n", name, lines)
+	})
+}
+
+type countingWriter struct {
+	counts *matchCounts
+	group  string
+	writer io.Writer
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.writer.Write(p)
+	cw.counts.add(cw.group, bytes.Count(p[:n], []byte("\n")))
+	return n, err
+}
+
+// defaultMaxGroups is the number of values of the capture group of --group-by whose lines are kept by default
+const defaultMaxGroups = 1000
+
+// captureGroupNames returns the names of the capture groups of the pattern, or their index for unnamed groups,
+// like the keys of captureGroups
+func (l LikeOptions) captureGroupNames() []string {
+	if l.patternRegexp == nil {
+		return nil
+	}
+	var names []string
+	for i, name := range l.patternRegexp.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// groupsLines tells whether --group-by names a capture group, whose values group the matching lines
+func (l LikeOptions) groupsLines() bool {
+	return len(l.GroupBy) > 0 && l.GroupBy != groupByReplicaSet
+}
+
+// lineGroups keeps the matching lines per value of a capture group of the pattern, in the order the values
+// were first seen, and prints them grouped once every line is read. Only the lines of the first max values
+// are kept.
+type lineGroups struct {
+	group  string
+	max    int
+	out    io.Writer
+	errOut io.Writer
+
+	mu      sync.Mutex
+	values  []string
+	lines   map[string][]groupedLine
+	dropped int
+	done    bool
+}
+
+// groupedLine is a matching line and the writer of its source, which prefixes and formats it when printed
+type groupedLine struct {
+	line   []byte
+	writer io.Writer
+}
+
+func newLineGroups(group string, max int, out, errOut io.Writer) *lineGroups {
+	return &lineGroups{group: group, max: max, out: out, errOut: errOut, lines: map[string][]groupedLine{}}
+}
+
+// writer returns a writer keeping the lines written to it in their group, to be written to w when printed
+func (g *lineGroups) writer(l LikeOptions, w io.Writer) io.Writer {
+	return &groupingWriter{l: l, groups: g, writer: w}
+}
+
+func (g *lineGroups) add(value string, line []byte, w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.lines[value]; !ok {
+		if len(g.values) == g.max {
+			g.dropped++
+			return
+		}
+		g.values = append(g.values, value)
+	}
+	g.lines[value] = append(g.lines[value], groupedLine{line: line, writer: w})
+}
+
+// Print writes the lines of every group under a header, once
+func (g *lineGroups) Print() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return nil
+	}
+	g.done = true
+
+	for _, value := range g.values {
+		name := value
+		if name == "" {
+			name = "(none)"
+		}
+		lines := g.lines[value]
+		if _, err := fmt.Fprintf(g.out, "---- %s=%s (%d lines) ----\n", g.group, name, len(lines)); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if _, err := line.writer.Write(line.line); err != nil {
+				return err
+			}
+		}
+	}
+	if g.dropped > 0 {
+		fmt.Fprintf(g.errOut, "warning: dropped %d lines of the values of %s beyond the first %d, use --max-groups to increase the limit\n", g.dropped, g.group, g.max)
+	}
+	return nil
+}
+
+// groupingWriter keeps every line written to it in the group of the value of the capture group in the line.
+// Lines where the capture group did not participate are kept in the group of the empty value.
+type groupingWriter struct {
+	l      LikeOptions
+	groups *lineGroups
+	writer io.Writer
+}
+
+func (gw *groupingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	value := gw.l.captureGroups(p)[gw.groups.group]
+	gw.groups.add(value, bytes.Clone(p), gw.writer)
+	return len(p), nil
+}
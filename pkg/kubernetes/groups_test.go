@@ -0,0 +1,190 @@
+package kubernetes
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGroupByReplicaSet(t *testing.T) {
+	oldPod := testPod("web-aaa-1", corev1.PodRunning, map[string]string{"pod-template-hash": "aaa"})
+	newPod := testPod("web-bbb-1", corev1.PodRunning, map[string]string{"pod-template-hash": "bbb"})
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods/web-aaa-1": &oldPod,
+		"/namespaces/test/pods/web-bbb-1": &newPod,
+	}}
+	l, _, _ := newFakeOptions(t, api)
+	var summary bytes.Buffer
+	l.byReplicaSet = true
+	l.Prefix = true
+	l.matchCounts = newMatchCounts("ReplicaSet", &summary)
+
+	var out bytes.Buffer
+	oldWriter := l.writerFor(corev1.ObjectReference{Namespace: "test", Name: "web-aaa-1", FieldPath: "spec.containers{app}"}, &out)
+	newWriter := l.writerFor(corev1.ObjectReference{Namespace: "test", Name: "web-bbb-1", FieldPath: "spec.containers{app}"}, &out)
+	for _, line := range []string{"ERROR old 1\n", "ERROR old 2\n"} {
+		oldWriter.Write([]byte(line))
+	}
+	newWriter.Write([]byte("ERROR new 1\n"))
+
+	wantOut := "[pod/web-aaa-1/app rs:aaa] ERROR old 1\n[pod/web-aaa-1/app rs:aaa] ERROR old 2\n[pod/web-bbb-1/app rs:bbb] ERROR new 1\n"
+	if got := out.String(); got != wantOut {
+		t.Errorf("got\n%s\nwant\n%s", got, wantOut)
+	}
+	l.matchCounts.Print()
+	if got, want := summary.String(), "Matching lines per ReplicaSet:\n  rs:aaa: 2\n  rs:bbb: 1\n"; got != want {
+		t.Errorf("got summary\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReplicaSetGroupOfPodWithoutHash(t *testing.T) {
+	pod := testPod("standalone", corev1.PodRunning, nil)
+	l, _, _ := newFakeOptions(t, &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/standalone": &pod}})
+	if group := l.replicaSetGroup(corev1.ObjectReference{Namespace: "test", Name: "standalone"}); group != "" {
+		t.Errorf("got group %q for a pod without pod-template-hash, want none", group)
+	}
+}
+
+func TestMatchCountsPrintsOnce(t *testing.T) {
+	var summary bytes.Buffer
+	counts := newMatchCounts("container", &summary)
+	counts.writer("test/api-1/app", &bytes.Buffer{}).Write([]byte("a\nb\n"))
+	counts.drop("test/api-1/app", 3)
+	counts.unparsedTimestamp("test/api-1/app")
+	counts.dropDuplicate("test/api-1/app")
+	counts.gap("test/api-1/app")
+	counts.Print()
+	counts.Print()
+	want := "Matching lines per container:\n  test/api-1/app: 2 (3 dropped, 1 unparsed timestamps, 1 duplicates dropped, 1 gaps detected)\n"
+	if got := summary.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// newRequestsAPI returns pods api-1 and api-2 whose logs interleave requests
+func newRequestsAPI() *fakeAPI {
+	api := &fakeAPI{objects: map[string]runtime.Object{}, logs: map[string]string{
+		"/namespaces/test/pods/api-1/log": "rid=a start\nrid=b start\nno request\nrid=a ERROR end\n",
+		"/namespaces/test/pods/api-2/log": "rid=b end\nrid=c start\n",
+	}}
+	for _, name := range []string{"api-1", "api-2"} {
+		pod := testPod(name, corev1.PodRunning, nil)
+		api.objects["/namespaces/test/pods/"+name] = &pod
+	}
+	return api
+}
+
+func TestGroupByCaptureGroup(t *testing.T) {
+	l, cmd, out, _ := newFakeCommand(t, newRequestsAPI())
+	flags := []string{"--pattern", `rid=(?P<rid>\w+)|request`, "--group-by", "rid", "--prefix"}
+	if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := "---- rid=a (2 lines) ----\n" +
+		"[pod/api-1/app] rid=a start\n" +
+		"[pod/api-1/app] rid=a ERROR end\n" +
+		"---- rid=b (1 lines) ----\n" +
+		"[pod/api-1/app] rid=b start\n" +
+		"---- rid=(none) (1 lines) ----\n" +
+		"[pod/api-1/app] no request\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGroupByCaptureGroupAcrossPods(t *testing.T) {
+	l, cmd, out, _ := newFakeCommand(t, newRequestsAPI())
+	if err := completeFlags(l, cmd, []string{"--pattern", `rid=(\w+)`, "--group-by", "1"}, "api-1", "api-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// the pods are read in any order, so are their lines within a group
+	groups := map[string][]string{}
+	var header string
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if strings.HasPrefix(line, "---- ") {
+			header = line
+			continue
+		}
+		groups[header] = append(groups[header], line)
+	}
+	want := map[string][]string{
+		"---- 1=a (2 lines) ----": {"[pod/api-1/app] rid=a ERROR end", "[pod/api-1/app] rid=a start"},
+		"---- 1=b (2 lines) ----": {"[pod/api-1/app] rid=b start", "[pod/api-2/app] rid=b end"},
+		"---- 1=c (1 lines) ----": {"[pod/api-2/app] rid=c start"},
+	}
+	for header, lines := range groups {
+		slices.Sort(lines)
+		if !slices.Equal(lines, want[header]) {
+			t.Errorf("%s: got %q, want %q", header, lines, want[header])
+		}
+	}
+	if len(groups) != len(want) {
+		t.Errorf("got groups\n%s", out.String())
+	}
+}
+
+func TestLineGroupsMaxGroups(t *testing.T) {
+	var out, errOut bytes.Buffer
+	l, _ := newOutputOptions(t, outputText, `rid=(?P<rid>\w+)`, nil)
+	groups := newLineGroups("rid", 2, &out, &errOut)
+	w := groups.writer(l, &out)
+	for _, line := range []string{"rid=a 1\n", "rid=b 1\n", "rid=c 1\n", "rid=a 2\n", "rid=d 1\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() > 0 {
+		t.Errorf("lines were written before the groups are printed: %q", out.String())
+	}
+	if err := groups.Print(); err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.Print(); err != nil {
+		t.Fatal(err)
+	}
+	want := "---- rid=a (2 lines) ----\nrid=a 1\nrid=a 2\n---- rid=b (1 lines) ----\nrid=b 1\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(errOut.String(), "dropped 2 lines") {
+		t.Errorf("expected a warning about the dropped lines, got %q", errOut.String())
+	}
+}
+
+func TestGroupByValidation(t *testing.T) {
+	tests := []struct {
+		flags []string
+		want  string
+	}{
+		{flags: []string{"--pattern", `rid=(?P<rid>\w+)`, "--group-by", "request"}, want: "unknown --group-by"},
+		{flags: []string{"--pattern", `rid=(?P<rid>\w+)`, "--group-by", "rid", "-f"}, want: "cannot be used with --follow"},
+		{flags: []string{"--pattern", `rid=(?P<rid>\w+)`, "--group-by", "rid", "--max-groups", "0"}, want: "--max-groups"},
+	}
+	for _, test := range tests {
+		l, cmd, _, _ := newFakeCommand(t, newRequestsAPI())
+		if err := completeFlags(l, cmd, test.flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: expected an error containing %q, got %v", test.flags, test.want, err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package kubernetes
+
+import "errors"
+
+// errHeadReached ends a stream once its first --head lines were read, like the end of its log. It only ends
+// that stream, the others go on.
+var errHeadReached = errors.New("head of the stream reached")
+
+// headReached tells whether the lines read so far of a stream are the --head ones
+func (l LikeOptions) headReached(lines int) bool {
+	return l.Head > 0 && lines >= l.Head
+}
+
+// endOfHead returns nil for a stream ended by --head, which is not reopened
+func endOfHead(err error) error {
+	if errors.Is(err, errHeadReached) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHeadStopsAfterTheFirstLines(t *testing.T) {
+	logs := map[string]string{"api-1": "INFO starting\nERROR one\nERROR two\nERROR three\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	// the lines that do not match are counted too
+	l.Head = 3
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR one\nERROR two\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeadLongerThanTheLog(t *testing.T) {
+	logs := map[string]string{"api-1": "ERROR one\nERROR two\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.Head = 5
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR one\nERROR two\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeadIsNotReopenedWhileFollowing(t *testing.T) {
+	shortReattachInterval(t)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(0, time.Now())},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR one\nERROR two\n"},
+	}
+	l, _ := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	l.Follow = true
+	l.Head = 1
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := l.consumeRequest(appRef, request, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 1 {
+		t.Errorf("got %d log requests, want the stream not to be reopened", got)
+	}
+}
+
+func TestHeadValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--head", "-1"},
+		{"--head", "10", "--no-filter"},
+		{"--head", "10", "--ordered-backlog"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// heartbeatAfterFunc schedules the next status line of --heartbeat, a fake clock in the tests
+var heartbeatAfterFunc = timeAfterFunc
+
+// heartbeat writes a status line to ErrOut when no line was printed for an interval while following, with the
+// number of lines read meanwhile, so that a quiet follow is not mistaken for a hung one or a filter that is wrong
+type heartbeat struct {
+	out       io.Writer
+	interval  time.Duration
+	dim       bool
+	afterFunc func(time.Duration, func()) flushTimer
+	// read counts the lines read since the last interval, printed tells whether a line was printed during it
+	read    atomic.Int64
+	printed atomic.Bool
+
+	mu      sync.Mutex
+	pods    map[string]bool
+	timer   flushTimer
+	stopped bool
+}
+
+func newHeartbeat(out io.Writer, interval time.Duration, dim bool) *heartbeat {
+	h := &heartbeat{out: out, interval: interval, dim: dim, afterFunc: heartbeatAfterFunc, pods: map[string]bool{}}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timer = h.afterFunc(interval, h.beat)
+	return h
+}
+
+// beat ends an interval, writing the status line if no line was printed during it
+func (h *heartbeat) beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	read := h.read.Swap(0)
+	if !h.printed.Swap(false) {
+		status := fmt.Sprintf("… %d lines read, 0 matched in last %s (%s)", read, h.interval, h.source())
+		if h.dim {
+			status = sgrDim + status + sgrReset
+		}
+		// in a single write, so that it is not mixed with the other lines written to ErrOut
+		fmt.Fprintln(h.out, status)
+	}
+	h.timer = h.afterFunc(h.interval, h.beat)
+}
+
+// source names the pod followed, or the number of pods
+func (h *heartbeat) source() string {
+	if len(h.pods) == 1 {
+		for pod := range h.pods {
+			return "pod " + pod
+		}
+	}
+	return fmt.Sprintf("%d pods", len(h.pods))
+}
+
+// Stop stops writing status lines
+func (h *heartbeat) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	h.timer.Stop()
+}
+
+// writer returns a writer telling the heartbeat that the lines written to w were printed
+func (h *heartbeat) writer(w io.Writer) io.Writer {
+	return &heartbeatWriter{heartbeat: h, writer: w}
+}
+
+type heartbeatWriter struct {
+	heartbeat *heartbeat
+	writer    io.Writer
+}
+
+func (hw *heartbeatWriter) Write(p []byte) (int, error) {
+	n, err := hw.writer.Write(p)
+	if n > 0 {
+		hw.heartbeat.printed.Store(true)
+	}
+	return n, err
+}
+
+// counted returns request counting the lines read from its stream, a container of pod
+func (h *heartbeat) counted(pod string, request rest.ResponseWrapper) rest.ResponseWrapper {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pods[pod] = true
+	return &heartbeatRequest{ResponseWrapper: request, heartbeat: h}
+}
+
+type heartbeatRequest struct {
+	rest.ResponseWrapper
+	heartbeat *heartbeat
+}
+
+func (r *heartbeatRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeatStream{ReadCloser: stream, heartbeat: r.heartbeat}, nil
+}
+
+type heartbeatStream struct {
+	io.ReadCloser
+	heartbeat *heartbeat
+}
+
+func (s *heartbeatStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.heartbeat.read.Add(int64(bytes.Count(p[:n], []byte("\n"))))
+	return n, err
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeHeartbeatClock replaces the clock of --heartbeat, an interval only ends when the test calls the function
+// received from the returned channel
+func fakeHeartbeatClock(t *testing.T) chan func() {
+	t.Helper()
+	scheduled := make(chan func(), 1)
+	previous := heartbeatAfterFunc
+	heartbeatAfterFunc = func(d time.Duration, f func()) flushTimer {
+		scheduled <- f
+		return time.NewTimer(time.Hour)
+	}
+	t.Cleanup(func() { heartbeatAfterFunc = previous })
+	return scheduled
+}
+
+// readLines reads lines from a container of pod through the counter of the heartbeat
+func readLines(t *testing.T, h *heartbeat, pod, lines string) {
+	t.Helper()
+	reader, writer := io.Pipe()
+	stream, err := h.counted(pod, pipeRequest{reader: reader}).Stream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(writer, lines)
+		writer.Close()
+	}()
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeartbeatWhenNothingPrinted(t *testing.T) {
+	scheduled := fakeHeartbeatClock(t)
+	var out lockedBuffer
+	h := newHeartbeat(&out, 30*time.Second, false)
+	defer h.Stop()
+	readLines(t, h, "api-1", "INFO one\nINFO two\nINFO three\n")
+
+	(<-scheduled)()
+	if got, want := out.String(), "… 3 lines read, 0 matched in last 30s (pod api-1)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the lines are counted again in every interval
+	(<-scheduled)()
+	if got, want := out.String(), "… 3 lines read, 0 matched in last 30s (pod api-1)\n… 0 lines read, 0 matched in last 30s (pod api-1)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeartbeatSuppressedWhileLinesArePrinted(t *testing.T) {
+	scheduled := fakeHeartbeatClock(t)
+	var out lockedBuffer
+	h := newHeartbeat(&out, 30*time.Second, false)
+	defer h.Stop()
+	readLines(t, h, "api-1", "INFO one\nERROR two\n")
+	readLines(t, h, "api-2", "INFO three\n")
+	if _, err := io.WriteString(h.writer(io.Discard), "ERROR two\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	(<-scheduled)()
+	if got := out.String(); got != "" {
+		t.Errorf("got %q after a line was printed, want no status line", got)
+	}
+	(<-scheduled)()
+	if got, want := out.String(), "… 0 lines read, 0 matched in last 30s (2 pods)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeartbeatDim(t *testing.T) {
+	scheduled := fakeHeartbeatClock(t)
+	var out lockedBuffer
+	h := newHeartbeat(&out, time.Minute, true)
+	defer h.Stop()
+
+	(<-scheduled)()
+	if got, want := out.String(), sgrDim+"… 0 lines read, 0 matched in last 1m0s (0 pods)"+sgrReset+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeartbeatStop(t *testing.T) {
+	scheduled := fakeHeartbeatClock(t)
+	var out lockedBuffer
+	h := newHeartbeat(&out, time.Hour, false)
+	h.Stop()
+	(<-scheduled)()
+	if got := out.String(); got != "" {
+		t.Errorf("got %q once stopped", got)
+	}
+}
+
+func TestHeartbeatOnlyOnTerminal(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--heartbeat", "30s"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.writeHeartbeat {
+		t.Error("the heartbeat is written to a stderr that is not a terminal")
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--heartbeat", "30s", "--heartbeat-mode", "always"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !l.writeHeartbeat {
+		t.Error("--heartbeat-mode=always did not force the heartbeat")
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--heartbeat-mode", "always"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected --heartbeat-mode without --heartbeat to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// errIdleTimeout stops the streams once no line was received for --idle-timeout
+var errIdleTimeout = errors.New("idle timeout reached")
+
+// idleTimer cancels its context when no line was received by any stream for the timeout, once it is started
+type idleTimer struct {
+	timeout time.Duration
+	out     io.Writer
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+func newIdleTimer(parent context.Context, timeout time.Duration, out io.Writer) *idleTimer {
+	ctx, cancel := context.WithCancel(parent)
+	return &idleTimer{
+		timeout: timeout,
+		out:     out,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// start starts the first idle window when the streams are about to be read, so that the time spent resolving
+// the targets is not counted. It is a no-op on a nil or started timer.
+func (t *idleTimer) start() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.timeout, t.expire)
+	}
+}
+
+// touch restarts the idle window after a line was received
+func (t *idleTimer) touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.expired && t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+func (t *idleTimer) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expired = true
+	fmt.Fprintf(t.out, "no new lines for %s, exiting\n", t.timeout)
+	t.cancel()
+}
+
+// Expired tells whether the streams were stopped because they were idle
+func (t *idleTimer) Expired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.expired
+}
@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdleTimerWaitsForStart(t *testing.T) {
+	var out bytes.Buffer
+	idle := newIdleTimer(context.Background(), time.Millisecond, &out)
+	idle.touch()
+	time.Sleep(20 * time.Millisecond)
+	if idle.Expired() || idle.ctx.Err() != nil {
+		t.Fatal("the idle timer expired before it was started")
+	}
+
+	idle.start()
+	select {
+	case <-idle.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the started idle timer did not expire")
+	}
+	if !idle.Expired() {
+		t.Error("the idle timer is not reported as expired")
+	}
+	if got, want := out.String(), "no new lines for 1ms, exiting\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIdleTimerTouchPostponesExpiry(t *testing.T) {
+	var out bytes.Buffer
+	idle := newIdleTimer(context.Background(), 200*time.Millisecond, &out)
+	idle.start()
+	idle.start()
+	for i := 0; i < 5; i++ {
+		time.Sleep(50 * time.Millisecond)
+		idle.touch()
+	}
+	if idle.Expired() {
+		t.Fatal("the idle timer expired although lines kept coming")
+	}
+	select {
+	case <-idle.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle timer did not expire once the lines stopped")
+	}
+}
+
+func TestNilIdleTimerStart(t *testing.T) {
+	var idle *idleTimer
+	idle.start()
+}
@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// ErrInterrupted is returned by Run when the streams were stopped by SIGINT or SIGTERM, once the output was
+// flushed and the summaries were printed
+var ErrInterrupted = errors.New("interrupted")
+
+// shutdownTimeout is how long an interrupted command has to flush its output, close its files and print its
+// summaries before it exits anyway
+var shutdownTimeout = 5 * time.Second
+
+// interruptHandlers run when an interrupted command does not end within shutdownTimeout, e.g. to print the
+// summaries of streams that do not stop. They are kept in the context returned by NotifyInterrupt, so that they
+// only belong to the run of the options completed with it.
+type interruptHandlers struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// interruptHandlersKey is the key of the interruptHandlers in the context returned by NotifyInterrupt
+type interruptHandlersKey struct{}
+
+// onInterrupt registers fn to run when the interrupted command does not end within shutdownTimeout, before it
+// exits. Handlers run in the order they were registered. Without the context of NotifyInterrupt it does nothing.
+func (l LikeOptions) onInterrupt(fn func()) {
+	h, ok := l.runContext().Value(interruptHandlersKey{}).(*interruptHandlers)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns = append(h.fns, fn)
+}
+
+func (h *interruptHandlers) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// NotifyInterrupt returns a copy of ctx canceled on the first SIGINT or SIGTERM. The streams of the options
+// completed with it then end, so that Run flushes the output, closes the files, prints the summaries and
+// returns ErrInterrupted. If it takes longer than shutdownTimeout, the handlers registered by these options run
+// and exit is called, and a second signal calls exit right away. stop releases the signals.
+func NotifyInterrupt(ctx context.Context, exit func()) (context.Context, context.CancelFunc) {
+	handlers := &interruptHandlers{}
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, interruptHandlersKey{}, handlers))
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	timeout := shutdownTimeout
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			cancel()
+		})
+	}
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		cancel()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-signals:
+			exit()
+		case <-timer.C:
+			handlers.run()
+			exit()
+		case <-done:
+		}
+	}()
+	return ctx, stop
+}
+
+// runContext returns the context of the command, canceled when it is interrupted
+func (l LikeOptions) runContext() context.Context {
+	if l.ctx != nil {
+		return l.ctx
+	}
+	return context.Background()
+}
+
+// sleep waits for d, or returns ErrInterrupted as soon as the command is interrupted
+func (l LikeOptions) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-l.runContext().Done():
+		return ErrInterrupted
+	case <-timer.C:
+		return nil
+	}
+}
+
+// interruptibleRequest closes its stream when ctx is canceled, whatever the context it is opened with, e.g.
+// the one of the consume function of kubectl, and its reads then fail with ErrInterrupted
+type interruptibleRequest struct {
+	rest.ResponseWrapper
+	ctx context.Context
+}
+
+func (r *interruptibleRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	if r.ctx.Err() != nil {
+		return nil, ErrInterrupted
+	}
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		if r.ctx.Err() != nil {
+			return nil, ErrInterrupted
+		}
+		return nil, err
+	}
+	return &interruptibleStream{
+		ReadCloser: stream,
+		ctx:        r.ctx,
+		stop:       context.AfterFunc(r.ctx, func() { stream.Close() }),
+	}, nil
+}
+
+type interruptibleStream struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop func() bool
+}
+
+func (s *interruptibleStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if err != nil && s.ctx.Err() != nil {
+		err = ErrInterrupted
+	}
+	return n, err
+}
+
+func (s *interruptibleStream) Close() error {
+	s.stop()
+	return s.ReadCloser.Close()
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// interrupt sends SIGINT to the test process, caught by NotifyInterrupt
+func interrupt(t *testing.T) {
+	t.Helper()
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recordExit returns the exit of an interrupted command for NotifyInterrupt and the channel it records to
+func recordExit() (func(), chan struct{}) {
+	exited := make(chan struct{}, 2)
+	return func() { exited <- struct{}{} }, exited
+}
+
+func TestInterruptFlushesOutputAndPrintsSummary(t *testing.T) {
+	exit, exited := recordExit()
+	l, cmd, out, errOut := newFakeCommand(t, newPodAPI("api-1"))
+	ctx, stop := NotifyInterrupt(context.Background(), exit)
+	defer stop()
+	cmd.SetContext(ctx)
+	if err := completeFlags(l, cmd, []string{"-f", "--no-reattach", "--pattern", "ERROR", "--dedup", "--stats", "--flush-interval", "1h", "--no-banner"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	// the followed stream never ends by itself
+	reader, writer := io.Pipe()
+	useFakeLogs(t, l)
+	logsForObject := l.LogsForObject
+	l.LogsForObject = func(getter genericclioptions.RESTClientGetter, object, options runtime.Object, timeout time.Duration, allContainers bool) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+		requests, err := logsForObject(getter, object, options, timeout, allContainers)
+		for ref := range requests {
+			requests[ref] = pipeRequest{reader: reader}
+		}
+		return requests, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+
+	io.WriteString(writer, "ERROR one\nERROR one\nINFO skipped\n")
+	interrupt(t)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Fatalf("got %v, want ErrInterrupted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the interrupted command did not end")
+	}
+	// the buffered line and its pending repeat count are written before exiting
+	if got, want := out.String(), "ERROR one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if want := "(repeated 1 times) ERROR one\n"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want the repeat count %q", errOut.String(), want)
+	}
+	if want := "Matching lines per container:\n  test/api-1/app: 1\n"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want the summary %q", errOut.String(), want)
+	}
+	select {
+	case <-exited:
+		t.Error("the command exited before Run returned")
+	default:
+	}
+}
+
+func TestSecondInterruptExitsRightAway(t *testing.T) {
+	exit, exited := recordExit()
+	shutdownTimeout = time.Hour
+	defer func() { shutdownTimeout = 5 * time.Second }()
+	ctx, stop := NotifyInterrupt(context.Background(), exit)
+	defer stop()
+
+	interrupt(t)
+	<-ctx.Done()
+	interrupt(t)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a second interrupt did not exit")
+	}
+}
+
+func TestInterruptTimeoutRunsHandlers(t *testing.T) {
+	exit, exited := recordExit()
+	shutdownTimeout = 10 * time.Millisecond
+	defer func() { shutdownTimeout = 5 * time.Second }()
+	ctx, stop := NotifyInterrupt(context.Background(), exit)
+	defer stop()
+	handled := make(chan struct{}, 1)
+	LikeOptions{ctx: ctx}.onInterrupt(func() { handled <- struct{}{} })
+
+	interrupt(t)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command did not exit after the shutdown timeout")
+	}
+	select {
+	case <-handled:
+	default:
+		t.Error("the handlers did not run before exiting")
+	}
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+const (
+	nonJSONPass = "pass"
+	nonJSONDrop = "drop"
+)
+
+var nonJSONValues = []string{nonJSONPass, nonJSONDrop}
+
+// jqProgram is the program of --jq
+type jqProgram struct {
+	code *gojq.Code
+}
+
+// compileJQ compiles the program of --jq, reporting the offset of syntax errors
+func compileJQ(expr string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		var parseErr *gojq.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("invalid --jq at offset %d: %w", parseErr.Offset, err)
+		}
+		return nil, fmt.Errorf("invalid --jq: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --jq: %w", err)
+	}
+	return code, nil
+}
+
+// jqLines runs the program of --jq on a matching line and returns a line per result.
+// The timestamp of the line, if any, prefixes every result. Lines that are not JSON are kept or dropped
+// according to --non-json, and the errors of the program are written to ErrOut without stopping the stream.
+func (l LikeOptions) jqLines(line []byte) [][]byte {
+	var timestamp []byte
+	message := line
+	if l.Timestamps {
+		if _, rest, ok := splitTimestamp(line); ok {
+			timestamp, message = line[:len(line)-len(rest)], rest
+		}
+	}
+	var v any
+	if err := json.Unmarshal(message, &v); err != nil {
+		if l.NonJSON == nonJSONDrop {
+			return nil
+		}
+		return [][]byte{line}
+	}
+
+	var lines [][]byte
+	iter := l.jq.code.Run(v)
+	for {
+		result, ok := iter.Next()
+		if !ok {
+			return lines
+		}
+		if err, ok := result.(error); ok {
+			var haltErr *gojq.HaltError
+			if errors.As(err, &haltErr) && haltErr.Value() == nil {
+				return lines
+			}
+			fmt.Fprintf(l.ErrOut, "warning: --jq: %v\n", err)
+			return lines
+		}
+		var out []byte
+		if s, ok := result.(string); ok && l.JQRaw {
+			out = []byte(s)
+		} else {
+			b, err := gojq.Marshal(result)
+			if err != nil {
+				fmt.Fprintf(l.ErrOut, "warning: --jq: %v\n", err)
+				continue
+			}
+			out = b
+		}
+		lines = append(lines, append(append(append([]byte(nil), timestamp...), out...), '\n'))
+	}
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func newJQOptions(t *testing.T, expr string) LikeOptions {
+	t.Helper()
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.JQ = expr
+	code, err := compileJQ(expr)
+	if err != nil {
+		t.Fatalf("compileJQ(%q): %v", expr, err)
+	}
+	l.jq = &jqProgram{code: code}
+	return l
+}
+
+func joinLines(lines [][]byte) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.Write(line)
+	}
+	return b.String()
+}
+
+func TestJQLinesExtractsField(t *testing.T) {
+	l := newJQOptions(t, ".msg")
+	if got, want := joinLines(l.jqLines([]byte(`{"msg":"boom","level":"error"}`+"\n"))), "\"boom\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	l.JQRaw = true
+	if got, want := joinLines(l.jqLines([]byte(`{"msg":"boom"}`+"\n"))), "boom\n"; got != want {
+		t.Errorf("with --jq-raw got %q, want %q", got, want)
+	}
+}
+
+func TestJQLinesArrayProducesSeveralLines(t *testing.T) {
+	l := newJQOptions(t, ".items[]")
+	lines := l.jqLines([]byte(`{"items":[1,{"a":2},"x"]}` + "\n"))
+	if got, want := joinLines(lines), "1\n{\"a\":2}\n\"x\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJQLinesKeepsTimestamp(t *testing.T) {
+	l := newJQOptions(t, ".msg")
+	l.Timestamps = true
+	l.JQRaw = true
+	lines := l.jqLines([]byte(`2024-06-12T10:04:05.123456789Z {"msg":"boom"}` + "\n"))
+	if got, want := joinLines(lines), "2024-06-12T10:04:05.123456789Z boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJQLinesRuntimeErrorDoesNotStopTheStream(t *testing.T) {
+	streams, _, _, errOut := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.JQ = ".a + 1"
+	code, err := compileJQ(l.JQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.jq = &jqProgram{code: code}
+
+	if lines := l.jqLines([]byte(`{"a":"text"}` + "\n")); len(lines) != 0 {
+		t.Errorf("expected no result for a failing line, got %q", joinLines(lines))
+	}
+	if !strings.Contains(errOut.String(), "warning: --jq:") {
+		t.Errorf("expected a warning on stderr, got %q", errOut.String())
+	}
+	if got, want := joinLines(l.jqLines([]byte(`{"a":1}`+"\n"))), "2\n"; got != want {
+		t.Errorf("the next line got %q, want %q", got, want)
+	}
+}
+
+func TestJQLinesNonJSON(t *testing.T) {
+	l := newJQOptions(t, ".msg")
+	line := []byte("plain text line\n")
+	if got := joinLines(l.jqLines(line)); got != string(line) {
+		t.Errorf("with --non-json=pass got %q, want %q", got, line)
+	}
+	l.NonJSON = nonJSONDrop
+	if lines := l.jqLines(line); len(lines) != 0 {
+		t.Errorf("with --non-json=drop got %q, want nothing", joinLines(lines))
+	}
+}
+
+func TestCompileJQReportsOffset(t *testing.T) {
+	_, err := compileJQ(".msg | ")
+	if err == nil || !strings.Contains(err.Error(), "offset") {
+		t.Errorf("expected a syntax error with its offset, got %v", err)
+	}
+}
+
+func TestVaildateCompilesJQ(t *testing.T) {
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.JQ = ".["
+	l.jq = &jqProgram{}
+	if err := l.Vaildate(); err == nil || !strings.Contains(err.Error(), "--jq") {
+		t.Errorf("expected an invalid --jq error, got %v", err)
+	}
+}
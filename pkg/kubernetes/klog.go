@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	// klogHeaderRegexp matches the klog header, e.g. "I0612 10:04:05.123456   12345 file.go:123] "
+	klogHeaderRegexp = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ [^\]\s]+\] `)
+
+	klogSeverities = map[byte]severity{
+		'I': severityInfo,
+		'W': severityWarning,
+		'E': severityError,
+		'F': severityFatal,
+	}
+)
+
+// klogLine is a log line split into its klog severity and message
+type klogLine struct {
+	severity severity
+	message  []byte
+}
+
+// parseKlogLine splits a klog formatted line into its severity and message.
+// When timestamps are requested, the timestamp added by the kubelet is skipped before parsing.
+func parseKlogLine(line []byte, timestamps bool) (klogLine, bool) {
+	if timestamps {
+		if i := bytes.IndexByte(line, ' '); i >= 0 {
+			line = line[i+1:]
+		}
+	}
+	header := klogHeaderRegexp.Find(line)
+	if header == nil {
+		return klogLine{}, false
+	}
+	return klogLine{
+		severity: klogSeverities[header[0]],
+		message:  line[len(header):],
+	}, true
+}
@@ -0,0 +1,42 @@
+package kubernetes
+
+import "testing"
+
+func TestParseKlogLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		timestamps bool
+		want       severity
+		message    string
+	}{
+		{line: "I0612 10:04:05.123456   12345 main.go:12] starting\n", want: severityInfo, message: "starting\n"},
+		{line: "W0612 10:04:05.123456       1 cache.go:7] slow\n", want: severityWarning, message: "slow\n"},
+		{line: "E0612 10:04:05.123456       1 server.go:99] request failed: [boom]\n", want: severityError, message: "request failed: [boom]\n"},
+		{line: "F0612 10:04:05.123456       1 main.go:40] out of memory\n", want: severityFatal, message: "out of memory\n"},
+		{line: "2024-06-12T10:04:05.123456789Z E0612 10:04:05.123456       1 main.go:12] boom\n", timestamps: true, want: severityError, message: "boom\n"},
+	}
+	for _, test := range tests {
+		entry, ok := parseKlogLine([]byte(test.line), test.timestamps)
+		if !ok {
+			t.Errorf("%q was not parsed", test.line)
+			continue
+		}
+		if entry.severity != test.want || string(entry.message) != test.message {
+			t.Errorf("%q: got severity %d and message %q, want %d and %q", test.line, entry.severity, entry.message, test.want, test.message)
+		}
+	}
+}
+
+func TestParseKlogLineRejectsOtherFormats(t *testing.T) {
+	for _, line := range []string{
+		"ERROR not a klog header\n",
+		"X0612 10:04:05.123456       1 main.go:12] unknown severity\n",
+		"I0612 10:04:05       1 main.go:12] no microseconds\n",
+		// the kubelet timestamp is only skipped with timestamps
+		"2024-06-12T10:04:05.123456789Z E0612 10:04:05.123456       1 main.go:12] boom\n",
+	} {
+		if _, ok := parseKlogLine([]byte(line), false); ok {
+			t.Errorf("%q was parsed as klog", line)
+		}
+	}
+}
@@ -2,25 +2,45 @@ package kubernetes
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"regexp"
+	"slices"
+	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
 	"k8s.io/client-go/rest"
 	"k8s.io/kubectl/pkg/cmd/logs"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
-	"k8s.io/kubectl/pkg/util/completion"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
 	utilcomp "k8s.io/kubectl/pkg/util/completion"
 )
 
 const (
-	logsUsageStr          = "like [-f] [-p] (POD | TYPE/NAME) [-c CONTAINER]"
+	logsUsageStr          = "like [-f] [-p] (POD | TYPE/NAME)... [-c CONTAINER]"
 	defaultPodLogsTimeout = 20 * time.Second
+	// matchAllPattern was the default pattern and is kept as an alias of the empty pattern.
+	// A literal "*" has to be escaped as `\*`.
+	matchAllPattern = "*"
+	// ellipsis ends the lines truncated by --max-line-length
+	ellipsis = "…"
+	// defaultContainerRefRegexp extracts the kind and the name of the container from the field path of the
+	// references returned by the logs helpers, e.g. spec.containers{app}
+	defaultContainerRefRegexp = `spec\.(initContainers|containers|ephemeralContainers){(.+)}`
 )
 
 var (
@@ -29,11 +49,181 @@ var (
 )
 
 type LikeOptions struct {
-	Pattern string
+	Pattern              string
+	ContainerRefRegexp   string
+	NoFilter             bool
+	Quiet                bool
+	OnlyMatching         bool
+	SplitStreams         bool
+	MaxLineLength        int
+	Null                 bool
+	FlushInterval        time.Duration
+	StripANSI            bool
+	RawOutput            bool
+	RawBytes             bool
+	MultilineJSON        bool
+	PrettyJSON           bool
+	JQ                   string
+	JQRaw                bool
+	NonJSON              string
+	IgnoreCase           bool
+	FixedStrings         bool
+	MatchTimeout         time.Duration
+	BeforeLines          int
+	Head                 int
+	Dedup                bool
+	DedupWindow          int
+	GroupBy              string
+	MaxGroups            int
+	MaxBytes             int64
+	LimitOutputBytes     int64
+	Compare              bool
+	CompareThreshold     float64
+	For                  time.Duration
+	NoMatchTimeout       time.Duration
+	NoMatchTimeoutMode   string
+	Wait                 bool
+	WaitTimeout          time.Duration
+	OrderedBacklog       bool
+	ResumeFile           string
+	ResumeMaxAge         time.Duration
+	MergeTimestamps      bool
+	MergeWindow          time.Duration
+	MatchColumns         string
+	MatchRunes           bool
+	Timeout              time.Duration
+	IdleTimeout          time.Duration
+	Stats                bool
+	MetricsAddr          string
+	PerSourceBuffer      int
+	ResourceArgs         []string
+	FieldSelector        string
+	Node                 string
+	AllNamespaces        bool
+	Klog                 bool
+	MinSeverity          string
+	SeverityFormat       string
+	InitContainers       bool
+	EphemeralContainers  bool
+	PreviousAndCurrent   bool
+	SinceLastRestart     bool
+	Verbose              bool
+	NoBanner             bool
+	BannerFormat         string
+	NoReattach           bool
+	ExitWithContainer    bool
+	PipefailExit         bool
+	ReconnectMaxAttempts int
+	ReconnectMaxBackoff  time.Duration
+	Retries              int
+	LogLevel             string
+	OutputDir            string
+	OutputFile           string
+	Tee                  string
+	MaxFileSize          string
+	MaxFiles             int
+	NoStdout             bool
+	CaptureRaw           string
+	TimestampsFormat     string
+	TimestampFormat      string
+	Heartbeat            time.Duration
+	HeartbeatMode        string
+	Exec                 string
+	ExecThrottle         time.Duration
+	Webhook              string
+	WebhookBatch         string
+	WebhookTemplate      string
+	LokiURL              string
+	LokiLabels           []string
+	OTLPEndpoint         string
+	Bell                 string
+	Notify               string
+	NotifyEvery          int
+	NotifyCommand        string
+	DryRun               bool
+	Exclude              []string
+	ExcludeContainers    []string
+	ContainerRegexp      string
+	Presets              []string
+	ListPresets          bool
+	Contexts             []string
+	PodStatus            []string
+	OnlyReady            bool
+	ExcludeSelector      string
+	ExcludeAnnotations   []string
+	PrefixLabels         []string
+	PrefixAnnotations    []string
+	Output               string
+	Template             string
+	OutputTemplate       string
+	Columns              []string
+	ColorBy              string
+	Color                string
+	NoColor              bool
 	*logs.LogsOptions
 	KubernetesConfigFlags          *genericclioptions.ConfigFlags
 	factory                        cmdutil.Factory
 	containerNameFromRefSpecRegexp *regexp.Regexp
+	minSeverity                    severity
+	severityOf                     severityParser
+	objects                        []runtime.Object
+	patternRegexp                  *regexp.Regexp
+	logger                         *slog.Logger
+	outputs                        *outputDir
+	excludeRegexps                 []*regexp.Regexp
+	excludeContainerRegexps        []*regexp.Regexp
+	containerRegexp                *regexp.Regexp
+	contextName                    string
+	// namespaceFrom tells where the namespace comes from, one of the namespaceFrom constants
+	namespaceFrom      string
+	contextOptions     []*LikeOptions
+	podPhases          map[corev1.PodPhase]bool
+	excludeSelector    labels.Selector
+	excludeAnnotations []annotationExclusion
+	byReplicaSet       bool
+	matchCounts        *matchCounts
+	tally              *matchTally
+	lineGroups         *lineGroups
+	lineExec           *lineExec
+	webhook            notifier
+	notifyBatch        notifyBatch
+	loki               notifier
+	otlp               *otlpExporter
+	notifySinks        []*notifySink
+	bell               *bell
+	desktopNotifier    *desktopNotifier
+	unmatched          io.Writer
+	merger             *timestampMerger
+	budget             *outputBudget
+	compareSelectors   []string
+	matchColumns       *columnRange
+	stats              *matchCounts
+	streamMetrics      *streamMetrics
+	idle               *idleTimer
+	deadline           *runDeadline
+	noMatch            *noMatchTimer
+	exits              *containerExits
+	resume             *resumeState
+	template           *template.Template
+	colorize           bool
+	maxFileSize        int64
+	capture            *rawCapture
+	timestampOrigin    *timestampOrigin
+	colorizeErr        bool
+	heartbeat          *heartbeat
+	writeHeartbeat     bool
+	jq                 *jqProgram
+	compareOptions     []*LikeOptions
+	// notices is where the stream writes what is not a line of the container, e.g. a restart marker
+	notices io.Writer
+	// ctx is the context of the command, canceled when it is interrupted
+	ctx context.Context
+	// requestTimeout is the --request-timeout opening the followed streams, which it does not bound
+	requestTimeout time.Duration
+	// literalPattern is the pattern when it is a plain string, matched with bytes.Contains rather than the regexp
+	literalPattern []byte
+	// snapshot counts the lines of the followed streams for the snapshot printed on SIGUSR1
+	snapshot *statsSnapshot
 }
 
 // NewLikeOptions creates a new LikeOptions struct
@@ -46,7 +236,34 @@ func NewLikeOptions(streams genericiooptions.IOStreams) LikeOptions {
 		KubernetesConfigFlags:          KubernetesConfigFlags,
 		factory:                        f,
 		LogsOptions:                    l,
-		containerNameFromRefSpecRegexp: regexp.MustCompile(`spec\.(?:initContainers|containers|ephemeralContainers){(.+)}`),
+		containerNameFromRefSpecRegexp: regexp.MustCompile(defaultContainerRefRegexp),
+		ContainerRefRegexp:             defaultContainerRefRegexp,
+		LogLevel:                       "error",
+		PodStatus:                      []string{string(corev1.PodRunning)},
+		PerSourceBuffer:                defaultPerSourceBuffer,
+		Output:                         outputText,
+		ColorBy:                        colorByMatch,
+		Color:                          colorAuto,
+		MaxFiles:                       defaultMaxFiles,
+		TimestampsFormat:               timestampsRaw,
+		NonJSON:                        nonJSONPass,
+		FlushInterval:                  defaultFlushInterval,
+		BannerFormat:                   bannerText,
+		ReconnectMaxAttempts:           defaultReconnectMaxAttempts,
+		ReconnectMaxBackoff:            defaultReconnectMaxBackoff,
+		Retries:                        defaultRetries,
+		MaxGroups:                      defaultMaxGroups,
+		DedupWindow:                    defaultDedupWindow,
+		WaitTimeout:                    defaultWaitTimeout,
+		HeartbeatMode:                  alertAuto,
+		NoMatchTimeoutMode:             noMatchReset,
+		ResumeMaxAge:                   defaultResumeMaxAge,
+		MergeWindow:                    defaultMergeWindow,
+		WebhookBatch:                   defaultWebhookBatch,
+		Bell:                           alertNever,
+		Notify:                         alertNever,
+		NotifyCommand:                  defaultNotifyCommand(),
+		logger:                         slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
 }
 
@@ -54,8 +271,131 @@ func NewLikeOptions(streams genericiooptions.IOStreams) LikeOptions {
 func (l *LikeOptions) AddFlags(cmd *cobra.Command) {
 	// Add flags from logs command
 	l.LogsOptions.AddFlags(cmd)
+	// --timestamps also takes the format of the timestamps
+	timestamps := cmd.Flag("timestamps")
+	timestamps.Value = &timestampsValue{timestamps: &l.Timestamps, format: &l.TimestampsFormat}
+	timestamps.Usage = fmt.Sprintf("Include timestamps on each line in the log output. One of: true, false, %s. relative prints them relative to now, e.g. -3m12s, and elapsed relative to the first printed line.", strings.Join(timestampsFormats, ", "))
+	// --since also takes days and rejects a duration without unit with an example
+	since := cmd.Flag("since")
+	since.Value = &sinceValue{since: &l.SinceSeconds}
+	since.Usage = "Only return logs newer than a relative duration like 90m, 1h30m, 1.5h or 2d. Defaults to all logs. Only one of since-time / since may be used."
+	cmd.Flag("since-time").Usage = "Only return logs after a specific date (RFC3339), or a local date and time like 2024-06-12T10:04:05 or 2024-06-12. Defaults to all logs. Only one of since-time / since may be used."
+	// --limit-bytes limits the logs read from the server, before any filtering
+	cmd.Flag("limit-bytes").Usage = "Maximum bytes of logs to read from every container, before filtering: a line that does not match --pattern counts too. Use --limit-output-bytes to limit the bytes of matching lines written. Defaults to no limit."
 	// Add flags from like command
-	cmd.Flags().StringVar(&l.Pattern, "pattern", "*", "pattern to match logs with regex")
+	cmd.Flag("all-pods").Usage = "Get logs from all pod(s), or from every pod of the namespace if no POD, TYPE/NAME or selector is given. Sets prefix to true."
+	cmd.Flags().StringVar(&l.Pattern, "pattern", l.Pattern, "pattern to match logs with regex. If empty or '*', every line is printed. Escape a literal '*' as '\\*'")
+	cmd.Flags().BoolVar(&l.NoFilter, "no-filter", l.NoFilter, "If true, print every line exactly as kubectl logs would, without any filtering, e.g. to check that the logs can be streamed.")
+	cmd.Flags().BoolVar(&l.Quiet, "quiet", l.Quiet, "If true, do not print notes to stderr, e.g. that --pattern matched no line of the logs read without --follow.")
+	cmd.Flags().BoolVar(&l.OnlyMatching, "only-matching", l.OnlyMatching, "If true, print only the matches of the pattern, each on its own line, like grep -o. There is no shorthand because -o is --output.")
+	cmd.Flags().IntVar(&l.MaxLineLength, "max-line-length", l.MaxLineLength, "If positive, truncate the printed lines longer than this number of characters with an ellipsis. The pattern still matches the whole line. 0 disables the truncation.")
+	cmd.Flags().IntVar(&l.Head, "head", l.Head, "If positive, stop reading the log of every container after this many lines, matching or not, e.g. to sample the startup logs. 0 means no limit.")
+	cmd.Flags().BoolVarP(&l.Null, "null", "0", l.Null, "If true, end every printed line with a NUL byte instead of a line break, e.g. for xargs -0.")
+	cmd.Flags().DurationVar(&l.FlushInterval, "flush-interval", l.FlushInterval, "Buffer the matching lines written to stdout and --output-file, and write them at most this long after they matched. With -f, --output-file is also fsynced at this interval. 0 writes every line at once.")
+	cmd.Flags().BoolVar(&l.SplitStreams, "split-streams", l.SplitStreams, "If true, write the matching lines to stdout and the other lines to stderr, prefixed with their source like the matching ones, so that each can be redirected.")
+	cmd.Flags().BoolVar(&l.StripANSI, "strip-ansi", l.StripANSI, "If true, remove the ANSI escape sequences, e.g. colors, of the lines before matching and printing them.")
+	cmd.Flags().BoolVar(&l.RawBytes, "raw-bytes", l.RawBytes, "If true, treat the lines as opaque bytes that may not be UTF-8, e.g. of binary streams: the pattern matches bytes, e.g. \\x80 the byte 0x80 and . a single byte, and --max-line-length counts bytes. Non-ASCII characters of the pattern, e.g. é, then match their code as a single byte, not UTF-8 text. The options decoding the lines as text are rejected.")
+	cmd.Flags().BoolVar(&l.RawOutput, "raw-output", l.RawOutput, "If true, print the control characters of the lines, e.g. the escape sequences moving the cursor, as they are instead of escaping them.")
+	cmd.Flags().DurationVar(&l.MatchTimeout, "match-timeout", l.MatchTimeout, "If set, skip the lines that take longer than this to match, e.g. 100ms, with a warning, so that a single huge line does not stall the stream. 0 means no timeout.")
+	cmd.Flags().IntVarP(&l.BeforeLines, "before-lines", "B", l.BeforeLines, "Print this many lines before every matching line, like grep -B, also when following.")
+	cmd.Flags().BoolVar(&l.MultilineJSON, "multiline-json", l.MultilineJSON, "If true, reassemble the JSON objects pretty-printed across several lines, then match and print every object as a whole.")
+	cmd.Flags().BoolVar(&l.PrettyJSON, "pretty-json", l.PrettyJSON, "If true, print the matching lines that are a JSON object or array indented, and the other ones as they are. The results of --jq are indented too.")
+	cmd.Flags().StringVar(&l.JQ, "jq", l.JQ, "If set, apply this jq program to every matching line that is JSON and print its results, e.g. '.msg'.")
+	cmd.Flags().BoolVar(&l.JQRaw, "jq-raw", l.JQRaw, "If true, print the string results of --jq without quotes, like jq -r.")
+	cmd.Flags().StringVar(&l.NonJSON, "non-json", l.NonJSON, fmt.Sprintf("What --jq does with the matching lines that are not JSON. One of: %s.", strings.Join(nonJSONValues, ", ")))
+	cmd.Flags().BoolVarP(&l.IgnoreCase, "ignore-case", "i", l.IgnoreCase, "If true, match the pattern case-insensitively.")
+	cmd.Flags().BoolVar(&l.FixedStrings, "fixed-strings", l.FixedStrings, "If true, match the pattern as a plain string instead of a regex, like grep -F. A pattern without regex metacharacters is always matched this way, which is faster.")
+	cmd.Flags().BoolVar(&l.Dedup, "dedup", l.Dedup, "If true, drop the matching lines of a container identical to one of its last --dedup-window distinct lines, and write '(repeated N times) LINE' to stderr once the line leaves the window. Timestamps and klog headers are ignored when comparing lines.")
+	cmd.Flags().IntVar(&l.DedupWindow, "dedup-window", l.DedupWindow, "Number of distinct lines --dedup compares every line with. 1 only collapses consecutive identical lines.")
+	cmd.Flags().StringVar(&l.GroupBy, "group-by", l.GroupBy, fmt.Sprintf("Count the matching lines per group and print the counts to stderr at the end, one of: %s. Or print the matching lines grouped by the value of this capture group of the pattern, by name or index, once they are all read.", strings.Join(groupByValues, ", ")))
+	cmd.Flags().IntVar(&l.MaxGroups, "max-groups", l.MaxGroups, "Maximum number of values of the capture group of --group-by whose lines are kept, the lines of other values are dropped.")
+	cmd.Flags().Int64Var(&l.MaxBytes, "max-bytes", l.MaxBytes, "Stop after writing this many bytes of matching lines across all containers, printing a notice to stderr. The bytes are counted as written, prefixes, colors and timestamps included, and a line is never cut. 0 means unlimited.")
+	cmd.Flags().Int64Var(&l.LimitOutputBytes, "limit-output-bytes", l.LimitOutputBytes, "Same as --max-bytes, only one of them may be specified. Unlike --limit-bytes, it counts the matching lines written rather than the logs read.")
+	cmd.Flags().BoolVar(&l.Compare, "compare", l.Compare, "If true, the two arguments are label selectors whose match rates per pod are compared at the end, e.g. --compare track=stable track=canary.")
+	cmd.Flags().Float64Var(&l.CompareThreshold, "compare-threshold", 10, "With --compare, exit with code 1 when the match rate per pod of the second group is more than this percentage above the first one.")
+	cmd.Flags().DurationVar(&l.NoMatchTimeout, "no-match-timeout", l.NoMatchTimeout, "When following, stop and exit with code 1 once no line matched for this duration, e.g. 2m to gate a rollout on a line telling that the pods are ready. 0 means no timeout.")
+	cmd.Flags().StringVar(&l.NoMatchTimeoutMode, "no-match-timeout-mode", l.NoMatchTimeoutMode, fmt.Sprintf("How --no-match-timeout is measured: reset restarts it on every matching line, absolute only waits for the first matching line from the start. One of: %s.", strings.Join(noMatchModes, ", ")))
+	cmd.Flags().DurationVar(&l.For, "for", l.For, "Stop after this duration, e.g. 10m, whatever the state of the streams, once the buffered output is flushed and the summaries are printed, and exit with code 0. With --compare, follow the logs for this duration, then print the comparison.")
+	cmd.Flags().BoolVar(&l.Wait, "wait", l.Wait, "If true, wait for the POD, or a pod selected by the selectors, to exist and start its container before reading the logs, e.g. right after kubectl apply. Why it does not start yet, e.g. ImagePullBackOff, is written to stderr.")
+	cmd.Flags().DurationVar(&l.WaitTimeout, "wait-timeout", l.WaitTimeout, "How long --wait waits for the pod to start before failing.")
+	cmd.Flags().BoolVar(&l.OrderedBacklog, "ordered-backlog", l.OrderedBacklog, "If true, read the existing logs of every container first and print them ordered by timestamp, then follow the new lines.")
+	cmd.Flags().StringVar(&l.ResumeFile, "resume-file", l.ResumeFile, "If set, read every container from the last line read by the previous run saved in this JSON file, or with --since if it has no entry, and save the last line read from every container to it at the end.")
+	cmd.Flags().DurationVar(&l.ResumeMaxAge, "resume-max-age", l.ResumeMaxAge, "Ignore the entries of --resume-file older than this duration, the containers are then read with --since. 0 means no limit.")
+	cmd.Flags().BoolVar(&l.MergeTimestamps, "merge-timestamps", l.MergeTimestamps, "If true, read the containers at the same time and print their lines ordered by the timestamps of the server. Lines without a timestamp keep their place after the previous line of their container.")
+	cmd.Flags().DurationVar(&l.MergeWindow, "merge-window", l.MergeWindow, "How long --merge-timestamps holds a line for the older lines of other containers before printing it.")
+	cmd.Flags().StringVar(&l.MatchColumns, "match-columns", l.MatchColumns, "Only match the pattern against these columns of each line, as START:END counted from 1, e.g. 20:40. Either side can be omitted.")
+	cmd.Flags().BoolVar(&l.MatchRunes, "match-runes", l.MatchRunes, "If true, --match-columns counts characters instead of bytes.")
+	cmd.Flags().DurationVar(&l.Timeout, "timeout", l.Timeout, "Abort reading the logs of a container after this duration, e.g. 30s. Does not apply when following. 0 means no timeout.")
+	cmd.Flags().DurationVar(&l.IdleTimeout, "idle-timeout", l.IdleTimeout, "When following, exit once no new line was received from any container for this duration, e.g. 1m. 0 means no timeout.")
+	cmd.Flags().StringVar(&l.TimestampFormat, "timestamp-format", l.TimestampFormat, fmt.Sprintf("Reformat the timestamps of --timestamps in the local timezone with this Go layout, e.g. '2006-01-02 15:04:05', or one of: %s.", strings.Join(timestampLayouts, ", ")))
+	cmd.Flags().DurationVar(&l.Heartbeat, "heartbeat", l.Heartbeat, "If set, write a status line with the number of lines read to stderr when no line was printed for this interval while following, e.g. 30s.")
+	cmd.Flags().StringVar(&l.HeartbeatMode, "heartbeat-mode", l.HeartbeatMode, fmt.Sprintf("When to write the status lines of --heartbeat: auto writes them only when stderr is a terminal, always also otherwise. One of: %s.", strings.Join(alertModes, ", ")))
+	cmd.Flags().StringVar(&l.Exec, "exec", l.Exec, "If set, run this shell command for every matching line, with the line on stdin and its pod, container, namespace and context in the LIKE_POD, LIKE_CONTAINER, LIKE_NAMESPACE and LIKE_CONTEXT variables. Its output and failures are written to stderr.")
+	cmd.Flags().DurationVar(&l.ExecThrottle, "exec-throttle", l.ExecThrottle, "Minimum interval between two runs of the --exec command, e.g. 1s. The lines are queued meanwhile and dropped once the queue is full.")
+	cmd.Flags().StringVar(&l.Webhook, "webhook", l.Webhook, "If set, POST the matching lines as JSON to this URL, with their namespace, pod, container, timestamp and the pattern. Failed requests are retried, then the lines are dropped and counted by --stats.")
+	cmd.Flags().StringVar(&l.WebhookBatch, "webhook-batch", l.WebhookBatch, "Number of matching lines sent together to --webhook, optionally followed by the longest time to wait for them, e.g. 10/5s.")
+	cmd.Flags().StringVar(&l.LokiURL, "loki-url", l.LokiURL, "If set, push the matching lines to this Grafana Loki, e.g. http://loki:3100, labeled with their namespace, pod and container. Uses basic auth with LOKI_USERNAME and LOKI_PASSWORD when set.")
+	cmd.Flags().StringVar(&l.OTLPEndpoint, "otlp-endpoint", l.OTLPEndpoint, "If set, export the matching lines as OpenTelemetry log records to this OTLP/HTTP endpoint, e.g. otel-collector:4318, configured by the OTEL_EXPORTER_OTLP_* and OTEL_BLRP_* environment variables.")
+	cmd.Flags().StringArrayVar(&l.LokiLabels, "loki-label", l.LokiLabels, "Additional KEY=VALUE label of the lines pushed to --loki-url. Can be repeated.")
+	cmd.Flags().StringVar(&l.WebhookTemplate, "webhook-template", l.WebhookTemplate, "Template of the body sent to --webhook, with the lines in .Matches and as text in .Text, e.g. '{\"text\": {{json .Text}}}' for Slack.")
+	cmd.Flags().StringVar(&l.Bell, "bell", l.Bell, fmt.Sprintf("Ring the terminal bell on matching lines, at most once every %s. --bell rings only when stderr is a terminal, --bell=always also otherwise. One of: %s.", bellInterval, strings.Join(alertModes, ", ")))
+	cmd.Flag("bell").NoOptDefVal = alertAuto
+	cmd.Flags().StringVar(&l.Notify, "notify", l.Notify, fmt.Sprintf("Show a desktop notification on the first matching line with --notify-command. --notify only notifies when stdout is a terminal, --notify=always also otherwise. One of: %s.", strings.Join(alertModes, ", ")))
+	cmd.Flag("notify").NoOptDefVal = alertAuto
+	cmd.Flags().IntVar(&l.NotifyEvery, "notify-every", l.NotifyEvery, "With --notify, also notify on every N matching lines after the first one. 0 only notifies on the first one.")
+	cmd.Flags().StringVar(&l.NotifyCommand, "notify-command", l.NotifyCommand, "Command of --notify, run without a shell. Every argument is a template of .Title, .Message, .Pod, .Container, .Namespace, .Line and .Count.")
+	cmd.Flags().StringVar(&l.MetricsAddr, "metrics-addr", l.MetricsAddr, "If set, serve Prometheus metrics of the lines read and matched per container on this address, e.g. :9090, at /metrics while the command runs.")
+	cmd.Flags().BoolVar(&l.Stats, "stats", l.Stats, "If true, print the number of matching lines of every container to stderr at the end, including the lines dropped by --per-source-buffer.")
+	cmd.Flags().IntVar(&l.PerSourceBuffer, "per-source-buffer", l.PerSourceBuffer, "Number of lines a container can queue while the output is busy with other containers. Its next lines are dropped when the queue is full.")
+	cmd.Flags().StringVar(&l.FieldSelector, "field-selector", l.FieldSelector, "Selector (field query) to filter pods on, supports '=', '==', and '!='.(e.g. --field-selector spec.nodeName=worker-3,status.phase=Running)")
+	cmd.Flags().StringVar(&l.Node, "node", l.Node, "Only select pods scheduled on this node. Shorthand for --field-selector spec.nodeName=NAME.")
+	cmd.Flags().StringSliceVar(&l.PodStatus, "pod-status", l.PodStatus, "Only stream the selected pods in these phases (Pending, Running, Succeeded, Failed, Unknown). Pods named explicitly are always streamed.")
+	cmd.Flags().BoolVar(&l.OnlyReady, "only-ready", l.OnlyReady, "If true, only stream the selected pods that are ready.")
+	cmd.Flags().StringVar(&l.ExcludeSelector, "exclude-selector", l.ExcludeSelector, "Selector (label query) of the selected pods not to stream, e.g. --exclude-selector track=canary.")
+	cmd.Flags().StringArrayVar(&l.ExcludeAnnotations, "exclude-annotation", l.ExcludeAnnotations, "Do not stream the selected pods with an annotation matching KEY=VALUE_REGEX. Can be repeated.")
+	cmd.Flags().StringSliceVar(&l.PrefixLabels, "prefix-label", l.PrefixLabels, "Add the KEY=VALUE of these labels of the pod to the prefix of its lines, e.g. --prefix-label version to tell canary and stable pods apart. Sets prefix to true.")
+	cmd.Flags().StringSliceVar(&l.PrefixAnnotations, "prefix-annotation", l.PrefixAnnotations, "Add the KEY=VALUE of these annotations of the pod to the prefix of its lines. Sets prefix to true.")
+	cmd.Flags().BoolVarP(&l.AllNamespaces, "all-namespaces", "A", l.AllNamespaces, "If present, select pods across all namespaces. Requires a selector.")
+	cmd.Flags().BoolVar(&l.Klog, "klog", l.Klog, "If true, parse lines as klog output and match the pattern against the message only")
+	cmd.Flags().StringVar(&l.MinSeverity, "min-severity", l.MinSeverity, "Only print lines at or above this severity (trace, debug, info, warning, error, fatal). Lines without a detected severity are dropped.")
+	cmd.Flags().StringVar(&l.SeverityFormat, "severity-format", "auto", fmt.Sprintf("How to detect the severity of a line for --min-severity. One of: %s.", strings.Join(severityFormats, ", ")))
+	cmd.Flags().BoolVar(&l.InitContainers, "init-containers", l.InitContainers, "Include init containers when using --all-containers. Terminated init containers are read before the other containers are streamed.")
+	cmd.Flags().BoolVar(&l.EphemeralContainers, "ephemeral-containers", l.EphemeralContainers, "Include ephemeral containers when using --all-containers.")
+	cmd.Flags().BoolVar(&l.PreviousAndCurrent, "previous-and-current", l.PreviousAndCurrent, "If true, print the logs of the previous instance of the container, then the current one, separated by the restart time.")
+	cmd.Flags().BoolVar(&l.PreviousAndCurrent, "include-previous", l.PreviousAndCurrent, "Alias of --previous-and-current.")
+	cmd.Flags().BoolVar(&l.SinceLastRestart, "since-last-restart", l.SinceLastRestart, "If true, only return the logs of every container since its last restart, or since it started if it never restarted.")
+	cmd.Flags().BoolVar(&l.NoReattach, "no-reattach", l.NoReattach, "If true, stop following a container when it restarts instead of reattaching to the new instance.")
+	cmd.Flags().BoolVar(&l.ExitWithContainer, "exit-with-container", l.ExitWithContainer, "If true, exit with the code of the first followed container that exited with a non-zero code once the streams ended, e.g. 137 when it was OOMKilled.")
+	cmd.Flags().BoolVar(&l.PipefailExit, "pipefail-exit", l.PipefailExit, "If true, exit with code 141 like a command killed by SIGPIPE when the reader of the output exits first, e.g. head. By default the streams stop quietly and the command exits with 0.")
+	cmd.Flags().IntVar(&l.ReconnectMaxAttempts, "reconnect-max-attempts", l.ReconnectMaxAttempts, "When following, how many times in a row a log stream that failed, e.g. on a restart of the API server, is opened again before giving up. 0 gives up at once.")
+	cmd.Flags().IntVar(&l.Retries, "retries", l.Retries, "How many times a request to the API server failing with a transient error, e.g. a timeout, 429 or 5xx, or a refused connection, is retried while resolving the pods and opening the log streams. The waits between them grow exponentially, with jitter. 0 fails at once.")
+	cmd.Flags().DurationVar(&l.ReconnectMaxBackoff, "reconnect-max-backoff", l.ReconnectMaxBackoff, "The longest wait before opening a failed log stream again, the wait starting at 1s and doubling after every failed attempt.")
+	cmd.Flags().StringVar(&l.OutputDir, "output-dir", l.OutputDir, "If set, write the matching lines of each container to its own NAMESPACE_POD_CONTAINER.log file in this directory and print a summary.")
+	cmd.Flags().StringVar(&l.OutputFile, "output-file", l.OutputFile, "If set, also append the matching lines to this file.")
+	cmd.Flags().StringVar(&l.Tee, "tee", l.Tee, "If set, also write the matching lines to this file, truncating it first like tee.")
+	cmd.Flags().StringVar(&l.MaxFileSize, "max-file-size", l.MaxFileSize, "Rotate --output-file when it would grow beyond this size, e.g. 50MB. Empty means never.")
+	cmd.Flags().IntVar(&l.MaxFiles, "max-files", l.MaxFiles, "Number of rotated files of --output-file to keep, named FILE.1, FILE.2 and so on.")
+	cmd.Flags().BoolVar(&l.NoStdout, "no-stdout", l.NoStdout, "If true, only write the matching lines to --output-file, not to stdout.")
+	cmd.Flags().StringVar(&l.CaptureRaw, "capture-raw", l.CaptureRaw, "If set, also write every line of every container, before any filtering and prefixed with its source, to this gzip file, e.g. raw.log.gz.")
+	cmd.Flags().StringArrayVar(&l.Exclude, "exclude", l.Exclude, "Drop lines matching this regex, even if they match the pattern. Can be repeated.")
+	cmd.Flags().StringVar(&l.ContainerRefRegexp, "container-ref-regexp", l.ContainerRefRegexp, "Advanced: the regex extracting the kind and the name of a container, its two capture groups, from the field path of the references of the logs helpers, for resources referencing their containers differently.")
+	cmd.Flags().StringVar(&l.ContainerRegexp, "container-regexp", l.ContainerRegexp, "Only stream the containers whose name matches this regex, along with the one given with -c.")
+	cmd.Flags().StringArrayVar(&l.ExcludeContainers, "exclude-container", l.ExcludeContainers, "Do not stream containers whose name matches this regex. Can be repeated.")
+	cmd.Flags().StringSliceVar(&l.Presets, "preset", l.Presets, "Apply the exclusions of these presets, e.g. mesh or quiet-http. Presets can also be defined in the config file.")
+	cmd.Flags().BoolVar(&l.ListPresets, "list-presets", l.ListPresets, "If true, print the available presets and exit.")
+	cmd.Flags().StringSliceVar(&l.Contexts, "contexts", l.Contexts, "Stream the same target from every one of these kubeconfig contexts, prefixing lines with the context.")
+	cmd.Flags().BoolVar(&l.DryRun, "dry-run", l.DryRun, "If true, only print the containers that would be streamed and the effective filter options.")
+	cmd.Flags().StringVarP(&l.Output, "output", "o", l.Output, fmt.Sprintf("Output format of the matching lines and of --dry-run. One of: %s. json writes an object per line with its source, timestamp and capture groups, go-template formats it with --template, csv and tsv write a row per line with --columns.", strings.Join(outputFormats, ", ")))
+	cmd.Flags().StringVar(&l.Template, "template", l.Template, "Template of every matching line with -o go-template, e.g. '{{.Pod}} {{.Timestamp}} {{.Line}}'. The functions color, trunc and date are available.")
+	cmd.Flags().StringVar(&l.OutputTemplate, "output-template", l.OutputTemplate, "Go template executed for every matching line, short for -o go-template --template, e.g. '{{.LineNumber}} {{.Pod}}/{{.Container}}: {{.Match}}'.")
+	cmd.Flags().StringSliceVar(&l.Columns, "columns", l.Columns, fmt.Sprintf("Columns of -o csv and -o tsv, among %s and the named capture groups of the pattern. Defaults to %s and the named capture groups.", strings.Join(recordColumns, ", "), strings.Join(defaultRecordColumns, ",")))
+	cmd.Flags().StringVar(&l.ColorBy, "color-by", l.ColorBy, fmt.Sprintf("How to color the lines on a terminal: match highlights the matches, level colors warnings and errors, pod colors every pod differently. One of: %s.", strings.Join(colorByValues, ", ")))
+	cmd.Flags().StringVar(&l.Color, "color", l.Color, fmt.Sprintf("When to write colors: auto writes them only to a terminal and when NO_COLOR is not set. One of: %s.", strings.Join(colorModes, ", ")))
+	cmd.Flags().BoolVar(&l.NoColor, "no-color", l.NoColor, "If true, never write colors. Same as --color=never.")
+	cmd.Flags().StringVar(&l.LogLevel, "log-level", l.LogLevel, "Level of the plugin's own diagnostics written to stderr. One of: debug, info, warn, error.")
+	cmd.Flags().BoolVar(&l.Verbose, "verbose", l.Verbose, "If true, print the resolved namespace, pods and containers to stderr before streaming.")
+	cmd.Flags().BoolVar(&l.NoBanner, "no-banner", l.NoBanner, "If true, do not print the line with the context, namespace, targets and pattern to stderr before streaming.")
+	cmd.Flags().StringVar(&l.BannerFormat, "banner-format", l.BannerFormat, fmt.Sprintf("The format of the line printed to stderr before streaming. One of: %s.", strings.Join(bannerFormats, ", ")))
 	// Add flags from kubectl command
 	l.KubernetesConfigFlags.AddFlags(cmd.Flags())
 	// reset help flag that is the help for kubectl and remove it from the command
@@ -65,71 +405,1151 @@ func (l *LikeOptions) AddFlags(cmd *cobra.Command) {
 
 // Complete fills in the gaps in the LikeOptions struct
 func (l *LikeOptions) Complete(args []string, cmd *cobra.Command) error {
-	if err := l.LogsOptions.Complete(l.factory, cmd, args); err != nil {
+	if err := l.applyConfig(cmd); err != nil {
 		return err
 	}
-	// Set the consume request function if the pattern is not empty
+	l.ctx = cmd.Context()
+
+	logger, err := newLogger(l.ErrOut, l.LogLevel)
+	if err != nil {
+		return err
+	}
+	l.logger = logger
+
+	// presets expand into the exclusions before anything is validated
+	if err := l.expandPresets(); err != nil {
+		return err
+	}
+
+	l.ContainerNameSpecified = cmd.Flag("container").Changed
+	l.TailSpecified = cmd.Flag("tail").Changed
+	l.ResourceArgs = args
+
+	// in comparison mode the arguments are the label selectors of the two groups
+	if l.Compare {
+		if len(args) != 2 {
+			return cmdutil.UsageErrorf(cmd, "--compare requires two label selectors, e.g. --compare track=stable track=canary")
+		}
+		if len(l.Selector) > 0 {
+			return cmdutil.UsageErrorf(cmd, "--compare cannot be used with a selector (-l)")
+		}
+		l.compareSelectors = args
+		l.ResourceArgs = nil
+		args = nil
+		if l.For > 0 {
+			l.Follow = true
+		}
+	}
+
+	if len(l.Node) > 0 {
+		nodeSelector := "spec.nodeName=" + l.Node
+		if len(l.FieldSelector) > 0 {
+			nodeSelector = l.FieldSelector + "," + nodeSelector
+		}
+		l.FieldSelector = nodeSelector
+	}
+	if len(l.FieldSelector) > 0 {
+		// a malformed selector fails before anything is requested, not once the pods are listed
+		if _, err := fields.ParseSelector(l.FieldSelector); err != nil {
+			return fmt.Errorf("invalid --field-selector: %w", err)
+		}
+	}
+
+	if len(args) == 0 && len(l.Selector) == 0 && len(l.FieldSelector) == 0 && !l.AllPods && !l.Compare {
+		return cmdutil.UsageErrorf(cmd, "%s", logsUsageErrStr)
+	}
+	if len(args) > 0 && len(l.Selector) != 0 {
+		return cmdutil.UsageErrorf(cmd, "only a selector (-l) or POD names are allowed")
+	}
+	if len(args) > 0 && l.AllNamespaces {
+		return cmdutil.UsageErrorf(cmd, "--all-namespaces can only be used with a selector (-l or --field-selector)")
+	}
+	if len(args) == 1 {
+		l.ResourceArg = args[0]
+	}
+
+	// prefix lines with their source when they come from several pods, containers or clusters
+	if l.AllPods || len(args) > 1 || len(l.Contexts) > 0 || l.Compare {
+		l.Prefix = true
+	}
+	if len(l.PrefixLabels) > 0 || len(l.PrefixAnnotations) > 0 {
+		if err := l.checkPrefixKeys(); err != nil {
+			return newError(ErrInvalidOptions, err)
+		}
+		l.Prefix = true
+	}
+
+	if err := l.checkContext(); err != nil {
+		return err
+	}
+	if err := l.resolveNamespace(); err != nil {
+		return err
+	}
+	if config, err := l.factory.ToRESTConfig(); err == nil {
+		l.logger.Debug("loaded client config", "server", config.Host, "namespace", l.Namespace)
+	}
+
+	// pods of a Deployment are also labelled with their ReplicaSet when a Deployment is resolved
+	l.byReplicaSet = l.GroupBy == groupByReplicaSet
+
+	// --merge-timestamps needs the timestamps of the server, the merger removes them unless they were asked for
+	if l.MergeTimestamps {
+		l.merger = newTimestampMerger(l.MergeWindow, mergeBufferSize, !l.Timestamps)
+		l.Timestamps = true
+	}
+
+	l.podPhases, err = parsePodPhases(l.PodStatus)
+	if err != nil {
+		return err
+	}
+	if l.ContainerRefRegexp != defaultContainerRefRegexp {
+		l.containerNameFromRefSpecRegexp, err = compileContainerRefRegexp(l.ContainerRefRegexp)
+		if err != nil {
+			return err
+		}
+	}
+	if len(l.ExcludeSelector) > 0 {
+		l.excludeSelector, err = labels.Parse(l.ExcludeSelector)
+		if err != nil {
+			return fmt.Errorf("invalid --exclude-selector: %w", err)
+		}
+	}
+	l.excludeAnnotations, err = parseAnnotationExclusions(l.ExcludeAnnotations)
+	if err != nil {
+		return err
+	}
+
+	if len(l.MinSeverity) > 0 {
+		l.minSeverity, err = parseSeverity(l.MinSeverity)
+		if err != nil {
+			return err
+		}
+	}
+	// the severity of the lines is detected the same way for --min-severity and --color-by=level
+	if len(l.MinSeverity) > 0 || l.ColorBy == colorByLevel {
+		// klog output has its own header, so prefer it unless a format was requested
+		if l.Klog && !cmd.Flag("severity-format").Changed {
+			l.SeverityFormat = "klog"
+		}
+		l.severityOf, err = newSeverityParser(l.SeverityFormat, l.Timestamps)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Compile the regular expression once, an empty or "*" pattern disables filtering
+	if l.Pattern != "" && (l.Pattern != matchAllPattern || l.FixedStrings) {
+		pattern := l.Pattern
+		if l.FixedStrings {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		// the regexp is still used to find the matches, e.g. to highlight them
+		if pattern == regexp.QuoteMeta(l.Pattern) && !l.IgnoreCase && !l.RawBytes {
+			l.literalPattern = []byte(l.Pattern)
+		}
+		if l.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		l.patternRegexp, err = regexp.Compile(pattern)
+		if err != nil {
+			return newError(ErrInvalidPattern, err)
+		}
+		l.logger.Debug("compiled pattern", "pattern", l.Pattern)
+	}
+	if len(l.MaxFileSize) > 0 {
+		l.maxFileSize, err = parseFileSize(l.MaxFileSize)
+		if err != nil {
+			return err
+		}
+	}
+	if l.TimestampsFormat == timestampsElapsed {
+		l.timestampOrigin = &timestampOrigin{}
+	}
+	if err := l.completeColor(); err != nil {
+		return err
+	}
+	if len(l.JQ) > 0 {
+		// compiled by Vaildate, shared by the copies of the options
+		l.jq = &jqProgram{}
+	}
+	if err := l.parseOutputTemplate(); err != nil {
+		return err
+	}
+	if err := l.completeColumns(); err != nil {
+		return err
+	}
+	if len(l.Webhook) > 0 {
+		l.webhook, err = l.newWebhookNotifier()
+		if err != nil {
+			return err
+		}
+		l.notifyBatch, err = parseNotifyBatch(l.WebhookBatch)
+		if err != nil {
+			return err
+		}
+	}
+	if len(l.LokiURL) > 0 {
+		l.loki, err = newLokiNotifier(l.LokiURL, l.LokiLabels)
+		if err != nil {
+			return err
+		}
+	}
+	if len(l.OTLPEndpoint) > 0 {
+		// the lines are exported without their timestamps
+		severity, err := newSeverityParser(l.SeverityFormat, false)
+		if err != nil {
+			return err
+		}
+		l.otlp, err = newOTLPExporter(l.OTLPEndpoint, severity)
+		if err != nil {
+			return err
+		}
+	}
+	if ring, err := alertEnabled(l.Bell, "--bell", l.ErrOut); err != nil {
+		return err
+	} else if ring {
+		l.bell = newBell(l.ErrOut, bellInterval)
+	}
+	l.writeHeartbeat, err = alertEnabled(l.HeartbeatMode, "--heartbeat-mode", l.ErrOut)
+	if err != nil {
+		return err
+	}
+	if notify, err := alertEnabled(l.Notify, "--notify", l.Out); err != nil {
+		return err
+	} else if notify {
+		l.desktopNotifier, err = newDesktopNotifier(l.NotifyCommand, l.NotifyEvery, l.ErrOut)
+		if err != nil {
+			return err
+		}
+	}
+	if len(l.MatchColumns) > 0 {
+		l.matchColumns, err = parseColumnRange(l.MatchColumns)
+		if err != nil {
+			return err
+		}
+	}
+	l.excludeRegexps, err = compileRegexps(l.Exclude, "--exclude")
+	if err != nil {
+		return err
+	}
+	l.excludeContainerRegexps, err = compileRegexps(l.ExcludeContainers, "--exclude-container")
+	if err != nil {
+		return err
+	}
+	if len(l.ContainerRegexp) > 0 {
+		l.containerRegexp, err = regexp.Compile(l.ContainerRegexp)
+		if err != nil {
+			return newError(ErrInvalidPattern, fmt.Errorf("invalid --container-regexp: %w", err))
+		}
+	}
+
+	l.GetPodTimeout, err = cmdutil.GetPodRunningTimeoutFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	// before kubectl logs parses --since-time, with an error telling the accepted forms
+	if err := l.completeSince(); err != nil {
+		return newError(ErrInvalidOptions, err)
+	}
+	logOptions, err := l.ToLogOptions()
+	if err != nil {
+		return err
+	}
+	// a field selector or a whole namespace can match as many pods as a label selector, so default the tail the same way
+	if len(args) == 0 && l.Tail == -1 && !l.TailSpecified {
+		logOptions.TailLines = &selectorTail
+	}
+	l.Options = logOptions
+
+	if err := l.useFactory(); err != nil {
+		return err
+	}
+	l.LogsForObject = polymorphichelpers.LogsForObjectFn
+	l.AllPodLogsForObject = polymorphichelpers.AllPodLogsForObjectFn
+
+	if l.MaxBytes > 0 {
+		l.budget = newOutputBudget(l.MaxBytes, l.ErrOut)
+	} else if l.LimitOutputBytes > 0 {
+		l.budget = newOutputBudget(l.LimitOutputBytes, l.ErrOut)
+		l.budget.flag = "--limit-output-bytes"
+	}
+	if l.For > 0 {
+		// started by run once the targets are resolved, it stops everything reading the context of the command
+		l.deadline = newRunDeadline(l.runContext(), l.For, l.ErrOut)
+		l.ctx = l.deadline.ctx
+	}
+	if l.NoMatchTimeout > 0 {
+		// started by run like the deadline, it fails the run once it stopped everything
+		l.noMatch = newNoMatchTimer(l.runContext(), l.NoMatchTimeout, l.NoMatchTimeoutMode)
+		l.ctx = l.noMatch.ctx
+	}
+	if l.ExitWithContainer {
+		l.exits = &containerExits{}
+	}
+	if len(l.ResumeFile) > 0 {
+		l.resume, err = loadResumeState(l.ResumeFile, l.ResumeMaxAge)
+		if err != nil {
+			return err
+		}
+	}
+	if l.IdleTimeout > 0 && l.Follow {
+		// started by run once the targets are resolved
+		l.idle = newIdleTimer(l.runContext(), l.IdleTimeout, l.ErrOut)
+	}
+	l.LogsOptions.ConsumeRequestFn = logs.DefaultConsumeRequest
+	// Set the consume request function if the pattern is not empty or the lines need parsing
 	// This is to ensure that the logs are filtered based on the pattern
-	if l.Pattern != "" {
+	// --no-filter always streams the logs as kubectl logs would
+	if l.NoFilter {
+		if (l.patternRegexp != nil || len(l.excludeRegexps) > 0 || l.minSeverity != severityUnknown) && !l.Quiet {
+			fmt.Fprintln(l.ErrOut, "note: --no-filter is set, --pattern, --exclude and --min-severity are ignored")
+		}
+	} else if l.patternRegexp != nil || l.Klog || l.minSeverity != severityUnknown || len(l.excludeRegexps) > 0 || l.Dedup || l.StripANSI || l.jq != nil || l.Timeout > 0 || l.idle != nil || l.SplitStreams || l.MaxLineLength > 0 || l.MultilineJSON || l.PrettyJSON || l.BeforeLines > 0 || l.Head > 0 {
 		l.LogsOptions.ConsumeRequestFn = l.DefaultConsumeRequest
 	}
+
+	if len(l.Contexts) > 0 {
+		l.contextOptions, err = l.resolveContexts()
+		return err
+	}
+	if l.Compare {
+		l.compareOptions, err = l.resolveCompareGroups()
+		return err
+	}
+
+	if l.Object == nil {
+		// the pods do not need to exist yet, e.g. right after kubectl apply
+		if l.Wait {
+			if err := l.waitForPods(); err != nil {
+				return err
+			}
+		}
+		l.objects, err = l.resolveObjects()
+		if err != nil {
+			return err
+		}
+		l.Object = l.objects[0]
+	} else {
+		l.objects = []runtime.Object{l.Object}
+	}
 	return nil
 }
 
-// Validate ensures that all required arguments and flag values are provided
+// Validate ensures that all required arguments and flag values are provided, its errors are of kind
+// ErrInvalidOptions
 func (l LikeOptions) Vaildate() error {
+	return newError(ErrInvalidOptions, l.validate())
+}
+
+func (l LikeOptions) validate() error {
+	if (l.InitContainers || l.EphemeralContainers) && !l.allContainers() {
+		return fmt.Errorf("--init-containers and --ephemeral-containers can only be used with --all-containers or --container-regexp")
+	}
+	if l.Compare && len(l.Contexts) > 0 {
+		return fmt.Errorf("--compare cannot be used with --contexts")
+	}
+	if l.OrderedBacklog && (len(l.Contexts) > 0 || l.Compare) {
+		return fmt.Errorf("--ordered-backlog cannot be used with --contexts or --compare")
+	}
+	if l.MergeTimestamps && (l.OrderedBacklog || l.OnlyMatching || len(l.Contexts) > 0 || l.Compare) {
+		return fmt.Errorf("--merge-timestamps cannot be used with --ordered-backlog, --only-matching, --contexts or --compare")
+	}
+	if l.MergeWindow <= 0 {
+		return fmt.Errorf("--merge-window must be greater than 0")
+	}
+	if l.MergeWindow != defaultMergeWindow && !l.MergeTimestamps {
+		return fmt.Errorf("--merge-window can only be used with --merge-timestamps")
+	}
+	if l.OnlyMatching && (l.patternRegexp == nil || l.NoFilter) {
+		return fmt.Errorf("--only-matching requires a --pattern")
+	}
+	// --no-filter streams the lines with the consume function of kubectl logs, which neither transforms them nor times out
+	if l.NoFilter && (l.IdleTimeout > 0 || l.Timeout > 0 || l.Dedup || l.StripANSI || len(l.JQ) > 0 || l.MaxLineLength > 0 || l.MultilineJSON || l.PrettyJSON || l.MatchTimeout > 0 || l.Head > 0) {
+		return fmt.Errorf("--no-filter cannot be used with --idle-timeout, --timeout, --dedup, --strip-ansi, --jq, --max-line-length, --multiline-json, --pretty-json, --match-timeout or --head")
+	}
+	if l.Head < 0 {
+		return fmt.Errorf("--head must be greater than or equal to 0")
+	}
+	if l.Head > 0 && l.OrderedBacklog {
+		return fmt.Errorf("--head cannot be used with --ordered-backlog, which reads the backlog of every container at once")
+	}
+	if l.Null && (l.Output == outputJSON || l.Output == outputCSV || l.Output == outputTSV) {
+		return fmt.Errorf("--null cannot be used with -o %s, whose records are already delimited", l.Output)
+	}
+	if l.MaxLineLength < 0 {
+		return fmt.Errorf("--max-line-length must be greater than or equal to 0")
+	}
+	if !slices.Contains(bannerFormats, l.BannerFormat) {
+		return fmt.Errorf("unknown --banner-format %q, must be one of %s", l.BannerFormat, strings.Join(bannerFormats, ", "))
+	}
+	if l.BeforeLines < 0 {
+		return fmt.Errorf("--before-lines must be greater than or equal to 0")
+	}
+	if l.BeforeLines > 0 && (l.NoFilter || l.OnlyMatching || len(l.JQ) > 0 || l.Output != outputText || l.SplitStreams || l.OrderedBacklog) {
+		return fmt.Errorf("--before-lines cannot be used with --no-filter, --only-matching, --jq, -o other than %s, --split-streams or --ordered-backlog", outputText)
+	}
+	// the lines before a match and the separators are written through the writers of the matching lines
+	if l.BeforeLines > 0 && (len(l.Exec) > 0 || len(l.Webhook) > 0 || len(l.LokiURL) > 0 || len(l.OTLPEndpoint) > 0 || l.Bell != alertNever || l.Notify != alertNever || l.Stats || len(l.MetricsAddr) > 0 || len(l.GroupBy) > 0 || l.Dedup || l.Heartbeat > 0) {
+		return fmt.Errorf("--before-lines cannot be used with --exec, --webhook, --loki-url, --otlp-endpoint, --bell, --notify, --stats, --metrics-addr, --group-by, --dedup or --heartbeat, which only take the matching lines")
+	}
+	if l.Retries < 0 {
+		return fmt.Errorf("--retries must be greater than or equal to 0")
+	}
+	if l.ReconnectMaxAttempts < 0 {
+		return fmt.Errorf("--reconnect-max-attempts must be greater than or equal to 0")
+	}
+	if l.ReconnectMaxBackoff <= 0 {
+		return fmt.Errorf("--reconnect-max-backoff must be greater than 0")
+	}
+	if (l.ReconnectMaxAttempts != defaultReconnectMaxAttempts || l.ReconnectMaxBackoff != defaultReconnectMaxBackoff) && (!l.Follow || l.NoReattach) {
+		return fmt.Errorf("--reconnect-max-attempts and --reconnect-max-backoff can only be used with --follow, without --no-reattach")
+	}
+	if l.MatchTimeout < 0 {
+		return fmt.Errorf("--match-timeout must be greater than or equal to 0")
+	}
+	if l.FlushInterval < 0 {
+		return fmt.Errorf("--flush-interval must be greater than or equal to 0")
+	}
+	if l.SplitStreams && (l.NoFilter || l.OrderedBacklog || l.merger != nil) {
+		return fmt.Errorf("--split-streams cannot be used with --no-filter, --ordered-backlog or --merge-timestamps")
+	}
+	if l.MultilineJSON && l.OrderedBacklog {
+		return fmt.Errorf("--multiline-json cannot be used with --ordered-backlog")
+	}
+	if len(l.JQ) > 0 && (l.OnlyMatching || l.Output != outputText) {
+		return fmt.Errorf("--jq cannot be used with --only-matching or -o other than %s", outputText)
+	}
+	if l.PrettyJSON && (l.OnlyMatching || l.MaxLineLength > 0 || l.Output != outputText) {
+		return fmt.Errorf("--pretty-json cannot be used with --only-matching, --max-line-length or -o other than %s", outputText)
+	}
+	if (l.JQRaw || l.NonJSON != nonJSONPass) && len(l.JQ) == 0 {
+		return fmt.Errorf("--jq-raw and --non-json can only be used with --jq")
+	}
+	if !slices.Contains(nonJSONValues, l.NonJSON) {
+		return fmt.Errorf("unknown --non-json %q, must be one of %s", l.NonJSON, strings.Join(nonJSONValues, ", "))
+	}
+	if l.jq != nil {
+		code, err := compileJQ(l.JQ)
+		if err != nil {
+			return err
+		}
+		l.jq.code = code
+	}
+	if l.RawBytes && (l.MatchRunes || l.StripANSI || len(l.JQ) > 0 || l.MultilineJSON || l.Output != outputText || len(l.MatchColumns) > 0 || len(l.MinSeverity) > 0) {
+		return fmt.Errorf("--raw-bytes cannot be used with --match-runes, --strip-ansi, --jq, --multiline-json, -o other than text, --match-columns or --min-severity")
+	}
+	if l.OnlyMatching && l.Output != outputText {
+		return fmt.Errorf("--only-matching can only be used with -o %s", outputText)
+	}
+	if l.MatchRunes && len(l.MatchColumns) == 0 {
+		return fmt.Errorf("--match-runes can only be used with --match-columns")
+	}
+	if l.WaitTimeout <= 0 {
+		return fmt.Errorf("--wait-timeout must be greater than 0")
+	}
+	if l.WaitTimeout != defaultWaitTimeout && !l.Wait {
+		return fmt.Errorf("--wait-timeout can only be used with --wait")
+	}
+	if l.Wait && (len(l.Contexts) > 0 || l.Compare) {
+		return fmt.Errorf("--wait cannot be used with --contexts or --compare")
+	}
+	if l.For < 0 {
+		return fmt.Errorf("--for must be greater than or equal to 0")
+	}
+	if l.PerSourceBuffer < 1 {
+		return fmt.Errorf("--per-source-buffer must be greater than 0")
+	}
+	if len(l.TimestampFormat) > 0 && (!l.Timestamps || l.merger != nil && l.merger.hide) {
+		return fmt.Errorf("--timestamp-format can only be used with --timestamps")
+	}
+	if len(l.TimestampFormat) > 0 && l.TimestampsFormat != timestampsRaw {
+		return fmt.Errorf("--timestamp-format cannot be used with --timestamps=%s", l.TimestampsFormat)
+	}
+	if l.Heartbeat < 0 {
+		return fmt.Errorf("--heartbeat must be greater than or equal to 0")
+	}
+	if l.Heartbeat > 0 && !l.Follow {
+		return fmt.Errorf("--heartbeat can only be used with --follow")
+	}
+	if l.HeartbeatMode != alertAuto && l.Heartbeat == 0 {
+		return fmt.Errorf("--heartbeat-mode can only be used with --heartbeat")
+	}
+	if l.ExecThrottle < 0 {
+		return fmt.Errorf("--exec-throttle must be greater than or equal to 0")
+	}
+	if l.ExecThrottle > 0 && len(l.Exec) == 0 {
+		return fmt.Errorf("--exec-throttle can only be used with --exec")
+	}
+	if (l.WebhookBatch != defaultWebhookBatch || len(l.WebhookTemplate) > 0) && len(l.Webhook) == 0 {
+		return fmt.Errorf("--webhook-batch and --webhook-template can only be used with --webhook")
+	}
+	if len(l.LokiLabels) > 0 && len(l.LokiURL) == 0 {
+		return fmt.Errorf("--loki-label can only be used with --loki-url")
+	}
+	if l.NotifyEvery < 0 {
+		return fmt.Errorf("--notify-every must be greater than or equal to 0")
+	}
+	if l.NotifyEvery > 0 && l.Notify == alertNever {
+		return fmt.Errorf("--notify-every can only be used with --notify")
+	}
+	if l.NoMatchTimeout < 0 {
+		return fmt.Errorf("--no-match-timeout must be greater than or equal to 0")
+	}
+	if l.NoMatchTimeout > 0 && !l.Follow {
+		return fmt.Errorf("--no-match-timeout can only be used with --follow")
+	}
+	if !slices.Contains(noMatchModes, l.NoMatchTimeoutMode) {
+		return fmt.Errorf("unknown --no-match-timeout-mode %q, must be one of %s", l.NoMatchTimeoutMode, strings.Join(noMatchModes, ", "))
+	}
+	if l.NoMatchTimeoutMode != noMatchReset && l.NoMatchTimeout == 0 {
+		return fmt.Errorf("--no-match-timeout-mode can only be used with --no-match-timeout")
+	}
+	if l.ExitWithContainer && !l.Follow {
+		return fmt.Errorf("--exit-with-container can only be used with --follow")
+	}
+	if len(l.ResumeFile) > 0 && (l.Previous || l.PreviousAndCurrent || l.SinceLastRestart || l.OrderedBacklog) {
+		return fmt.Errorf("--resume-file cannot be used with --previous, --previous-and-current, --since-last-restart or --ordered-backlog")
+	}
+	if l.ResumeMaxAge < 0 {
+		return fmt.Errorf("--resume-max-age must be greater than or equal to 0")
+	}
+	if l.ResumeMaxAge != defaultResumeMaxAge && len(l.ResumeFile) == 0 {
+		return fmt.Errorf("--resume-max-age can only be used with --resume-file")
+	}
+	if l.IdleTimeout < 0 {
+		return fmt.Errorf("--idle-timeout must be greater than or equal to 0")
+	}
+	if l.IdleTimeout > 0 && !l.Follow {
+		return fmt.Errorf("--idle-timeout can only be used with --follow")
+	}
+	if l.Timeout < 0 {
+		return fmt.Errorf("--timeout must be greater than or equal to 0")
+	}
+	if (len(l.MaxFileSize) > 0 || l.NoStdout) && len(l.OutputFile) == 0 {
+		return fmt.Errorf("--max-file-size and --no-stdout require --output-file")
+	}
+	if l.MaxFiles < 0 {
+		return fmt.Errorf("--max-files must be greater than or equal to 0")
+	}
+	if len(l.OutputFile) > 0 && len(l.OutputDir) > 0 {
+		return fmt.Errorf("--output-file cannot be used with --output-dir")
+	}
+	if len(l.Tee) > 0 && (len(l.OutputFile) > 0 || len(l.OutputDir) > 0) {
+		return fmt.Errorf("--tee cannot be used with --output-file or --output-dir")
+	}
+	if l.MaxBytes < 0 {
+		return fmt.Errorf("--max-bytes must be greater than or equal to 0")
+	}
+	if l.LimitOutputBytes < 0 {
+		return fmt.Errorf("--limit-output-bytes must be greater than or equal to 0")
+	}
+	if l.MaxBytes > 0 && l.LimitOutputBytes > 0 {
+		return fmt.Errorf("only one of --max-bytes or --limit-output-bytes may be specified")
+	}
+	if len(l.GroupBy) > 0 && l.GroupBy != groupByReplicaSet && !slices.Contains(l.captureGroupNames(), l.GroupBy) {
+		return fmt.Errorf("unknown --group-by %q, must be one of %s or a capture group of the pattern", l.GroupBy, strings.Join(groupByValues, ", "))
+	}
+	if l.groupsLines() && (l.Follow || len(l.Contexts) > 0 || l.Compare) {
+		return fmt.Errorf("--group-by with a capture group cannot be used with --follow, --contexts or --compare, the lines are printed once they are all read")
+	}
+	if l.DedupWindow < 1 {
+		return fmt.Errorf("--dedup-window must be greater than 0")
+	}
+	if l.DedupWindow != defaultDedupWindow && !l.Dedup {
+		return fmt.Errorf("--dedup-window can only be used with --dedup")
+	}
+	if l.MaxGroups < 1 {
+		return fmt.Errorf("--max-groups must be greater than 0")
+	}
+	if !slices.Contains(colorByValues, l.ColorBy) {
+		return fmt.Errorf("unknown --color-by %q, must be one of %s", l.ColorBy, strings.Join(colorByValues, ", "))
+	}
+	if !slices.Contains(outputFormats, l.Output) {
+		return fmt.Errorf("unknown output format %q, must be one of %s", l.Output, strings.Join(outputFormats, ", "))
+	}
+	for _, re := range l.excludeContainerRegexps {
+		if len(l.Container) > 0 && re.MatchString(l.Container) {
+			return fmt.Errorf("container %s is excluded by --exclude-container=%s", l.Container, re)
+		}
+	}
+	if len(l.Contexts) > 0 && len(*l.KubernetesConfigFlags.Context) > 0 {
+		return fmt.Errorf("only one of --context or --contexts may be specified")
+	}
+	if l.SinceLastRestart && (len(l.SinceTime) > 0 || l.SinceSeconds != 0 || l.Previous || l.PreviousAndCurrent) {
+		return fmt.Errorf("--since-last-restart cannot be used with --since, --since-time, --previous or --previous-and-current")
+	}
+	if l.PreviousAndCurrent && l.Previous {
+		return fmt.Errorf("only one of --previous or --previous-and-current (--include-previous) may be specified")
+	}
 	return l.LogsOptions.Validate()
 }
 
 // Run executes the LikeOptions
 func (l LikeOptions) Run() error {
-	return l.LogsOptions.RunLogs()
+	err := l.run()
+	l.deadline.Stop()
+	l.noMatch.Stop()
+	if noMatchErr := l.noMatch.Err(); noMatchErr != nil && errors.Is(err, ErrInterrupted) {
+		return noMatchErr
+	}
+	if errors.Is(err, ErrBrokenPipe) && l.PipefailExit {
+		return ErrBrokenPipe
+	}
+	// the streams are stopped on purpose once --max-bytes, --idle-timeout or --for is reached, or once the reader
+	// of the output is gone
+	if errors.Is(err, errMaxBytesReached) || errors.Is(err, ErrBrokenPipe) || errors.Is(err, errIdleTimeout) || errors.Is(err, ErrInterrupted) && l.deadline.Expired() {
+		return nil
+	}
+	if err == nil {
+		// with --exit-with-container, the code of a container that failed
+		return l.exits.Err()
+	}
+	// an interrupted command returns ErrInterrupted once its output is flushed, to exit with code 130
+	return err
+}
+
+func (l LikeOptions) run() (err error) {
+	// only a broken stdout stops the streams, the errors of the files are still reported
+	l.Out = &brokenPipeWriter{writer: l.Out}
+	if len(l.OutputDir) > 0 && !l.DryRun {
+		outputs, err := newOutputDir(l.OutputDir, l.Out)
+		if err != nil {
+			return err
+		}
+		defer outputs.Close()
+		l.onInterrupt(func() { outputs.Close() })
+		l.outputs = outputs
+	}
+	if len(l.OutputFile) > 0 && !l.DryRun {
+		file, err := newRotatingFile(l.OutputFile, l.maxFileSize, l.MaxFiles)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		l.onInterrupt(func() { file.Close() })
+		if l.Follow {
+			// with --flush-interval 0 the lines are not buffered, and the file is synced as often as the other outputs
+			file.syncEvery(cmp.Or(l.FlushInterval, outputFlushInterval))
+		}
+		l.Out = io.MultiWriter(l.Out, file)
+		if l.NoStdout {
+			l.Out = file
+		}
+	}
+	if len(l.Tee) > 0 && !l.DryRun {
+		file, err := newTeeFile(l.Tee)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		l.onInterrupt(func() { file.Close() })
+		l.Out = io.MultiWriter(l.Out, file)
+	}
+	if l.FlushInterval > 0 && !l.DryRun {
+		buffered := newBufferedOutput(l.Out, l.FlushInterval)
+		defer func() {
+			if closeErr := buffered.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		// registered before the summaries printed on interrupt, so that they are written after the buffered lines
+		l.onInterrupt(func() { buffered.Close() })
+		l.Out = buffered
+	}
+	if l.Null && !l.DryRun {
+		l.Out = &nullWriter{writer: l.Out}
+	}
+	// --max-bytes counts what is finally written, prefixes, colors and timestamps included
+	if l.budget != nil && !l.DryRun {
+		l.Out = l.budget.writer(l.Out)
+	}
+	if len(l.CaptureRaw) > 0 && !l.DryRun {
+		capture, err := newRawCapture(l.CaptureRaw)
+		if err != nil {
+			return err
+		}
+		defer capture.Close()
+		l.onInterrupt(func() { capture.Close() })
+		l.capture = capture
+	}
+	if l.Heartbeat > 0 && l.writeHeartbeat && !l.DryRun {
+		heartbeat := newHeartbeat(l.ErrOut, l.Heartbeat, l.colorizeErr)
+		defer heartbeat.Stop()
+		l.heartbeat = heartbeat
+	}
+	if len(l.Exec) > 0 && !l.DryRun {
+		lineExec := newLineExec(l.Exec, l.ExecThrottle, l.ErrOut)
+		defer lineExec.Close()
+		l.lineExec = lineExec
+	}
+	if !l.DryRun {
+		stopCounters, err := l.startCounters()
+		if err != nil {
+			return err
+		}
+		defer stopCounters()
+	}
+	if l.desktopNotifier != nil {
+		defer l.desktopNotifier.Close()
+	}
+	if l.webhook != nil && !l.DryRun {
+		sink := newNotifySink(l.webhook, "--webhook", l.notifyBatch, webhookBackoff, l.ErrOut, l.stats)
+		defer sink.Close()
+		l.onInterrupt(sink.Close)
+		l.notifySinks = append(l.notifySinks, sink)
+	}
+	if l.loki != nil && !l.DryRun {
+		sink := newNotifySink(l.loki, "--loki-url", lokiBatch, webhookBackoff, l.ErrOut, l.stats)
+		defer sink.Close()
+		l.onInterrupt(sink.Close)
+		l.notifySinks = append(l.notifySinks, sink)
+	}
+	if l.otlp != nil && !l.DryRun {
+		sink := newNotifySink(l.otlp, "--otlp-endpoint", l.otlp.batch, webhookBackoff, l.ErrOut, l.stats)
+		defer sink.Close()
+		l.onInterrupt(sink.Close)
+		l.notifySinks = append(l.notifySinks, sink)
+	}
+	if l.GroupBy == groupByReplicaSet && !l.DryRun {
+		counts := newMatchCounts("ReplicaSet", l.ErrOut)
+		defer counts.Print()
+		l.onInterrupt(counts.Print)
+		l.matchCounts = counts
+	}
+	if l.groupsLines() && !l.DryRun {
+		groups := newLineGroups(l.GroupBy, l.MaxGroups, l.Out, l.ErrOut)
+		l.onInterrupt(func() { groups.Print() })
+		defer func() {
+			// the groups are also printed when interrupted
+			if err == nil || errors.Is(err, ErrInterrupted) {
+				if printErr := groups.Print(); err == nil {
+					err = printErr
+				}
+			}
+		}()
+		l.lineGroups = groups
+	}
+	if l.merger != nil {
+		defer l.merger.Close()
+	}
+	if l.resume != nil && !l.DryRun {
+		// also saved when the run fails, the marks of the lines read are still right
+		defer func() {
+			if saveErr := l.resume.save(); saveErr != nil && err == nil {
+				err = fmt.Errorf("saving --resume-file: %w", saveErr)
+			}
+		}()
+		l.onInterrupt(func() { l.resume.save() })
+	}
+	if l.patternRegexp != nil && !l.NoFilter && !l.Follow && !l.Quiet && !l.DryRun {
+		tally := &matchTally{}
+		defer func() {
+			// the logs were read to the end, i.e. not stopped by --max-bytes or an interrupt
+			if err == nil {
+				tally.report(l.ErrOut, l.Pattern)
+			}
+		}()
+		l.tally = tally
+	}
+
+	if len(l.contextOptions) > 0 {
+		return l.runContexts()
+	}
+	if len(l.compareOptions) > 0 {
+		return l.runCompare()
+	}
+
+	requests, err := l.logRequests()
+	if err != nil {
+		return err
+	}
+	if l.DryRun {
+		return l.printDryRun(l.logTargets(requests))
+	}
+	if err := l.printBanner(l.logTargets(requests)); err != nil {
+		return err
+	}
+	if l.Verbose {
+		fmt.Fprintf(l.ErrOut, "Namespace: %s\n", l.namespaceDescription())
+		l.printTargets(l.logTargets(requests))
+	}
+	if err := l.printRecordHeader(); err != nil {
+		return err
+	}
+	l.idle.start()
+	l.deadline.start()
+	l.noMatch.start()
+
+	if l.InitContainers {
+		terminated, err := l.terminatedInitContainerRequests(requests)
+		if err != nil {
+			return err
+		}
+		if err := l.sequentialConsumeRequest(terminated); err != nil {
+			return err
+		}
+	}
+
+	if l.OrderedBacklog {
+		requests, err = l.printOrderedBacklog(requests)
+		if err != nil {
+			return err
+		}
+		if !l.Follow {
+			return nil
+		}
+	}
+
+	// pods selected without naming them can come and go while following
+	followSelected := l.Follow && len(l.ResourceArgs) == 0
+	if followSelected || l.Follow && len(requests) > 1 {
+		if err := l.checkFollowConcurrency(len(requests)); err != nil {
+			return err
+		}
+
+		if followSelected {
+			return l.parallelConsumeRequestAndWatch(requests)
+		}
+		return l.parallelConsumeRequest(requests)
+	}
+	// the lines of the containers are ordered while they are read at the same time
+	if l.merger != nil && len(requests) > 1 {
+		return l.parallelConsumeRequest(requests)
+	}
+
+	return l.sequentialConsumeRequest(requests)
 }
 
 // DefaultConsumeRequest consumes the logs from the request and writes to the output
 func (l LikeOptions) DefaultConsumeRequest(request rest.ResponseWrapper, out io.Writer) error {
-	readCloser, err := request.Stream(context.TODO())
+	ctx := l.runContext()
+	if l.idle != nil {
+		ctx = l.idle.ctx
+	}
+	// a hung stream must not block forever when the logs are read once
+	if l.Timeout > 0 && !l.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+	readCloser, err := request.Stream(ctx)
 	if err != nil {
-		return err
+		return l.readError(ctx, err)
 	}
 	defer readCloser.Close()
-	// Compile the regular expression
-	re, err := regexp.Compile(l.Pattern)
-	if err != nil {
-		return err
+
+	if l.Dedup {
+		deduper := &lineDeduper{out: out, notices: l.noticeWriter(), key: l.dedupKey, window: l.DedupWindow}
+		defer deduper.Flush()
+		out = deduper
 	}
 
+	var before *beforeLines
+	if l.BeforeLines > 0 {
+		// kept for the whole stream, so that the lines before a match are printed also when following
+		before = &beforeLines{max: l.BeforeLines}
+	}
+	var assembler *jsonAssembler
+	if l.MultilineJSON {
+		assembler = &jsonAssembler{timestamps: l.Timestamps}
+	}
+	watchdog := l.startMatchWatchdog()
+	defer watchdog.Close()
+	lines := 0
 	r := bufio.NewReader(readCloser)
 	for {
 		bytes, err := r.ReadBytes('\n')
-		if re.Match(bytes) {
-			if _, err := out.Write(bytes); err != nil {
+		if len(bytes) > 0 && l.idle != nil {
+			l.idle.touch()
+		}
+		if len(bytes) > 0 {
+			lines++
+		}
+		if err == nil && l.headReached(lines) {
+			err = errHeadReached
+		}
+		if l.StripANSI {
+			bytes = ansiRegexp.ReplaceAll(bytes, nil)
+		}
+		records := [][]byte{bytes}
+		if assembler != nil {
+			records = assembler.add(bytes)
+			if err != nil {
+				records = append(records, assembler.Flush())
+			}
+		}
+		for _, record := range records {
+			if err := l.writeRecord(out, record, before, watchdog); err != nil {
 				return err
 			}
 		}
 		if err != nil {
-			if err != io.EOF {
+			if err == errHeadReached {
 				return err
 			}
+			if err != io.EOF {
+				return l.readError(ctx, err)
+			}
 			return nil
 		}
 	}
 }
 
+// writeRecord writes a line, or a JSON object of --multiline-json, to out when it matches, after the lines
+// of --before-lines kept by before, or to the writer of --split-streams when it does not
+func (l LikeOptions) writeRecord(out io.Writer, record []byte, before *beforeLines, watchdog *matchWatchdog) error {
+	if len(record) == 0 {
+		return nil
+	}
+	matched, skipped := l.matchLineWithin(watchdog, record)
+	l.tally.add(matched)
+	if skipped {
+		if before != nil {
+			before.gap = true
+		}
+		return nil
+	}
+	if matched {
+		if before != nil {
+			if err := before.flush(out, l.truncate); err != nil {
+				return err
+			}
+		}
+		for _, line := range l.outputLines(record) {
+			if _, err := out.Write(l.truncate(line)); err != nil {
+				return err
+			}
+		}
+	} else if before != nil {
+		before.add(record)
+	} else if l.unmatched != nil {
+		if _, err := l.unmatched.Write(l.truncate(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readError replaces the error of a read that was aborted by --timeout, --idle-timeout or an interrupt with a
+// clearer one
+func (l LikeOptions) readError(ctx context.Context, err error) error {
+	if l.runContext().Err() != nil {
+		return ErrInterrupted
+	}
+	if l.idle != nil && l.idle.Expired() {
+		return errIdleTimeout
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("log read timed out after %s", l.Timeout)
+	}
+	return err
+}
+
+// startMatchWatchdog returns the watchdog matching the lines of a stream with --match-timeout, or nil without it
+func (l LikeOptions) startMatchWatchdog() *matchWatchdog {
+	if l.MatchTimeout <= 0 {
+		return nil
+	}
+	return newMatchWatchdog(l.MatchTimeout, l.matchLine, l.ErrOut)
+}
+
+// matchLineWithin reports whether the line matches like matchLine, or that it was skipped by the watchdog of
+// --match-timeout, if any
+func (l LikeOptions) matchLineWithin(watchdog *matchWatchdog, line []byte) (matched, skipped bool) {
+	if watchdog == nil {
+		return l.matchLine(line), false
+	}
+	return watchdog.match(line)
+}
+
+// matchLine reports whether the line passes the severity threshold and matches the pattern
+func (l LikeOptions) matchLine(line []byte) bool {
+	for _, re := range l.excludeRegexps {
+		if l.regexpMatch(re, line) {
+			return false
+		}
+	}
+	if l.minSeverity != severityUnknown && l.severityOf(line) < l.minSeverity {
+		return false
+	}
+	if l.Klog {
+		// lines without a klog header, e.g. stack traces, are matched as a whole
+		if entry, ok := parseKlogLine(line, l.Timestamps); ok {
+			return l.matchPattern(entry.message)
+		}
+	}
+	return l.matchPattern(line)
+}
+
+// truncate cuts the line after --max-line-length characters, or bytes with --raw-bytes
+func (l LikeOptions) truncate(line []byte) []byte {
+	return truncateLine(line, l.MaxLineLength, !l.RawBytes)
+}
+
+// truncateLine cuts the line after max characters, or bytes unless runes is true, ending it with an ellipsis
+// and its line break. A max of 0 keeps the line whole.
+func truncateLine(line []byte, max int, runes bool) []byte {
+	if max <= 0 {
+		return line
+	}
+	end := min(max, len(line))
+	if runes {
+		end = 0
+		for i := 0; i < max && end < len(line); i++ {
+			_, size := utf8.DecodeRune(line[end:])
+			end += size
+		}
+	}
+	eol := len(line)
+	for eol > end && (line[eol-1] == '\n' || line[eol-1] == '\r') {
+		eol--
+	}
+	if eol <= end {
+		return line
+	}
+	truncated := make([]byte, 0, end+len(ellipsis)+len(line)-eol)
+	truncated = append(truncated, line[:end]...)
+	truncated = append(truncated, ellipsis...)
+	return append(truncated, line[eol:]...)
+}
+
+// outputLines returns the lines to write for a matching line: the line itself, every match
+// of the pattern on its own line with --only-matching, or the results of --jq, indented with --pretty-json
+func (l LikeOptions) outputLines(line []byte) [][]byte {
+	if l.jq != nil {
+		return l.prettyJSONLines(l.jqLines(line))
+	}
+	if !l.OnlyMatching {
+		return l.prettyJSONLines([][]byte{line})
+	}
+	matches := l.matchRanges(line)
+	lines := make([][]byte, 0, len(matches))
+	for _, match := range matches {
+		lines = append(lines, []byte(match.Text+"\n"))
+	}
+	return lines
+}
+
+// compileRegexps compiles the regexes given to flag
+func compileRegexps(exprs []string, flag string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, newError(ErrInvalidPattern, fmt.Errorf("invalid %s regex %q: %w", flag, expr, err))
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// matchPattern reports whether b, or its --match-columns, matches the pattern.
+// Every line matches when no pattern is given.
+func (l LikeOptions) matchPattern(b []byte) bool {
+	if l.patternRegexp == nil {
+		return true
+	}
+	if l.matchColumns != nil {
+		b = l.matchColumns.columns(b, l.MatchRunes)
+	}
+	if l.literalPattern != nil {
+		return bytes.Contains(b, l.literalPattern)
+	}
+	return l.regexpMatch(l.patternRegexp, b)
+}
+
 // RegisterCompletionFunc registers the completion functions for the LikeOptions
 func (l *LikeOptions) RegisterCompletionFunc(cmd *cobra.Command) {
 	utilcomp.SetFactoryForCompletion(l.factory)
-	cmd.ValidArgsFunction = completion.PodResourceNameAndContainerCompletionFunc(l.factory)
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// every argument is a POD or TYPE/NAME, so complete each one like the first
+		return l.completeResourceArg(toComplete)
+	}
 	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
 		"namespace",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return utilcomp.CompGetResource(l.factory, "namespace", toComplete), cobra.ShellCompDirectiveNoFileComp
 		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"node",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return utilcomp.CompGetResource(l.factory, "node", toComplete), cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"pattern",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return loadPatterns(), cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"min-severity",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"trace", "debug", "info", "warning", "error", "fatal"}, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"severity-format",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return severityFormats, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"pod-status",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return strings.Split(joinPodPhases(), ", "), cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"timestamp-format",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return timestampLayouts, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"non-json",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nonJSONValues, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"color",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return colorModes, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"color-by",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return colorByValues, cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"group-by",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// the capture groups of the pattern given so far
+			var l LikeOptions
+			if pattern, err := regexp.Compile(cmd.Flag("pattern").Value.String()); err == nil {
+				l.patternRegexp = pattern
+			}
+			return append(slices.Clone(groupByValues), l.captureGroupNames()...), cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"preset",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			presets, err := loadPresets()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			return presetNames(presets), cobra.ShellCompDirectiveNoFileComp
+		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"output",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return outputFormats, cobra.ShellCompDirectiveNoFileComp
+		}))
 	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
 		"context",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return utilcomp.ListContextsInConfig(toComplete), cobra.ShellCompDirectiveNoFileComp
 		}))
+	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
+		"contexts",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return utilcomp.ListContextsInConfig(toComplete), cobra.ShellCompDirectiveNoFileComp
+		}))
 	cmdutil.CheckErr(cmd.RegisterFlagCompletionFunc(
 		"cluster",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -0,0 +1,403 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestCompleteNodeSelectsPodsByNode(t *testing.T) {
+	pods := &corev1.PodList{Items: []corev1.Pod{testPod("api-1", corev1.PodRunning, nil)}}
+	api := &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods": pods}}
+	l, cmd, _, _ := newFakeCommand(t, api)
+
+	if err := completeFlags(l, cmd, []string{"--node", "worker-3", "-l", "app=api", "--field-selector", "status.phase=Running"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.FieldSelector, "status.phase=Running,spec.nodeName=worker-3"; got != want {
+		t.Errorf("got field selector %q, want %q", got, want)
+	}
+	queries := strings.Join(api.requests("/namespaces/test/pods"), "&")
+	if !strings.Contains(queries, "spec.nodeName%3Dworker-3") || !strings.Contains(queries, "labelSelector=app%3Dapi") {
+		t.Errorf("the pods were not listed by node and label: %q", queries)
+	}
+}
+
+func TestCompleteRejectsInvalidFieldSelector(t *testing.T) {
+	api := &fakeAPI{}
+	l, cmd, _, _ := newFakeCommand(t, api)
+	err := completeFlags(l, cmd, []string{"--field-selector", "status.phase"})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid --field-selector") {
+		t.Errorf("got %v, want the field selector rejected", err)
+	}
+	if queries := api.requests("/namespaces/test/pods"); len(queries) > 0 {
+		t.Errorf("the pods were listed with an invalid field selector: %q", queries)
+	}
+}
+
+func TestNodeCompletionListsNodes(t *testing.T) {
+	nodes := &corev1.NodeList{Items: []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "control-plane"}},
+	}}
+	api := &fakeAPI{objects: map[string]runtime.Object{"/nodes": nodes}}
+	l, cmd, _, _ := newFakeCommand(t, api)
+	// completion gets the resources like kubectl get, as unstructured objects
+	l.factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+		Client:               fake.CreateHTTPClient(api.roundTrip),
+	}
+	l.RegisterCompletionFunc(cmd)
+
+	complete, ok := cmd.GetFlagCompletionFunc("node")
+	if !ok {
+		t.Fatal("--node has no completion")
+	}
+	names, directive := complete(cmd, nil, "worker")
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("got directive %v, want no file completion", directive)
+	}
+	slices.Sort(names)
+	if got, want := strings.Join(names, ","), "worker-1,worker-2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompleteRejectsPodsWithSelector(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, &fakeAPI{})
+	if err := completeFlags(l, cmd, []string{"-l", "app=api"}, "pod-a"); err == nil || !strings.Contains(err.Error(), "only a selector") {
+		t.Errorf("expected POD names with -l to be rejected, got %v", err)
+	}
+}
+
+func newPodAPI(name string) *fakeAPI {
+	pod := testPod(name, corev1.PodRunning, nil)
+	return &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/" + name: &pod}}
+}
+
+func TestMatchAllPatternDoesNotFilter(t *testing.T) {
+	for _, pattern := range []string{"", "*"} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, []string{"--pattern", pattern}, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if l.patternRegexp != nil {
+			t.Errorf("--pattern %q compiled to %s, want no filtering", pattern, l.patternRegexp)
+		}
+		for _, line := range []string{"ERROR boom\n", "\n", "a line with * in it\n"} {
+			if !l.matchLine([]byte(line)) {
+				t.Errorf("--pattern %q filtered out %q", pattern, line)
+			}
+		}
+	}
+}
+
+func TestEscapedStarMatchesLiteralStar(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--pattern", `\*`}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !l.matchLine([]byte("a line with * in it\n")) {
+		t.Error(`--pattern \* does not match a literal *`)
+	}
+	if l.matchLine([]byte("a plain line\n")) {
+		t.Error(`--pattern \* matches a line without *`)
+	}
+}
+
+func TestLiteralPatternFastPath(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		literal bool
+		matches []string
+		misses  []string
+	}{
+		{flags: []string{"--pattern", "connection refused"}, literal: true, matches: []string{"dial: connection refused\n"}, misses: []string{"connection reset\n"}},
+		{flags: []string{"--pattern", "err(or)?"}, matches: []string{"an err\n", "an error\n"}, misses: []string{"err(or)?\n"}},
+		{flags: []string{"--pattern", "ERROR", "-i"}, matches: []string{"error\n"}},
+		{flags: []string{"--pattern", "a.b*", "--fixed-strings"}, literal: true, matches: []string{"x a.b* y\n"}, misses: []string{"aXbb\n"}},
+		{flags: []string{"--pattern", "*", "--fixed-strings"}, literal: true, matches: []string{"a * b\n"}, misses: []string{"a b\n"}},
+	}
+	for _, test := range tests {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, test.flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if got := l.literalPattern != nil; got != test.literal {
+			t.Errorf("%v: got literal %t, want %t", test.flags, got, test.literal)
+		}
+		for _, line := range test.matches {
+			if !l.matchLine([]byte(line)) {
+				t.Errorf("%v: %q does not match", test.flags, line)
+			}
+		}
+		for _, line := range test.misses {
+			if l.matchLine([]byte(line)) {
+				t.Errorf("%v: %q matches", test.flags, line)
+			}
+		}
+	}
+}
+
+// BenchmarkMatchLine matches the lines of a synthetic log with a literal pattern, with the fast path and with
+// the regexp it replaces
+func BenchmarkMatchLine(b *testing.B) {
+	var lines [][]byte
+	size := 0
+	for i := 0; i < 10000; i++ {
+		line := fmt.Appendf(nil, "2024-06-12T10:04:05.%09dZ INFO request_id=%d method=GET path=/api/v1/items/%d status=200 duration=12ms\n", i, i, i)
+		if i%100 == 0 {
+			line = fmt.Appendf(nil, "2024-06-12T10:04:05.%09dZ ERROR request_id=%d connection refused\n", i, i)
+		}
+		lines = append(lines, line)
+		size += len(line)
+	}
+	l := NewLikeOptions(genericiooptions.IOStreams{})
+	l.patternRegexp = regexp.MustCompile("connection refused")
+	for _, bench := range []struct {
+		name    string
+		literal []byte
+	}{
+		{name: "literal", literal: []byte("connection refused")},
+		{name: "regexp"},
+	} {
+		l.literalPattern = bench.literal
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				for _, line := range lines {
+					l.matchLine(line)
+				}
+			}
+		})
+	}
+}
+
+func TestNoFilterPrintsEveryLine(t *testing.T) {
+	api := newPodAPI("api-1")
+	api.logs = map[string]string{"/namespaces/test/pods/api-1/log": "INFO starting\nERROR boom\n"}
+	l, cmd, out, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--no-filter", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "INFO starting\nERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.Contains(errOut.String(), "--pattern, --exclude and --min-severity are ignored") {
+		t.Errorf("expected a note about the ignored pattern, got %q", errOut.String())
+	}
+}
+
+func TestNoFilterRejectsLineOptions(t *testing.T) {
+	for _, flags := range [][]string{
+		{"-f", "--idle-timeout", "1m"},
+		{"--timeout", "1m"},
+		{"--dedup"},
+		{"--strip-ansi"},
+		{"--jq", ".msg"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, append(flags, "--no-filter"), "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil || !strings.Contains(err.Error(), "--no-filter cannot be used") {
+			t.Errorf("expected %v to be rejected with --no-filter, got %v", flags, err)
+		}
+	}
+}
+
+func TestSplitStreams(t *testing.T) {
+	logs := map[string]string{
+		"api-1": "INFO starting\nERROR boom\n",
+		"api-2": "ERROR again\nINFO done\n",
+	}
+	for name, consume := range map[string]func(LikeOptions, map[corev1.ObjectReference]rest.ResponseWrapper) error{
+		"sequential": LikeOptions.sequentialConsumeRequest,
+		// the containers streamed in parallel must not share the writer of their unmatched lines
+		"parallel": LikeOptions.parallelConsumeRequest,
+	} {
+		t.Run(name, func(t *testing.T) {
+			l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+			l.SplitStreams = true
+			l.Prefix = true
+			var out, errOut lockedBuffer
+			l.Out, l.ErrOut = &out, &errOut
+
+			if err := consume(l, requests); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"[pod/api-1/app] ERROR boom\n", "[pod/api-2/app] ERROR again\n"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("got stdout %q, want %q", out.String(), want)
+				}
+			}
+			if strings.Contains(out.String(), "INFO") {
+				t.Errorf("unexpected unmatched line on stdout %q", out.String())
+			}
+			for _, want := range []string{"[pod/api-1/app] INFO starting\n", "[pod/api-2/app] INFO done\n"} {
+				if !strings.Contains(errOut.String(), want) {
+					t.Errorf("got stderr %q, want %q", errOut.String(), want)
+				}
+			}
+			if strings.Contains(errOut.String(), "ERROR") {
+				t.Errorf("unexpected matching line on stderr %q", errOut.String())
+			}
+		})
+	}
+}
+
+func TestSplitStreamsValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--split-streams", "--no-filter"},
+		{"--split-streams", "--ordered-backlog"},
+		{"--split-streams", "--merge-timestamps"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
+
+func TestMatchTimeoutKeepsFastLines(t *testing.T) {
+	logs := map[string]string{"api-1": "INFO starting\nxxERROR boom\n"}
+	l, requests := newOutputOptions(t, outputText, `(x+x+)+ERROR`, logs)
+	l.MatchTimeout = time.Minute
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	var out, errOut bytes.Buffer
+	l.Out, l.ErrOut = &out, &errOut
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "xxERROR boom\n"; got != want || errOut.Len() > 0 {
+		t.Errorf("got %q and warnings %q, want %q", got, errOut.String(), want)
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		max  int
+		want string
+	}{
+		{"0123456789\n", 0, "0123456789\n"},
+		{"0123456789\n", 10, "0123456789\n"},
+		{"0123456789\n", 4, "0123…\n"},
+		{"0123456789\r\n", 4, "0123…\r\n"},
+		{"0123456789", 4, "0123…"},
+		// characters are not cut in the middle
+		{"héllo wörld\n", 5, "héllo…\n"},
+	} {
+		if got := string(truncateLine([]byte(test.line), test.max, true)); got != test.want {
+			t.Errorf("truncateLine(%q, %d) = %q, want %q", test.line, test.max, got, test.want)
+		}
+	}
+}
+
+func TestRawBytes(t *testing.T) {
+	// a binary stream, with bytes that are not UTF-8 around and inside the match
+	logs := map[string]string{"api-1": "\xff\xfeERR\x80 ERROR \xe9t\xe9\n\x00\x01 INFO\n\xe2\x82\xac\xe2\x82\xac ERROR\n"}
+	l, requests := newOutputOptions(t, outputText, "ERR\\x80|ERROR", logs)
+	l.RawBytes = true
+	l.RawOutput = true
+	l.colorize = true
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	// the matches are highlighted by byte
+	want := "\xff\xfe" + sgrMatch + "ERR\x80" + sgrReset + " " + sgrMatch + "ERROR" + sgrReset + " \xe9t\xe9\n" +
+		"\xe2\x82\xac\xe2\x82\xac " + sgrMatch + "ERROR" + sgrReset + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// --max-line-length counts bytes, even in the middle of a character
+	l.colorize = false
+	l.MaxLineLength = 4
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	out.Reset()
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "\xff\xfeER…\n\xe2\x82\xac\xe2…\n"; got != want {
+		t.Errorf("with --max-line-length got %q, want %q", got, want)
+	}
+}
+
+func TestRawBytesPatternLiterals(t *testing.T) {
+	l := LikeOptions{RawBytes: true}
+	re := regexp.MustCompile("café")
+	// the é of the pattern is the byte 0xe9, not its UTF-8 encoding
+	for line, want := range map[string]bool{"caf\xe9\n": true, "caf\xc3\xa9\n": false, "cafe\n": false} {
+		if got := l.regexpMatch(re, []byte(line)); got != want {
+			t.Errorf("%q: got %v, want %v", line, got, want)
+		}
+	}
+	if line := []byte("ERROR boom\n"); &latin1(line)[0] != &line[0] {
+		t.Error("an ASCII line was decoded")
+	}
+}
+
+func TestRawBytesValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--raw-bytes", "--strip-ansi"},
+		{"--raw-bytes", "--match-columns", "1:10", "--match-runes"},
+		{"--raw-bytes", "--multiline-json"},
+		{"--raw-bytes", "-o", "json"},
+		{"--raw-bytes", "--match-columns", "1:10"},
+		{"--raw-bytes", "--min-severity", "error"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
+
+func TestMaxLineLengthMatchesWholeLine(t *testing.T) {
+	logs := map[string]string{"api-1": "blob=" + strings.Repeat("A", 100) + " ERROR at the end\nINFO short\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.MaxLineLength = 10
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "blob=AAAAA…\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
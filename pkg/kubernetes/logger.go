@@ -0,0 +1,16 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// newLogger returns the logger used for the diagnostics of the plugin itself, as opposed to the pod logs it filters
+func newLogger(w io.Writer, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, must be one of debug, info, warn or error", level)
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: l})), nil
+}
@@ -0,0 +1,138 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const lokiPushPath = "/loki/api/v1/push"
+
+// lokiBatch pushes the matching lines by hundreds, or a second after the first one
+var lokiBatch = notifyBatch{size: 100, interval: time.Second}
+
+// lokiLabelName is the syntax of the names of the Loki labels
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// lokiNotifier pushes the matching lines to Grafana Loki, in a stream per container
+type lokiNotifier struct {
+	url      string
+	labels   map[string]string
+	username string
+	password string
+	client   *http.Client
+}
+
+// lokiPush is the body of a push request, see https://grafana.com/docs/loki/latest/reference/loki-http-api/#ingest-logs
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	// Values are the timestamps in nanoseconds since the epoch, as strings, and the lines
+	Values [][2]string `json:"values"`
+}
+
+// newLokiNotifier checks the URL of --loki-url and parses the labels of --loki-label. The credentials of the
+// basic auth are read from LOKI_USERNAME and LOKI_PASSWORD.
+func newLokiNotifier(rawURL string, labels []string) (*lokiNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --loki-url %q, must be an http or https URL", rawURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(u.Path, lokiPushPath) {
+		u.Path += lokiPushPath
+	}
+	n := &lokiNotifier{
+		url:      u.String(),
+		labels:   map[string]string{},
+		username: os.Getenv("LOKI_USERNAME"),
+		password: os.Getenv("LOKI_PASSWORD"),
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		if !found || !lokiLabelName.MatchString(key) || len(value) == 0 {
+			return nil, fmt.Errorf("invalid --loki-label %q, must be KEY=VALUE with a KEY of letters, digits and underscores", label)
+		}
+		n.labels[key] = value
+	}
+	return n, nil
+}
+
+// streams groups the matching lines by container, in the order of their first line
+func (n *lokiNotifier) streams(matches []notification) []lokiStream {
+	var streams []lokiStream
+	index := map[notification]int{}
+	now := time.Now()
+	for _, match := range matches {
+		key := notification{Context: match.Context, Namespace: match.Namespace, Pod: match.Pod, Container: match.Container}
+		i, ok := index[key]
+		if !ok {
+			labels := map[string]string{}
+			for k, v := range n.labels {
+				labels[k] = v
+			}
+			labels["namespace"] = match.Namespace
+			labels["pod"] = match.Pod
+			labels["container"] = match.Container
+			if len(match.Context) > 0 {
+				labels["context"] = match.Context
+			}
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, lokiStream{Stream: labels})
+		}
+		t := now
+		if parsed, err := time.Parse(time.RFC3339Nano, match.Timestamp); err == nil {
+			t = parsed
+		}
+		streams[i].Values = append(streams[i].Values, [2]string{strconv.FormatInt(t.UnixNano(), 10), match.Line})
+	}
+	return streams
+}
+
+func (n *lokiNotifier) Notify(ctx context.Context, payload notifyPayload) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if err := json.NewEncoder(gz).Encode(lokiPush{Streams: n.streams(payload.Matches)}); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	if len(n.username) > 0 || len(n.password) > 0 {
+		req.SetBasicAuth(n.username, n.password)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if message = bytes.TrimSpace(message); len(message) > 0 {
+			return fmt.Errorf("loki returned %s: %s", resp.Status, message)
+		}
+		return fmt.Errorf("loki returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lokiServer records the pushes it receives, failing the first failures requests with a 503
+type lokiServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	pushes   []lokiPush
+	requests int
+	failures int
+	auth     []string
+}
+
+func newLokiServer(t *testing.T, failures int) *lokiServer {
+	t.Helper()
+	s := &lokiServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if r.URL.Path != lokiPushPath || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("got a request to %s with Content-Encoding %q", r.URL.Path, r.Header.Get("Content-Encoding"))
+		}
+		if username, password, ok := r.BasicAuth(); ok {
+			s.auth = append(s.auth, username+":"+password)
+		}
+		if s.requests <= s.failures {
+			http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("the body is not gzipped: %v", err)
+			return
+		}
+		var push lokiPush
+		if err := json.NewDecoder(gz).Decode(&push); err != nil {
+			t.Errorf("invalid push: %v", err)
+			return
+		}
+		s.pushes = append(s.pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestLokiPush(t *testing.T) {
+	server := newLokiServer(t, 0)
+	logs := map[string]string{
+		"api-1": "2024-06-12T10:04:05Z INFO starting\n2024-06-12T10:04:06Z ERROR boom\n",
+		"api-2": "2024-06-12T10:04:07.5Z ERROR again\n",
+	}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.Pattern = "ERROR"
+	l.Timestamps = true
+	loki, err := newLokiNotifier(server.URL, []string{"cluster=prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := newNotifySink(loki, "--loki-url", notifyBatch{size: 10}, time.Millisecond, io.Discard, nil)
+	l.notifySinks = []*notifySink{sink}
+	l.Out = io.Discard
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.pushes) != 1 {
+		t.Fatalf("got %d pushes, want 1", len(server.pushes))
+	}
+	streams := map[string]lokiStream{}
+	for _, stream := range server.pushes[0].Streams {
+		streams[stream.Stream["pod"]] = stream
+	}
+	want := map[string]lokiStream{
+		"api-1": {
+			Stream: map[string]string{"namespace": "test", "pod": "api-1", "container": "app", "cluster": "prod"},
+			Values: [][2]string{{"1718186646000000000", "ERROR boom"}},
+		},
+		"api-2": {
+			Stream: map[string]string{"namespace": "test", "pod": "api-2", "container": "app", "cluster": "prod"},
+			Values: [][2]string{{"1718186647500000000", "ERROR again"}},
+		},
+	}
+	if !reflect.DeepEqual(streams, want) {
+		t.Errorf("got streams %v, want %v", streams, want)
+	}
+}
+
+func TestLokiBasicAuth(t *testing.T) {
+	server := newLokiServer(t, 0)
+	t.Setenv("LOKI_USERNAME", "tenant")
+	t.Setenv("LOKI_PASSWORD", "secret")
+	loki, err := newLokiNotifier(server.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newNotifySink(loki, "--loki-url", notifyBatch{size: 1}, time.Millisecond, io.Discard, nil)
+	io.WriteString(s.writer(notification{Pod: "api-1"}, false, io.Discard), "ERROR boom\n")
+	s.Close()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !reflect.DeepEqual(server.auth, []string{"tenant:secret"}) {
+		t.Errorf("got credentials %q, want tenant:secret", server.auth)
+	}
+}
+
+func TestLokiOutage(t *testing.T) {
+	server := newLokiServer(t, 100)
+	var errOut, statsOut bytes.Buffer
+	stats := newMatchCounts("container", &statsOut)
+	loki, err := newLokiNotifier(server.URL+lokiPushPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newNotifySink(loki, "--loki-url", notifyBatch{size: 2}, time.Millisecond, &errOut, stats)
+	w := stats.writer("test/api-1/app", s.writer(notification{Pod: "api-1", source: "test/api-1/app"}, false, io.Discard))
+	io.WriteString(w, "one\ntwo\n")
+	s.Close()
+	stats.Print()
+
+	if server.requests != webhookRetries+1 {
+		t.Errorf("got %d requests, want %d", server.requests, webhookRetries+1)
+	}
+	if want := "error: dropped 2 matching lines after 4 failed --loki-url requests: loki returned 503 Service Unavailable: ingester unavailable"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+	if want := "test/api-1/app: 2 (2 not sent to --loki-url)"; !strings.Contains(statsOut.String(), want) {
+		t.Errorf("got stats %q, want %q", statsOut.String(), want)
+	}
+}
+
+func TestLokiValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--loki-url", "loki:3100"},
+		{"--loki-url", "http://loki:3100", "--loki-label", "cluster"},
+		{"--loki-url", "http://loki:3100", "--loki-label", "my-cluster=prod"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--loki-label", "cluster=prod"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err == nil {
+		t.Error("expected --loki-label without --loki-url to be rejected")
+	}
+}
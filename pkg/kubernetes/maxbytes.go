@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// errMaxBytesReached stops the streams once --max-bytes of output were written
+var errMaxBytesReached = errors.New("maximum output size reached")
+
+// outputBudget is the number of bytes that may still be written by all the streams together. The bytes are counted
+// as they are finally written, prefixes, colors, highlighted matches and timestamps included, so that the output
+// is never larger than the limit whatever the flags.
+type outputBudget struct {
+	limit int64
+	out   io.Writer
+	// flag is the flag of the limit named in the notice, --max-bytes or --limit-output-bytes
+	flag string
+
+	mu      sync.Mutex
+	written int64
+	notice  sync.Once
+}
+
+func newOutputBudget(limit int64, out io.Writer) *outputBudget {
+	return &outputBudget{limit: limit, out: out, flag: "--max-bytes"}
+}
+
+// take reserves n bytes of the budget. Once a write would go over the limit, a truncation notice is
+// written once and errMaxBytesReached is returned.
+func (b *outputBudget) take(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.written+int64(n) > b.limit {
+		b.notice.Do(func() {
+			fmt.Fprintf(b.out, "output truncated after %d bytes, %s=%d reached\n", b.written, b.flag, b.limit)
+		})
+		return errMaxBytesReached
+	}
+	b.written += int64(n)
+	return nil
+}
+
+// writer returns a writer taking what is written to w from the budget. A write that would go over
+// the limit is not written at all, so that the output ends with a complete line.
+func (b *outputBudget) writer(w io.Writer) io.Writer {
+	return &budgetWriter{budget: b, writer: w}
+}
+
+type budgetWriter struct {
+	budget *outputBudget
+	writer io.Writer
+}
+
+func (bw *budgetWriter) Write(p []byte) (int, error) {
+	if err := bw.budget.take(len(p)); err != nil {
+		return 0, err
+	}
+	return bw.writer.Write(p)
+}
@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestBudgetWriterCountsTheFinalOutput(t *testing.T) {
+	var out, notice bytes.Buffer
+	l, _, _ := newFakeOptions(t, &fakeAPI{})
+	l.Prefix = true
+	// two prefixed lines of 26 bytes fit, not a third one
+	l.budget = newOutputBudget(60, &notice)
+	w := l.writerFor(corev1.ObjectReference{Namespace: "test", Name: "api-1", FieldPath: "spec.containers{app}"}, l.budget.writer(&out))
+
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		_, err = w.Write([]byte("ERROR boom\n"))
+	}
+	if !errors.Is(err, errMaxBytesReached) {
+		t.Fatalf("got %v, want the limit to be reached by the third line", err)
+	}
+	want := strings.Repeat("[pod/api-1/app] ERROR boom\n", 2)
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := notice.String(), "output truncated after 54 bytes, --max-bytes=60 reached\n"; got != want {
+		t.Errorf("got notice %q, want %q", got, want)
+	}
+
+	w.Write([]byte("ERROR again\n"))
+	if strings.Count(notice.String(), "truncated") != 1 {
+		t.Errorf("the notice was written more than once: %q", notice.String())
+	}
+}
+
+func TestBudgetIsSharedBySources(t *testing.T) {
+	var out, notice bytes.Buffer
+	budget := newOutputBudget(10, &notice)
+	first, second := budget.writer(&out), budget.writer(&out)
+	if _, err := first.Write([]byte("12345\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Write([]byte("12345\n")); !errors.Is(err, errMaxBytesReached) {
+		t.Errorf("got %v, want the budget of both writers to be shared", err)
+	}
+	if got := out.String(); got != "12345\n" {
+		t.Errorf("got %q, want only the first line", got)
+	}
+}
+
+func TestLimitOutputBytesCountsWhatIsWritten(t *testing.T) {
+	api1, api2 := testPod("api-1", corev1.PodRunning, nil), testPod("api-2", corev1.PodRunning, nil)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": &api1, "/namespaces/test/pods/api-2": &api2},
+		logs: map[string]string{
+			"/namespaces/test/pods/api-1/log": strings.Repeat("INFO skipped\nERROR boom\n", 20),
+			"/namespaces/test/pods/api-2/log": strings.Repeat("ERROR boom\n", 20),
+		},
+	}
+	l, cmd, out, errOut := newFakeCommand(t, api)
+	// the prefixes and the escapes of the colors and the highlighted matches are counted, the buffered lines
+	// are flushed when the limit is reached
+	flags := []string{"--pattern", "ERROR", "--no-banner", "--color", "always", "--flush-interval", "1h", "--limit-output-bytes", "200"}
+	if err := completeFlags(l, cmd, flags, "api-1", "api-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatalf("got %v, want the limit to end the run cleanly", err)
+	}
+
+	got := out.String()
+	if len(got) == 0 || len(got) > 200 || !strings.HasSuffix(got, "\n") || !strings.Contains(got, "\x1b[") {
+		t.Errorf("got %q, want at most 200 bytes of colored complete lines", got)
+	}
+	if strings.Contains(got, "skipped") {
+		t.Errorf("got %q, want only the matching lines", got)
+	}
+	if want := fmt.Sprintf("output truncated after %d bytes, --limit-output-bytes=200 reached\n", len(got)); errOut.String() != want {
+		t.Errorf("got notice %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestMaxBytesAndLimitOutputBytesConflict(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--max-bytes", "100", "--limit-output-bytes", "200"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "only one of --max-bytes or --limit-output-bytes") {
+		t.Errorf("got %v, want the conflict of --max-bytes and --limit-output-bytes", err)
+	}
+}
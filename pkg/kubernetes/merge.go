@@ -0,0 +1,185 @@
+package kubernetes
+
+import (
+	"bytes"
+	"container/heap"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMergeWindow is how long --merge-timestamps holds a line for the older lines of other containers
+	defaultMergeWindow = time.Second
+	// mergeBufferSize is the number of lines --merge-timestamps holds at most, the oldest one is written
+	// when it is exceeded
+	mergeBufferSize = 10000
+)
+
+// timestampMerger orders the lines of every container by the timestamp of the server with a bounded reorder buffer.
+// A line is held for the window after it was received, so that the older lines of slower containers can be
+// written before it. Lines without a timestamp keep their place after the previous line of their container,
+// and are written as they are received when their container has not had a timestamp yet.
+type timestampMerger struct {
+	window time.Duration
+	size   int
+	// hide removes the timestamps once the lines are ordered, when they were not asked for
+	hide bool
+
+	mu      sync.Mutex
+	lines   mergeHeap
+	seq     int
+	active  int
+	err     error
+	mux     *multiplexer
+	out     io.Writer
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// mergedLine is a line held by the merger until it is written to its container's writer
+type mergedLine struct {
+	time     time.Time
+	seq      int
+	received time.Time
+	line     []byte
+	writer   io.Writer
+}
+
+// mergeHeap orders the held lines by timestamp, then by the order they were received
+type mergeHeap []mergedLine
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].time.Equal(h[j].time) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].time.Before(h[j].time)
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergedLine)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	line := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return line
+}
+
+func newTimestampMerger(window time.Duration, size int, hide bool) *timestampMerger {
+	m := &timestampMerger{window: window, size: size, hide: hide, stopped: make(chan struct{})}
+	// a window that is not positive is rejected by Vaildate
+	if window > 0 {
+		go m.run()
+	}
+	return m
+}
+
+// run writes the lines held for longer than the window
+func (m *timestampMerger) run() {
+	ticker := time.NewTicker(m.window / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopped:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for len(m.lines) > 0 && now.Sub(m.lines[0].received) >= m.window {
+				m.writeOldest()
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// writeOldest writes the oldest held line, the first error is returned by the next writes
+func (m *timestampMerger) writeOldest() {
+	line := heap.Pop(&m.lines).(mergedLine)
+	if _, err := line.writer.Write(line.line); err != nil && m.err == nil {
+		m.err = err
+	}
+}
+
+// Flush writes every held line in order
+func (m *timestampMerger) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.lines) > 0 {
+		m.writeOldest()
+	}
+}
+
+// Close writes every held line and stops the merger
+func (m *timestampMerger) Close() {
+	m.once.Do(func() { close(m.stopped) })
+	m.Flush()
+}
+
+// source returns the single source of mux the ordered lines are written to, so that the multiplexer
+// does not interleave them again. The containers streamed in parallel are tracked until done is called.
+func (m *timestampMerger) source(mux *multiplexer) io.Writer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mux != mux {
+		m.mux, m.out = mux, mux.source("the merged containers")
+	}
+	m.active++
+	return m.out
+}
+
+// done tells that a container streamed to the source of a multiplexer is done, the held lines are written
+// once every container is done, before the multiplexer is closed
+func (m *timestampMerger) done() {
+	m.mu.Lock()
+	m.active--
+	active := m.active
+	m.mu.Unlock()
+	if active == 0 {
+		m.Flush()
+	}
+}
+
+// writer returns a writer holding the lines of a container until they can be written to w in order
+func (m *timestampMerger) writer(w io.Writer) io.Writer {
+	return &mergeWriter{merger: m, writer: w}
+}
+
+type mergeWriter struct {
+	merger *timestampMerger
+	writer io.Writer
+	// last is the timestamp of the previous line of the container
+	last time.Time
+}
+
+func (mw *mergeWriter) Write(p []byte) (int, error) {
+	m := mw.merger
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	now := time.Now()
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		t, rest, ok := splitTimestamp(line)
+		if ok {
+			mw.last = t
+			if m.hide {
+				line = rest
+			}
+		} else if mw.last.IsZero() {
+			if _, err := mw.writer.Write(line); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		m.seq++
+		heap.Push(&m.lines, mergedLine{time: mw.last, seq: m.seq, received: now, line: append([]byte(nil), line...), writer: mw.writer})
+		for len(m.lines) > m.size {
+			m.writeOldest()
+		}
+	}
+	return len(p), m.err
+}
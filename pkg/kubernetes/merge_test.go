@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMergeTimestampsOrdersContainers(t *testing.T) {
+	logs := map[string]string{
+		"api-1": "2024-06-12T10:04:01Z ERROR one\n2024-06-12T10:04:04Z ERROR four\n2024-06-12T10:04:05Z INFO five\n",
+		"api-2": "2024-06-12T10:04:02Z ERROR two\ncontinued without a timestamp\n2024-06-12T10:04:03Z ERROR three\n2024-06-12T10:04:06Z ERROR six\n",
+	}
+	tests := []struct {
+		timestamps bool
+		want       string
+	}{
+		{
+			want: `[pod/api-1/app] ERROR one
+[pod/api-2/app] ERROR two
+[pod/api-2/app] continued without a timestamp
+[pod/api-2/app] ERROR three
+[pod/api-1/app] ERROR four
+[pod/api-2/app] ERROR six
+`,
+		},
+		{
+			timestamps: true,
+			want: `[pod/api-1/app] 2024-06-12T10:04:01Z ERROR one
+[pod/api-2/app] 2024-06-12T10:04:02Z ERROR two
+[pod/api-2/app] continued without a timestamp
+[pod/api-2/app] 2024-06-12T10:04:03Z ERROR three
+[pod/api-1/app] 2024-06-12T10:04:04Z ERROR four
+[pod/api-2/app] 2024-06-12T10:04:06Z ERROR six
+`,
+		},
+	}
+	for _, test := range tests {
+		l, requests := newOutputOptions(t, outputText, "ERROR|continued", logs)
+		l.Prefix = true
+		l.merger = newTimestampMerger(time.Minute, mergeBufferSize, !test.timestamps)
+		l.Timestamps = true
+		var out bytes.Buffer
+		l.Out = &out
+
+		if err := l.parallelConsumeRequest(requests); err != nil {
+			t.Fatal(err)
+		}
+		l.merger.Close()
+
+		if out.String() != test.want {
+			t.Errorf("--timestamps=%v: got\n%s\nwant\n%s", test.timestamps, out.String(), test.want)
+		}
+	}
+}
+
+func TestMergeTimestampsWindow(t *testing.T) {
+	m := newTimestampMerger(40*time.Millisecond, mergeBufferSize, true)
+	defer m.Close()
+	var out lockedBuffer
+	w := m.writer(&out)
+	if _, err := w.Write([]byte("2024-06-12T10:04:02Z ERROR two\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("2024-06-12T10:04:01Z ERROR one\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "" {
+		t.Errorf("got %q before the window ended, want nothing", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got, want := out.String(), "ERROR one\nERROR two\n"; got != want {
+		t.Errorf("got %q once the window ended, want %q", got, want)
+	}
+}
+
+func TestMergeTimestampsBufferSize(t *testing.T) {
+	m := newTimestampMerger(time.Minute, 2, false)
+	defer m.Close()
+	var out bytes.Buffer
+	w := m.writer(&out)
+	io.WriteString(w, "2024-06-12T10:04:03Z three\n2024-06-12T10:04:01Z one\n")
+	if out.Len() > 0 {
+		t.Fatalf("got %q before the buffer was full", out.String())
+	}
+	// a third line writes the oldest one
+	io.WriteString(w, "2024-06-12T10:04:02Z two\n")
+	if got, want := out.String(), "2024-06-12T10:04:01Z one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMergeTimestampsWithoutTimestampsYet(t *testing.T) {
+	m := newTimestampMerger(time.Minute, mergeBufferSize, true)
+	defer m.Close()
+	var out bytes.Buffer
+	io.WriteString(m.writer(&out), "no timestamp at all\n")
+	if got, want := out.String(), "no timestamp at all\n"; got != want {
+		t.Errorf("got %q, want the line written as it is received", got)
+	}
+}
+
+func TestMergeTimestampsValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--merge-timestamps", "--ordered-backlog"},
+		{"--merge-timestamps", "--pattern", "ERROR", "--only-matching"},
+		{"--merge-timestamps", "--merge-window", "0s"},
+		{"--merge-window", "5s"},
+		{"--merge-timestamps", "--timestamp-format", "unix"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--merge-timestamps", "--timestamps", "--timestamp-format", "unix"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Errorf("--timestamp-format with --timestamps: %v", err)
+	}
+}
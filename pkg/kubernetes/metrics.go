@@ -0,0 +1,257 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// metricsShutdownTimeout is how long the server of --metrics-addr waits for the running scrapes when the command ends
+const metricsShutdownTimeout = 5 * time.Second
+
+// counterVec is a counter per value of its labels, e.g. the matching lines per source
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  int
+}
+
+// add adds n to the counter of the label values, a counter added 0 is reported as 0
+func (c *counterVec) add(n int, values ...string) {
+	key := strings.Join(values, "\x00")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: values}
+		c.values[key] = v
+	}
+	v.value += n
+}
+
+// get returns the counter of the label values
+func (c *counterVec) get(values ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[strings.Join(values, "\x00")]; ok {
+		return v.value
+	}
+	return 0
+}
+
+// each calls fn with the label values and the value of every counter, sorted by label values
+func (c *counterVec) each(fn func(values []string, value int)) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]counterValue, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, *c.values[key])
+	}
+	c.mu.Unlock()
+	for _, v := range values {
+		fn(v.labels, v.value)
+	}
+}
+
+// metricsRegistry holds the counters read by --stats and served by --metrics-addr
+type metricsRegistry struct {
+	mu       sync.Mutex
+	counters []*counterVec
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{}
+}
+
+// counter registers a counter with the labels
+func (r *metricsRegistry) counter(name, help string, labels ...string) *counterVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &counterVec{name: name, help: help, labels: labels, values: map[string]*counterValue{}}
+	r.counters = append(r.counters, c)
+	return c
+}
+
+// writeText writes every counter in the text format of Prometheus
+func (r *metricsRegistry) writeText(w io.Writer) error {
+	r.mu.Lock()
+	counters := append([]*counterVec(nil), r.counters...)
+	r.mu.Unlock()
+	b := bufio.NewWriter(w)
+	for _, c := range counters {
+		fmt.Fprintf(b, "# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(b, "# TYPE %s counter\n", c.name)
+		c.each(func(values []string, value int) {
+			b.WriteString(c.name)
+			if len(c.labels) > 0 {
+				pairs := make([]string, len(c.labels))
+				for i, label := range c.labels {
+					pairs[i] = fmt.Sprintf("%s=%q", label, values[i])
+				}
+				b.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+			fmt.Fprintf(b, " %d\n", value)
+		})
+	}
+	return b.Flush()
+}
+
+func (r *metricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.writeText(w)
+}
+
+// metricsServer serves the registry on /metrics until it is closed
+type metricsServer struct {
+	server   *http.Server
+	listener net.Listener
+	done     chan struct{}
+}
+
+// serveMetrics listens on addr, e.g. :9090, and serves the registry in the background
+func serveMetrics(addr string, registry *metricsRegistry) (*metricsServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serve --metrics-addr: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	s := &metricsServer{
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// URL returns the URL of the metrics
+func (s *metricsServer) URL() string {
+	return "http://" + s.listener.Addr().String() + "/metrics"
+}
+
+// Close stops accepting scrapes and waits for the running ones
+func (s *metricsServer) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	s.server.Shutdown(ctx)
+	<-s.done
+}
+
+// streamMetrics counts what is read from the log streams of every source for --metrics-addr
+type streamMetrics struct {
+	linesRead  *counterVec
+	bytesRead  *counterVec
+	reconnects *counterVec
+	errors     *counterVec
+}
+
+func newStreamMetrics(registry *metricsRegistry) *streamMetrics {
+	return &streamMetrics{
+		linesRead:  registry.counter("kubectl_like_lines_read_total", "Lines read from the log stream of every source, matching or not.", "source"),
+		bytesRead:  registry.counter("kubectl_like_bytes_read_total", "Bytes read from the log stream of every source.", "source"),
+		reconnects: registry.counter("kubectl_like_reconnects_total", "Log streams reopened after they ended or the container restarted.", "source"),
+		errors:     registry.counter("kubectl_like_stream_errors_total", "Log streams that failed.", "source"),
+	}
+}
+
+// counted returns the request of source, counting the lines and bytes of its stream
+func (m *streamMetrics) counted(source string, request rest.ResponseWrapper) rest.ResponseWrapper {
+	m.linesRead.add(0, source)
+	m.bytesRead.add(0, source)
+	return &countedRequest{ResponseWrapper: request, metrics: m, source: source}
+}
+
+type countedRequest struct {
+	rest.ResponseWrapper
+	metrics *streamMetrics
+	source  string
+}
+
+func (r *countedRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &countedStream{ReadCloser: stream, metrics: r.metrics, source: r.source}, nil
+}
+
+type countedStream struct {
+	io.ReadCloser
+	metrics *streamMetrics
+	source  string
+}
+
+func (s *countedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.metrics.bytesRead.add(n, s.source)
+		s.metrics.linesRead.add(bytes.Count(p[:n], []byte("\n")), s.source)
+	}
+	return n, err
+}
+
+// startCounters sets up the counters of --stats and --metrics-addr, and the snapshot printed on the signals of
+// statsSignals while following, which only counts the lines read and matched. stop ends them, printing the summary
+// of --stats.
+func (l *LikeOptions) startCounters() (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if l.Stats || len(l.MetricsAddr) > 0 {
+		// --stats prints the counters served by --metrics-addr
+		registry := newMetricsRegistry()
+		if len(l.MetricsAddr) > 0 {
+			server, err := serveMetrics(l.MetricsAddr, registry)
+			if err != nil {
+				return nil, err
+			}
+			stops = append(stops, server.Close)
+			l.logger.Info("serving metrics", "url", server.URL())
+			l.streamMetrics = newStreamMetrics(registry)
+		}
+		var out io.Writer
+		if l.Stats {
+			out = l.ErrOut
+		}
+		stats := newRegisteredMatchCounts(registry, "container", out)
+		stops = append(stops, stats.Print)
+		l.onInterrupt(stats.Print)
+		l.stats = stats
+	}
+	if l.Follow && len(statsSignals) > 0 {
+		snapshot := newStatsSnapshot(l.ErrOut)
+		// stopped first, so that a snapshot being printed is not mixed with the summary of --stats
+		stops = append(stops, snapshot.notify())
+		l.snapshot = snapshot
+	}
+	return stop, nil
+}
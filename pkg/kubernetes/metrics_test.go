@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q", got)
+	}
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	logs := map[string]string{
+		"api-1": "INFO starting\nERROR boom\n",
+		"api-2": "ERROR again\nERROR still\nINFO done\n",
+	}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.Out = io.Discard
+	registry := newMetricsRegistry()
+	server, err := serveMetrics("127.0.0.1:0", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	l.streamMetrics = newStreamMetrics(registry)
+	l.stats = newRegisteredMatchCounts(registry, "container", nil)
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	got := scrape(t, server.URL())
+	for _, want := range []string{
+		"# TYPE kubectl_like_lines_read_total counter\n",
+		`kubectl_like_lines_read_total{source="test/api-1/app"} 2` + "\n",
+		`kubectl_like_lines_read_total{source="test/api-2/app"} 3` + "\n",
+		`kubectl_like_bytes_read_total{source="test/api-1/app"} 25` + "\n",
+		`kubectl_like_lines_matched_total{source="test/api-1/app"} 1` + "\n",
+		`kubectl_like_lines_matched_total{source="test/api-2/app"} 2` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("the metrics do not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMetricsCountReconnects(t *testing.T) {
+	shortReattachInterval(t)
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {
+			restartedPod(0, time.Now()),
+			restartedPod(1, time.Now()),
+			succeededPod(),
+		}},
+		logs: map[string]string{"/namespaces/test/pods/api-1/log": "INFO serving\nERROR crashed\n"},
+	}
+	l, _ := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Follow = true
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	registry := newMetricsRegistry()
+	l.streamMetrics = newStreamMetrics(registry)
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.consumeRequest(appRef, request, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.streamMetrics.reconnects.get("test/api-1/app"); got != 1 {
+		t.Errorf("got %d reconnects, want 1", got)
+	}
+	if got := l.streamMetrics.linesRead.get("test/api-1/app"); got != 4 {
+		t.Errorf("got %d lines read, want 4 from both instances", got)
+	}
+}
+
+func TestMetricsLabelsAreEscaped(t *testing.T) {
+	registry := newMetricsRegistry()
+	registry.counter("kubectl_like_test_total", "A test.", "source", "flag").add(2, `a"b\c`, "--webhook")
+	var out bytes.Buffer
+	if err := registry.writeText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP kubectl_like_test_total A test.\n# TYPE kubectl_like_test_total counter\n" +
+		`kubectl_like_test_total{source="a\"b\\c",flag="--webhook"} 2` + "\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestMetricsServerShutsDown(t *testing.T) {
+	server, err := serveMetrics("127.0.0.1:0", newMetricsRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := server.URL()
+	scrape(t, url)
+	server.Close()
+	if _, err := http.Get(url); err == nil {
+		t.Error("the metrics are still served after Close")
+	}
+	if _, err := serveMetrics(strings.TrimPrefix(strings.TrimSuffix(url, "/metrics"), "http://"), newMetricsRegistry()); err != nil {
+		t.Errorf("the address is not released: %v", err)
+	}
+}
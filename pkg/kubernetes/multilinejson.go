@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"bytes"
+)
+
+// maxJSONObjectLines is the number of lines after which --multiline-json gives up on an object that is never
+// closed, e.g. a line starting with a brace that is not JSON, and passes the lines on as they are
+const maxJSONObjectLines = 1000
+
+// jsonAssembler reassembles the JSON objects and arrays pretty-printed across several lines into a single
+// record, so that they are matched and printed as a whole. The record keeps the timestamp of its first line
+// and the line breaks of the object, and drops the timestamps of the other lines.
+type jsonAssembler struct {
+	timestamps bool
+
+	record []byte
+	lines  int
+	depth  int
+	// inString and escaped are the state of the scanner inside a string of the object
+	inString bool
+	escaped  bool
+}
+
+// add returns the records complete with the line: the line itself when it is not part of an object,
+// the whole object when the line closes it, or nothing while the object is still open
+func (a *jsonAssembler) add(line []byte) [][]byte {
+	message := line
+	if a.timestamps {
+		if _, rest, ok := splitTimestamp(line); ok {
+			message = rest
+		}
+	}
+	if a.record == nil {
+		trimmed := bytes.TrimSpace(message)
+		if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+			return [][]byte{line}
+		}
+		a.scan(message)
+		if a.depth <= 0 {
+			a.reset()
+			return [][]byte{line}
+		}
+		a.record = append([]byte{}, line...)
+		a.lines = 1
+		return nil
+	}
+
+	a.record = append(a.record, message...)
+	a.lines++
+	a.scan(message)
+	if a.depth > 0 && a.lines < maxJSONObjectLines {
+		return nil
+	}
+	return [][]byte{a.Flush()}
+}
+
+// Flush returns the object still open, e.g. at the end of the stream, as it is
+func (a *jsonAssembler) Flush() []byte {
+	record := a.record
+	a.reset()
+	return record
+}
+
+func (a *jsonAssembler) reset() {
+	a.record, a.lines, a.depth, a.inString, a.escaped = nil, 0, 0, false, false
+}
+
+// scan updates the depth of the object with the braces and brackets outside of its strings
+func (a *jsonAssembler) scan(b []byte) {
+	for _, c := range b {
+		switch {
+		case a.escaped:
+			a.escaped = false
+		case a.inString:
+			switch c {
+			case '\\':
+				a.escaped = true
+			case '"':
+				a.inString = false
+			}
+		case c == '"':
+			a.inString = true
+		case c == '{' || c == '[':
+			a.depth++
+		case c == '}' || c == ']':
+			a.depth--
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONAssembler(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		timestamps bool
+		lines      []string
+		want       []string
+	}{
+		{
+			name:  "plain and single-line JSON lines",
+			lines: []string{"INFO starting\n", `{"level":"info"}` + "\n"},
+			want:  []string{"INFO starting\n", `{"level":"info"}` + "\n"},
+		},
+		{
+			name:  "pretty-printed object",
+			lines: []string{"{\n", `  "level": "error",` + "\n", `  "err": {"code": 1}` + "\n", "}\n", "INFO next\n"},
+			want:  []string{"{\n  \"level\": \"error\",\n  \"err\": {\"code\": 1}\n}\n", "INFO next\n"},
+		},
+		{
+			name:  "braces in strings",
+			lines: []string{"{\n", `  "msg": "unbalanced } \" {"` + "\n", "}\n"},
+			want:  []string{"{\n  \"msg\": \"unbalanced } \\\" {\"\n}\n"},
+		},
+		{
+			name:       "timestamps of the other lines are dropped",
+			timestamps: true,
+			lines:      []string{"2024-06-12T10:04:05Z [\n", "2024-06-12T10:04:05Z   1\n", "2024-06-12T10:04:05Z ]\n"},
+			want:       []string{"2024-06-12T10:04:05Z [\n  1\n]\n"},
+		},
+		{
+			name:  "object never closed",
+			lines: []string{"{ not json\n", "INFO next"},
+			want:  []string{"{ not json\nINFO next"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a := &jsonAssembler{timestamps: test.timestamps}
+			var got []string
+			for _, line := range test.lines {
+				for _, record := range a.add([]byte(line)) {
+					got = append(got, string(record))
+				}
+			}
+			if record := a.Flush(); record != nil {
+				got = append(got, string(record))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMultilineJSON(t *testing.T) {
+	logs := map[string]string{"api-1": strings.Join([]string{
+		"{",
+		`  "level": "info",`,
+		`  "msg": "starting"`,
+		"}",
+		"{",
+		`  "level": "error",`,
+		`  "msg": "boom"`,
+		"}",
+		"",
+	}, "\n")}
+	l, requests := newOutputOptions(t, outputText, `"level": "error"`, logs)
+	l.MultilineJSON = true
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "{\n  \"level\": \"error\",\n  \"msg\": \"boom\"\n}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// --jq reads the object as a whole
+	jq := newJQOptions(t, ".msg")
+	l.jq, l.JQRaw = jq.jq, true
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	out.Reset()
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "boom\n"; got != want {
+		t.Errorf("with --jq got %q, want %q", got, want)
+	}
+}
+
+func TestMultilineJSONValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--multiline-json", "--no-filter"},
+		{"--multiline-json", "--ordered-backlog"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// defaultPerSourceBuffer is the number of lines a source can queue before its new lines are dropped
+const defaultPerSourceBuffer = 1000
+
+// multiplexer merges the lines of concurrent streams into a single writer. Every source has its own bounded
+// queue and the queues are drained round-robin, so that a chatty source cannot delay the lines of quiet ones.
+// When the queue of a source is full, its new lines are dropped and counted, and a warning is written once.
+type multiplexer struct {
+	out    io.Writer
+	errOut io.Writer
+	size   int
+	stats  *matchCounts
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	sources []*muxSource
+	next    int
+	closed  bool
+	err     error
+	warned  bool
+}
+
+// muxSource is the writer of a single source of a multiplexer
+type muxSource struct {
+	m     *multiplexer
+	name  string
+	queue [][]byte
+}
+
+// newMultiplexer returns a multiplexer writing to out. The first dropped line is reported to errOut,
+// and every dropped line is added to stats if not nil.
+func newMultiplexer(out, errOut io.Writer, size int, stats *matchCounts) *multiplexer {
+	m := &multiplexer{out: out, errOut: errOut, size: size, stats: stats}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// source returns a new writer whose lines are queued separately from the other sources
+func (m *multiplexer) source(name string) io.Writer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := &muxSource{m: m, name: name}
+	m.sources = append(m.sources, s)
+	return s
+}
+
+func (s *muxSource) Write(p []byte) (int, error) {
+	m := s.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(s.queue) >= m.size {
+		if !m.warned {
+			m.warned = true
+			fmt.Fprintf(m.errOut, "warning: dropping lines of %s, its queue of %d lines is full, use --per-source-buffer to increase it and --stats to count the dropped lines\n", s.name, m.size)
+		}
+		if m.stats != nil {
+			m.stats.drop(s.name, 1)
+		}
+		return len(p), nil
+	}
+	s.queue = append(s.queue, append([]byte(nil), p...))
+	m.cond.Signal()
+	return len(p), nil
+}
+
+// Close stops accepting lines, Run returns once the queued lines are written
+func (m *multiplexer) Close() error {
+	return m.CloseWithError(nil)
+}
+
+// CloseWithError stops accepting lines and makes Run return err without writing the queued lines
+func (m *multiplexer) CloseWithError(err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		m.err = err
+	}
+	m.cond.Broadcast()
+	return nil
+}
+
+// Run writes the queued lines round-robin until the multiplexer is closed and drained
+func (m *multiplexer) Run() error {
+	for {
+		m.mu.Lock()
+		line := m.dequeue()
+		for line == nil && !m.closed {
+			m.cond.Wait()
+			line = m.dequeue()
+		}
+		err := m.err
+		m.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			return nil
+		}
+		if _, err := m.out.Write(line); err != nil {
+			m.CloseWithError(err)
+			return err
+		}
+	}
+}
+
+// dequeue pops the first line of the next source with queued lines, or returns nil if all queues are empty
+func (m *multiplexer) dequeue() []byte {
+	for i := range m.sources {
+		s := m.sources[(m.next+i)%len(m.sources)]
+		if len(s.queue) > 0 {
+			line := s.queue[0]
+			s.queue = s.queue[1:]
+			m.next = (m.next + i + 1) % len(m.sources)
+			return line
+		}
+	}
+	return nil
+}
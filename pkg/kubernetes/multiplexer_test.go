@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// arrivalWriter records when every line starting with a watched prefix is written
+type arrivalWriter struct {
+	mu       sync.Mutex
+	prefix   string
+	arrivals map[string]time.Time
+}
+
+func (w *arrivalWriter) Write(p []byte) (int, error) {
+	// a slow terminal or pipe
+	time.Sleep(10 * time.Microsecond)
+	if line := string(p); strings.HasPrefix(line, w.prefix) {
+		w.mu.Lock()
+		w.arrivals[line] = time.Now()
+		w.mu.Unlock()
+	}
+	return len(p), nil
+}
+
+func TestMultiplexerFairness(t *testing.T) {
+	const slowLines = 20
+	const maxDelay = 500 * time.Millisecond
+	out := &arrivalWriter{prefix: "slow", arrivals: map[string]time.Time{}}
+	var errOut bytes.Buffer
+	stats := newMatchCounts("container", &bytes.Buffer{})
+	mux := newMultiplexer(out, &errOut, 100, stats)
+	fast, slow := mux.source("fast"), mux.source("slow")
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				fast.Write([]byte(fmt.Sprintf("fast %d\n", i)))
+			}
+		}
+	}()
+	sent := map[string]time.Time{}
+	go func() {
+		defer wg.Done()
+		defer close(stop)
+		for i := 0; i < slowLines; i++ {
+			line := fmt.Sprintf("slow %d\n", i)
+			sent[line] = time.Now()
+			slow.Write([]byte(line))
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	go func() {
+		wg.Wait()
+		mux.Close()
+	}()
+	if err := mux.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for line, at := range sent {
+		arrived, ok := out.arrivals[line]
+		if !ok {
+			t.Errorf("%q of the slow source was not written", line)
+			continue
+		}
+		if delay := arrived.Sub(at); delay > maxDelay {
+			t.Errorf("%q of the slow source was written after %s", line, delay)
+		}
+	}
+	if stats.dropped.get("fast") == 0 {
+		t.Error("the lines of the fast source overflowing its queue were not counted as dropped")
+	}
+	if stats.dropped.get("slow") != 0 {
+		t.Errorf("%d lines of the slow source were dropped", stats.dropped.get("slow"))
+	}
+	if got := strings.Count(errOut.String(), "warning: dropping lines of fast"); got != 1 {
+		t.Errorf("got %d drop warnings, want one: %q", got, errOut.String())
+	}
+}
+
+func TestMultiplexerWarnsWithoutStats(t *testing.T) {
+	var out, errOut bytes.Buffer
+	mux := newMultiplexer(&out, &errOut, 1, nil)
+	source := mux.source("test/api-1/app")
+	for i := 0; i < 3; i++ {
+		source.Write([]byte("line\n"))
+	}
+	if !strings.Contains(errOut.String(), "warning: dropping lines of test/api-1/app, its queue of 1 lines is full") {
+		t.Errorf("no warning for the dropped lines: %q", errOut.String())
+	}
+	mux.Close()
+	if err := mux.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "line\n" {
+		t.Errorf("got %q, want the queued line only", got)
+	}
+}
+
+func TestMultiplexerCloseWithError(t *testing.T) {
+	var out bytes.Buffer
+	mux := newMultiplexer(&out, &bytes.Buffer{}, 10, nil)
+	mux.source("a").Write([]byte("queued\n"))
+	mux.CloseWithError(errMaxBytesReached)
+	if err := mux.Run(); err != errMaxBytesReached {
+		t.Errorf("got %v, want the error the multiplexer was closed with", err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("the queued lines were written after an error: %q", out.String())
+	}
+}
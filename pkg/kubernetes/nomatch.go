@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// matchTally counts the lines read and matched by every stream when the logs are read once, so that a pattern
+// matching nothing can be told from empty logs at the end
+type matchTally struct {
+	scanned atomic.Int64
+	matched atomic.Int64
+}
+
+// add counts a line read, and whether it matched. It is a no-op on a nil tally.
+func (t *matchTally) add(matched bool) {
+	if t == nil {
+		return
+	}
+	t.scanned.Add(1)
+	if matched {
+		t.matched.Add(1)
+	}
+}
+
+// report writes a note to out when no line matched the pattern
+func (t *matchTally) report(out io.Writer, pattern string) {
+	if t.matched.Load() > 0 {
+		return
+	}
+	fmt.Fprintf(out, "note: no lines matched --pattern %q (scanned %d lines)\n", pattern, t.scanned.Load())
+}
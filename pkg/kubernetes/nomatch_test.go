@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+)
+
+func runNoMatch(t *testing.T, flags ...string) (string, string) {
+	t.Helper()
+	api := newPodAPI("api-1")
+	api.logs = map[string]string{"/namespaces/test/pods/api-1/log": "INFO starting\nINFO ready\n"}
+	l, cmd, out, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, append(flags, "--no-banner"), "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), errOut.String()
+}
+
+func TestNoMatchNote(t *testing.T) {
+	_, errOut := runNoMatch(t, "--pattern", "ERROR")
+	if want := "note: no lines matched --pattern \"ERROR\" (scanned 2 lines)\n"; errOut != want {
+		t.Errorf("got %q, want %q", errOut, want)
+	}
+}
+
+func TestNoMatchNoteOnlyWhenNothingMatched(t *testing.T) {
+	tests := map[string][]string{
+		"matched":   {"--pattern", "ready"},
+		"quiet":     {"--pattern", "ERROR", "--quiet"},
+		"following": {"--pattern", "ERROR", "-f", "--no-reattach"},
+	}
+	for name, flags := range tests {
+		if _, errOut := runNoMatch(t, flags...); strings.Contains(errOut, "no lines matched") {
+			t.Errorf("%s: got the note %q", name, errOut)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// noMatchReset restarts the --no-match-timeout window on every matching line
+	noMatchReset = "reset"
+	// noMatchAbsolute only waits for the first matching line, from the start of the streams
+	noMatchAbsolute = "absolute"
+)
+
+var noMatchModes = []string{noMatchReset, noMatchAbsolute}
+
+// noMatchAfterFunc calls f once d elapsed and returns the function stopping it, replaced by the tests with a
+// fake clock
+var noMatchAfterFunc = deadlineAfterFunc
+
+// noMatchTimer cancels its context when no line matched for --no-match-timeout. Like the one of --for, the
+// context replaces the one of the command, so that everything stops like when the command is interrupted, but
+// the run then fails, e.g. to gate a rollout on a line telling that the pods are ready.
+type noMatchTimer struct {
+	timeout time.Duration
+	reset   bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu sync.Mutex
+	// generation tells the window of a pending expiry, so that the expiry of a window reset meanwhile is ignored
+	generation int
+	stop       func() bool
+	started    bool
+	matched    bool
+	expired    bool
+}
+
+func newNoMatchTimer(parent context.Context, timeout time.Duration, mode string) *noMatchTimer {
+	ctx, cancel := context.WithCancel(parent)
+	return &noMatchTimer{
+		timeout: timeout,
+		reset:   mode == noMatchReset,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// start starts the first window when the streams are about to be read, so that the time spent resolving the
+// targets is not counted. It is a no-op on a nil or started timer.
+func (t *noMatchTimer) start() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		t.started = true
+		t.schedule()
+	}
+}
+
+// schedule starts a new window, the mutex being held
+func (t *noMatchTimer) schedule() {
+	t.generation++
+	generation := t.generation
+	t.stop = noMatchAfterFunc(t.timeout, func() { t.expire(generation) })
+}
+
+// match restarts the window after a matching line, or stops the timer for good in the absolute mode
+func (t *noMatchTimer) match() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started || t.expired || t.matched && !t.reset {
+		return
+	}
+	t.matched = true
+	t.stop()
+	if t.reset {
+		t.schedule()
+	} else {
+		t.generation++
+	}
+}
+
+func (t *noMatchTimer) expire(generation int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the window was reset, or an interrupt came first
+	if generation != t.generation || t.ctx.Err() != nil {
+		return
+	}
+	t.expired = true
+	t.cancel()
+}
+
+// Stop stops the timer once the run is over. It is a no-op on a nil or unstarted timer.
+func (t *noMatchTimer) Stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		t.stop()
+	}
+	t.generation++
+}
+
+// Err returns the error of a run stopped because no line matched in time, nil otherwise or on a nil timer
+func (t *noMatchTimer) Err() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.expired {
+		return nil
+	}
+	if t.reset && t.matched {
+		return newError(ErrNoMatches, fmt.Errorf("no line matched for --no-match-timeout=%s since the last match", t.timeout))
+	}
+	return newError(ErrNoMatches, fmt.Errorf("no line matched within --no-match-timeout=%s", t.timeout))
+}
+
+// writer returns a writer restarting the window of the timer on every matching line written to w
+func (t *noMatchTimer) writer(w io.Writer) io.Writer {
+	return &noMatchWriter{timer: t, writer: w}
+}
+
+type noMatchWriter struct {
+	timer  *noMatchTimer
+	writer io.Writer
+}
+
+func (w *noMatchWriter) Write(p []byte) (int, error) {
+	w.timer.match()
+	return w.writer.Write(p)
+}
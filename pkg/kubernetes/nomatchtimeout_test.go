@@ -0,0 +1,121 @@
+package kubernetes
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeNoMatchClock replaces the clock of --no-match-timeout, a window only expires when the test calls the
+// function received from the returned channel
+func fakeNoMatchClock(t *testing.T) chan func() {
+	t.Helper()
+	scheduled := make(chan func(), 2)
+	previous := noMatchAfterFunc
+	noMatchAfterFunc = func(d time.Duration, f func()) func() bool {
+		scheduled <- f
+		return func() bool { return true }
+	}
+	t.Cleanup(func() { noMatchAfterFunc = previous })
+	return scheduled
+}
+
+// runNoMatchTimeout follows the logs written to the returned writer with --no-match-timeout 2m and the flags
+func runNoMatchTimeout(t *testing.T, flags ...string) (*io.PipeWriter, chan error) {
+	t.Helper()
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	flags = append([]string{"-f", "--no-reattach", "--pattern", "ERROR", "--no-match-timeout", "2m", "--no-banner"}, flags...)
+	if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	writer := pipeLogs(t, l)
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+	return writer, done
+}
+
+func waitForRun(t *testing.T, done chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run did not end")
+		return nil
+	}
+}
+
+func TestNoMatchTimeoutResetsOnEveryMatch(t *testing.T) {
+	scheduled := fakeNoMatchClock(t)
+	writer, done := runNoMatchTimeout(t)
+	first := <-scheduled
+
+	io.WriteString(writer, "INFO starting\nERROR one\n")
+	second := <-scheduled
+	// the window before the match is over
+	first()
+	select {
+	case err := <-done:
+		t.Fatalf("the run ended with %v after the window was reset", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	second()
+	err := waitForRun(t, done)
+	if err == nil || errors.Is(err, ErrInterrupted) || err.Error() != "no line matched for --no-match-timeout=2m0s since the last match" {
+		t.Fatalf("got %v, want the run failed by --no-match-timeout", err)
+	}
+}
+
+func TestNoMatchTimeoutAbsolute(t *testing.T) {
+	scheduled := fakeNoMatchClock(t)
+	writer, done := runNoMatchTimeout(t, "--no-match-timeout-mode", "absolute")
+	expire := <-scheduled
+
+	io.WriteString(writer, "INFO starting\n")
+	expire()
+	err := waitForRun(t, done)
+	if !errors.Is(err, ErrNoMatches) || errors.Is(err, ErrInterrupted) || err.Error() != "no line matched within --no-match-timeout=2m0s" {
+		t.Fatalf("got %v, want the run failed by --no-match-timeout", err)
+	}
+}
+
+func TestNoMatchTimeoutAbsoluteStopsAtFirstMatch(t *testing.T) {
+	scheduled := fakeNoMatchClock(t)
+	writer, done := runNoMatchTimeout(t, "--no-match-timeout-mode", "absolute")
+	expire := <-scheduled
+
+	io.WriteString(writer, "ERROR one\n")
+	// read once the first line was matched
+	io.WriteString(writer, "INFO waiting\n")
+	expire()
+	writer.Close()
+	if err := waitForRun(t, done); err != nil {
+		t.Fatalf("got %v, want the run to end with the logs", err)
+	}
+	select {
+	case <-scheduled:
+		t.Error("a window was started after the first match")
+	default:
+	}
+}
+
+func TestNoMatchTimeoutValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--no-match-timeout", "2m"},
+		{"-f", "--no-match-timeout", "-1s"},
+		{"-f", "--no-match-timeout", "2m", "--no-match-timeout-mode", "sliding"},
+		{"-f", "--no-match-timeout-mode", "absolute"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("%v: expected an error", flags)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpLogsPath = "/v1/logs"
+	// the defaults of the OTel SDKs for the batch log record processor and the exporters
+	defaultOTLPBatchSize     = 512
+	defaultOTLPScheduleDelay = time.Second
+	defaultOTLPTimeout       = 10 * time.Second
+	otlpScopeName            = "kubectl-like"
+)
+
+// otlpSeverities are the severity numbers of the OTel log data model
+var otlpSeverities = map[severity]int{
+	severityTrace:   1,
+	severityDebug:   5,
+	severityInfo:    9,
+	severityWarning: 13,
+	severityError:   17,
+	severityFatal:   21,
+}
+
+var otlpSeverityTexts = map[severity]string{
+	severityTrace:   "TRACE",
+	severityDebug:   "DEBUG",
+	severityInfo:    "INFO",
+	severityWarning: "WARN",
+	severityError:   "ERROR",
+	severityFatal:   "FATAL",
+}
+
+// otlpExporter exports the matching lines as OTLP log records over HTTP, encoded as JSON, with a resource
+// per container. It is configured by the standard OTEL_EXPORTER_OTLP_* and OTEL_BLRP_* environment variables.
+type otlpExporter struct {
+	url      string
+	headers  map[string]string
+	gzip     bool
+	client   *http.Client
+	severity severityParser
+	// batch is the batch of the notify sink, from OTEL_BLRP_MAX_EXPORT_BATCH_SIZE and OTEL_BLRP_SCHEDULE_DELAY
+	batch notifyBatch
+}
+
+// otelEnv returns the variable OTEL_EXPORTER_OTLP_LOGS_<name>, or OTEL_EXPORTER_OTLP_<name> when it is not set
+func otelEnv(name string) string {
+	if v, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_LOGS_" + name); ok {
+		return v
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+}
+
+// otelMilliseconds parses the variable as a number of milliseconds, or returns def when it is not set
+func otelMilliseconds(variable, value string, def time.Duration) (time.Duration, error) {
+	if len(value) == 0 {
+		return def, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, must be a number of milliseconds", variable, value)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// newOTLPExporter returns the exporter of --otlp-endpoint, given as HOST:PORT or as a URL. HOST:PORT is
+// reached over https, or http when OTEL_EXPORTER_OTLP_INSECURE is true, and a URL without a path is
+// completed with /v1/logs.
+func newOTLPExporter(endpoint string, severity severityParser) (*otlpExporter, error) {
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https"
+		if insecure, _ := strconv.ParseBool(otelEnv("INSECURE")); insecure {
+			scheme = "http"
+		}
+		endpoint = scheme + "://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --otlp-endpoint %q, must be HOST:PORT or an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpLogsPath
+	}
+
+	e := &otlpExporter{url: u.String(), headers: map[string]string{}, severity: severity}
+	// OTEL_EXPORTER_OTLP_HEADERS is a list of KEY=VALUE, with URL encoded values
+	for _, header := range strings.Split(otelEnv("HEADERS"), ",") {
+		if len(strings.TrimSpace(header)) == 0 {
+			continue
+		}
+		key, value, found := strings.Cut(header, "=")
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if !found || err != nil || len(strings.TrimSpace(key)) == 0 {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS %q, must be KEY=VALUE,...", header)
+		}
+		e.headers[strings.TrimSpace(key)] = value
+	}
+	switch compression := otelEnv("COMPRESSION"); compression {
+	case "", "none":
+	case "gzip":
+		e.gzip = true
+	default:
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_COMPRESSION %q, must be gzip or none", compression)
+	}
+	timeout, err := otelMilliseconds("OTEL_EXPORTER_OTLP_TIMEOUT", otelEnv("TIMEOUT"), defaultOTLPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if certificate := otelEnv("CERTIFICATE"); len(certificate) > 0 {
+		pem, err := os.ReadFile(certificate)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read OTEL_EXPORTER_OTLP_CERTIFICATE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in OTEL_EXPORTER_OTLP_CERTIFICATE %s", certificate)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	e.client = &http.Client{Timeout: timeout, Transport: transport}
+
+	e.batch = notifyBatch{size: defaultOTLPBatchSize}
+	if size := os.Getenv("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"); len(size) > 0 {
+		if e.batch.size, err = strconv.Atoi(size); err != nil || e.batch.size < 1 {
+			return nil, fmt.Errorf("invalid OTEL_BLRP_MAX_EXPORT_BATCH_SIZE %q, must be a positive number", size)
+		}
+	}
+	e.batch.interval, err = otelMilliseconds("OTEL_BLRP_SCHEDULE_DELAY", os.Getenv("OTEL_BLRP_SCHEDULE_DELAY"), defaultOTLPScheduleDelay)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// the OTLP/JSON encoding of the export request, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	// the 64-bit integers are strings in JSON
+	TimeUnixNano         string          `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber,omitempty"`
+	SeverityText         string          `json:"severityText,omitempty"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+// logsRequest groups the matching lines in a resource per container, in the order of their first line
+func (e *otlpExporter) logsRequest(matches []notification) otlpLogsRequest {
+	var request otlpLogsRequest
+	index := map[notification]int{}
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for _, match := range matches {
+		key := notification{Context: match.Context, Namespace: match.Namespace, Pod: match.Pod, Container: match.Container}
+		i, ok := index[key]
+		if !ok {
+			i = len(request.ResourceLogs)
+			index[key] = i
+			request.ResourceLogs = append(request.ResourceLogs, otlpResourceLogs{
+				Resource: otlpResource{Attributes: []otlpAttribute{
+					otlpString("k8s.namespace.name", match.Namespace),
+					otlpString("k8s.pod.name", match.Pod),
+					otlpString("k8s.container.name", match.Container),
+				}},
+				ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: otlpScopeName}}},
+			})
+		}
+		record := otlpLogRecord{ObservedTimeUnixNano: observed, Body: otlpValue{StringValue: match.Line}}
+		if t, err := time.Parse(time.RFC3339Nano, match.Timestamp); err == nil {
+			record.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+		}
+		if e.severity != nil {
+			s := e.severity([]byte(match.Line))
+			record.SeverityNumber, record.SeverityText = otlpSeverities[s], otlpSeverityTexts[s]
+		}
+		if len(match.Pattern) > 0 {
+			record.Attributes = []otlpAttribute{otlpString("log.pattern", match.Pattern)}
+		}
+		scope := &request.ResourceLogs[i].ScopeLogs[0]
+		scope.LogRecords = append(scope.LogRecords, record)
+	}
+	return request
+}
+
+func (e *otlpExporter) Notify(ctx context.Context, payload notifyPayload) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var gz *gzip.Writer
+	if e.gzip {
+		gz = gzip.NewWriter(&body)
+		w = gz
+	}
+	if err := json.NewEncoder(w).Encode(e.logsRequest(payload.Matches)); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, &body)
+	if err != nil {
+		return err
+	}
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if gz != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the OTLP endpoint returned %s", resp.Status)
+	}
+	return nil
+}
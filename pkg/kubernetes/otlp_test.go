@@ -0,0 +1,172 @@
+package kubernetes
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// otlpCollector is an OTLP/HTTP collector stub keeping the export requests it receives in memory
+type otlpCollector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []otlpLogsRequest
+	headers  []http.Header
+}
+
+func newOTLPCollector(t *testing.T) *otlpCollector {
+	t.Helper()
+	c := &otlpCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpLogsPath || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got a request to %s with Content-Type %q", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("invalid gzip body: %v", err)
+				return
+			}
+			body = gz
+		}
+		var request otlpLogsRequest
+		if err := json.NewDecoder(body).Decode(&request); err != nil {
+			t.Errorf("invalid export request: %v", err)
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.requests = append(c.requests, request)
+		c.headers = append(c.headers, r.Header.Clone())
+		io.WriteString(w, "{}")
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func newTestOTLPExporter(t *testing.T, endpoint string) *otlpExporter {
+	t.Helper()
+	severity, err := newSeverityParser("auto", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := newOTLPExporter(endpoint, severity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestOTLPExport(t *testing.T) {
+	collector := newOTLPCollector(t)
+	logs := map[string]string{
+		"api-1": "2024-06-12T10:04:05Z INFO starting\n2024-06-12T10:04:06Z level=error msg=boom\n",
+		"api-2": "2024-06-12T10:04:07Z WARN almost full error budget\n",
+	}
+	l, requests := newOutputOptions(t, outputText, "error", logs)
+	l.Pattern = "error"
+	l.Timestamps = true
+	exporter := newTestOTLPExporter(t, collector.URL)
+	sink := newNotifySink(exporter, "--otlp-endpoint", notifyBatch{size: 10}, time.Millisecond, io.Discard, nil)
+	l.notifySinks = []*notifySink{sink}
+	l.Out = io.Discard
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.requests) != 1 {
+		t.Fatalf("got %d export requests, want 1", len(collector.requests))
+	}
+	records := map[string]otlpLogRecord{}
+	for _, resource := range collector.requests[0].ResourceLogs {
+		attributes := map[string]string{}
+		for _, attribute := range resource.Resource.Attributes {
+			attributes[attribute.Key] = attribute.Value.StringValue
+		}
+		pod := attributes["k8s.pod.name"]
+		if want := map[string]string{"k8s.namespace.name": "test", "k8s.pod.name": pod, "k8s.container.name": "app"}; !reflect.DeepEqual(attributes, want) {
+			t.Errorf("got resource attributes %v, want %v", attributes, want)
+		}
+		if len(resource.ScopeLogs) != 1 || resource.ScopeLogs[0].Scope.Name != otlpScopeName || len(resource.ScopeLogs[0].LogRecords) != 1 {
+			t.Fatalf("got scope logs %+v, want a record of %s", resource.ScopeLogs, otlpScopeName)
+		}
+		records[pod] = resource.ScopeLogs[0].LogRecords[0]
+	}
+	for pod, want := range map[string]otlpLogRecord{
+		"api-1": {TimeUnixNano: "1718186646000000000", SeverityNumber: 17, SeverityText: "ERROR", Body: otlpValue{StringValue: "level=error msg=boom"}},
+		"api-2": {TimeUnixNano: "1718186647000000000", SeverityNumber: 13, SeverityText: "WARN", Body: otlpValue{StringValue: "WARN almost full error budget"}},
+	} {
+		got := records[pod]
+		if got.ObservedTimeUnixNano == "" {
+			t.Errorf("%s: the record has no observed time", pod)
+		}
+		if want.Attributes = []otlpAttribute{otlpString("log.pattern", "error")}; !reflect.DeepEqual(got.Attributes, want.Attributes) {
+			t.Errorf("%s: got attributes %v, want %v", pod, got.Attributes, want.Attributes)
+		}
+		got.ObservedTimeUnixNano, got.Attributes, want.Attributes = "", nil, nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got record %+v, want %+v", pod, got, want)
+		}
+	}
+}
+
+func TestOTLPEnvironment(t *testing.T) {
+	collector := newOTLPCollector(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret%3D1,x-tenant=team")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_COMPRESSION", "gzip")
+	t.Setenv("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE", "2")
+	t.Setenv("OTEL_BLRP_SCHEDULE_DELAY", "250")
+	exporter := newTestOTLPExporter(t, strings.TrimPrefix(collector.URL, "http://"))
+	if want := (notifyBatch{size: 2, interval: 250 * time.Millisecond}); exporter.batch != want {
+		t.Errorf("got batch %+v, want %+v", exporter.batch, want)
+	}
+
+	s := newNotifySink(exporter, "--otlp-endpoint", exporter.batch, time.Millisecond, io.Discard, nil)
+	io.WriteString(s.writer(notification{Namespace: "test", Pod: "api-1", Container: "app"}, false, io.Discard), "one\ntwo\n")
+	s.Close()
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.requests) != 1 {
+		t.Fatalf("got %d export requests, want 1", len(collector.requests))
+	}
+	header := collector.headers[0]
+	if header.Get("Api-Key") != "secret=1" || header.Get("X-Tenant") != "team" || header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("got headers %v", header)
+	}
+	if records := collector.requests[0].ResourceLogs[0].ScopeLogs[0].LogRecords; len(records) != 2 || records[0].SeverityNumber != 0 {
+		t.Errorf("got records %+v, want 2 without severity", records)
+	}
+}
+
+func TestOTLPEndpointValidation(t *testing.T) {
+	for _, endpoint := range []string{"ftp://collector:4318", "http://"} {
+		if _, err := newOTLPExporter(endpoint, nil); err == nil {
+			t.Errorf("expected %q to be rejected", endpoint)
+		}
+	}
+	e, err := newOTLPExporter("collector:4318", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://collector:4318/v1/logs"; e.url != want {
+		t.Errorf("got %s, want %s", e.url, want)
+	}
+	t.Setenv("OTEL_BLRP_SCHEDULE_DELAY", "soon")
+	if _, err := newOTLPExporter("collector:4318", nil); err == nil {
+		t.Error("expected an invalid OTEL_BLRP_SCHEDULE_DELAY to be rejected")
+	}
+}
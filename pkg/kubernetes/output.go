@@ -0,0 +1,378 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	outputText       = "text"
+	outputJSON       = "json"
+	outputGoTemplate = "go-template"
+	outputCSV        = "csv"
+	outputTSV        = "tsv"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputGoTemplate, outputCSV, outputTSV}
+
+// recordColumns are the columns of -o csv and -o tsv, besides the named capture groups of the pattern
+var recordColumns = []string{"context", "namespace", "pod", "container", "timestamp", "line", "raw"}
+
+// defaultRecordColumns are written when --columns is not given, followed by the named capture groups
+var defaultRecordColumns = []string{"namespace", "pod", "container", "timestamp", "line"}
+
+// MatchRecord is a matching line with its source, as written by -o json and passed to -o go-template
+type MatchRecord struct {
+	Context   string            `json:"context,omitempty"`
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Container string            `json:"container"`
+	Timestamp string            `json:"timestamp,omitempty"`
+	Line      string            `json:"line"`
+	Groups    map[string]string `json:"groups,omitempty"`
+	// Raw is the line as received, including the timestamp
+	Raw string `json:"raw"`
+	// Matches are the byte offsets of the matches of the pattern in Line
+	Matches []MatchRange `json:"matches,omitempty"`
+	// LineNumber is the number of the line among the lines printed for its container, from 1, and Match the
+	// text of the first match of the pattern in Line. They are only set for the templates, -o json keeps its fields.
+	LineNumber int    `json:"-"`
+	Match      string `json:"-"`
+}
+
+// MatchRange is the byte offset of a match in a line, End excluded
+type MatchRange struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+const matchRecordFields = "Context, Namespace, Pod, Container, Timestamp, Line, Groups, Raw, Matches, LineNumber, Match"
+
+// recordWriter writes every line written to it as an encoded MatchRecord on its own line
+type recordWriter struct {
+	l      LikeOptions
+	source MatchRecord
+	writer io.Writer
+	encode func(MatchRecord) ([]byte, error)
+	// lines counts the lines written, the streams of a container being read one after the other
+	lines int
+}
+
+func (l LikeOptions) newRecordWriter(ref corev1.ObjectReference, writer io.Writer) io.Writer {
+	_, container := l.containerFromRef(ref)
+	rw := &recordWriter{
+		l: l,
+		source: MatchRecord{
+			Context:   l.contextName,
+			Namespace: ref.Namespace,
+			Pod:       ref.Name,
+			Container: container,
+		},
+		writer: writer,
+		encode: l.encodeJSON,
+	}
+	switch l.Output {
+	case outputGoTemplate:
+		rw.encode = l.executeTemplate
+	case outputCSV, outputTSV:
+		rw.encode = l.encodeCSV
+	}
+	return rw
+}
+
+func (rw *recordWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	record := rw.source
+	line := bytes.TrimRight(p, "\r\n")
+	record.Raw = string(line)
+	if rw.l.Timestamps {
+		if t, rest, ok := splitTimestamp(line); ok {
+			record.Timestamp = t.Format(time.RFC3339Nano)
+			line = rest
+		}
+	}
+	record.Line = string(line)
+	record.Groups = rw.l.captureGroups(line)
+	record.Matches = rw.l.matchRanges(line)
+	rw.lines++
+	record.LineNumber = rw.lines
+	if len(record.Matches) > 0 {
+		record.Match = record.Matches[0].Text
+	}
+
+	b, err := rw.encode(record)
+	if err != nil {
+		return 0, err
+	}
+	// a single write per line, so that lines of concurrent streams don't interleave
+	if _, err := rw.writer.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l LikeOptions) encodeJSON(record MatchRecord) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func (l LikeOptions) executeTemplate(record MatchRecord) ([]byte, error) {
+	var b bytes.Buffer
+	if err := l.template.Execute(&b, record); err != nil {
+		return nil, fmt.Errorf("error executing template %q: %w, the fields are %s", l.Template, err, matchRecordFields)
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// nullWriter ends every line written to it with a NUL byte instead of its line break, for --null.
+// Every write is a whole line or record, as the lines of concurrent streams are written at once, so the
+// line breaks inside a record, e.g. of a template, are kept.
+type nullWriter struct {
+	writer io.Writer
+}
+
+func (nw *nullWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	record := bytes.TrimSuffix(bytes.TrimSuffix(p, []byte("\n")), []byte("\r"))
+	if _, err := nw.writer.Write(append(record[:len(record):len(record)], 0)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// usesRecords tells whether every line is written as an encoded MatchRecord rather than as text
+func (l LikeOptions) usesRecords() bool {
+	return l.Output != outputText
+}
+
+// completeColumns checks --columns, or defaults them to the usual columns and the named capture groups
+func (l *LikeOptions) completeColumns() error {
+	if l.Output != outputCSV && l.Output != outputTSV {
+		return nil
+	}
+	var groups []string
+	if l.patternRegexp != nil {
+		for _, name := range l.patternRegexp.SubexpNames() {
+			if name != "" {
+				groups = append(groups, name)
+			}
+		}
+	}
+	if len(l.Columns) == 0 {
+		l.Columns = append(append([]string{}, defaultRecordColumns...), groups...)
+		return nil
+	}
+	for _, column := range l.Columns {
+		if !slices.Contains(recordColumns, column) && !slices.Contains(groups, column) {
+			return fmt.Errorf("unknown column %q, must be one of %s or a named capture group of the pattern", column, strings.Join(recordColumns, ", "))
+		}
+	}
+	return nil
+}
+
+// recordValue returns the value of a column of -o csv for the record
+func recordValue(record MatchRecord, column string) string {
+	switch column {
+	case "context":
+		return record.Context
+	case "namespace":
+		return record.Namespace
+	case "pod":
+		return record.Pod
+	case "container":
+		return record.Container
+	case "timestamp":
+		return record.Timestamp
+	case "line":
+		return record.Line
+	case "raw":
+		return record.Raw
+	default:
+		return record.Groups[column]
+	}
+}
+
+// newCSVWriter returns a CSV writer, separating the fields with tabs for -o tsv
+func (l LikeOptions) newCSVWriter(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if l.Output == outputTSV {
+		cw.Comma = '\t'
+	}
+	return cw
+}
+
+func (l LikeOptions) encodeCSV(record MatchRecord) ([]byte, error) {
+	row := make([]string, len(l.Columns))
+	for i, column := range l.Columns {
+		row[i] = recordValue(record, column)
+	}
+	var b bytes.Buffer
+	cw := l.newCSVWriter(&b)
+	if err := cw.Write(row); err != nil {
+		return nil, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// printRecordHeader writes the header row of -o csv and -o tsv once, before the lines are streamed
+func (l LikeOptions) printRecordHeader() error {
+	if l.Output != outputCSV && l.Output != outputTSV {
+		return nil
+	}
+	cw := l.newCSVWriter(l.Out)
+	if err := cw.Write(l.Columns); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// patternSubject returns the part of the line the pattern is matched against, and its offset in the line
+func (l LikeOptions) patternSubject(line []byte) ([]byte, int) {
+	subject := line
+	// the pattern is matched against the message of klog lines. The line may start with the timestamp
+	// of the kubelet, or have it already split off like in the records of -o.
+	if l.Klog {
+		entry, ok := parseKlogLine(line, false)
+		if !ok && l.Timestamps {
+			entry, ok = parseKlogLine(line, true)
+		}
+		if ok {
+			subject = entry.message
+		}
+	}
+	if l.matchColumns != nil {
+		subject = l.matchColumns.columns(subject, l.MatchRunes)
+	}
+	// the subject is a subslice of the line, so the difference of their capacities is its offset
+	return subject, cap(line) - cap(subject)
+}
+
+// captureGroups returns the values of the capture groups of the pattern in the line, by name or by index
+// for unnamed groups
+func (l LikeOptions) captureGroups(line []byte) map[string]string {
+	if l.patternRegexp == nil || l.patternRegexp.NumSubexp() == 0 {
+		return nil
+	}
+	subject, _ := l.patternSubject(line)
+	match := l.regexpFindSubmatch(l.patternRegexp, subject)
+	if match == nil {
+		return nil
+	}
+	groups := map[string]string{}
+	for i, name := range l.patternRegexp.SubexpNames() {
+		if i == 0 || match[i] == nil {
+			continue
+		}
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		groups[name] = string(match[i])
+	}
+	return groups
+}
+
+// matchRanges returns the byte offsets in the line of every match of the pattern
+func (l LikeOptions) matchRanges(line []byte) []MatchRange {
+	if l.patternRegexp == nil {
+		return nil
+	}
+	subject, offset := l.patternSubject(line)
+	var ranges []MatchRange
+	for _, loc := range l.regexpFindAllIndex(l.patternRegexp, subject) {
+		ranges = append(ranges, MatchRange{
+			Start: offset + loc[0],
+			End:   offset + loc[1],
+			Text:  string(subject[loc[0]:loc[1]]),
+		})
+	}
+	return ranges
+}
+
+var templateColors = map[string]string{
+	"bold":    "1",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+}
+
+// templateFuncs returns the functions available in -o go-template
+func (l LikeOptions) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// color wraps text in the ANSI escape codes of a color, e.g. {{color "red" .Line}}, if colors are written
+		"color": func(name, text string) (string, error) {
+			code, ok := templateColors[name]
+			if !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			return l.sgr(code, text), nil
+		},
+		// trunc keeps the first n characters of text, e.g. {{trunc 80 .Line}}
+		"trunc": func(n int, text string) string {
+			runes := []rune(text)
+			if n < 0 || len(runes) <= n {
+				return text
+			}
+			return string(runes[:n])
+		},
+		// date formats an RFC3339 timestamp with a Go layout, e.g. {{date "15:04:05" .Timestamp}}
+		"date": func(layout, timestamp string) string {
+			t, err := time.Parse(time.RFC3339Nano, timestamp)
+			if err != nil {
+				return timestamp
+			}
+			return t.Format(layout)
+		},
+	}
+}
+
+// parseOutputTemplate parses the template of -o go-template, given with --template, as -o go-template=TEMPLATE
+// or with --output-template
+func (l *LikeOptions) parseOutputTemplate() error {
+	flag := "--template"
+	if format, text, found := strings.Cut(l.Output, "="); found && format == outputGoTemplate {
+		l.Output = outputGoTemplate
+		l.Template = text
+	}
+	if len(l.OutputTemplate) > 0 {
+		if l.Output != outputText && l.Output != outputGoTemplate || len(l.Template) > 0 {
+			return fmt.Errorf("--output-template cannot be used with --template or -o %s", l.Output)
+		}
+		flag = "--output-template"
+		l.Output = outputGoTemplate
+		l.Template = l.OutputTemplate
+	}
+	if l.Output != outputGoTemplate {
+		return nil
+	}
+	if len(l.Template) == 0 {
+		return fmt.Errorf("-o go-template requires a template, e.g. --template '{{.Pod}} {{.Line}}'")
+	}
+	var err error
+	l.template, err = template.New("output").Funcs(l.templateFuncs()).Option("missingkey=error").Parse(l.Template)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", flag, err)
+	}
+	return nil
+}
@@ -0,0 +1,420 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+// newOutputOptions returns options matching pattern with -o output, and the log requests of pods logging the given lines
+func newOutputOptions(t *testing.T, output, pattern string, logs map[string]string) (LikeOptions, map[corev1.ObjectReference]rest.ResponseWrapper) {
+	t.Helper()
+	api := &fakeAPI{objects: map[string]runtime.Object{}, logs: map[string]string{}}
+	for pod, log := range logs {
+		object := testPod(pod, corev1.PodRunning, nil)
+		api.objects["/namespaces/test/pods/"+pod] = &object
+		api.logs["/namespaces/test/pods/"+pod+"/log"] = log
+	}
+	l, _, _ := newFakeOptions(t, api)
+	l.Output = output
+	l.Options = &corev1.PodLogOptions{Container: "app"}
+	l.patternRegexp = regexp.MustCompile(pattern)
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	l.PerSourceBuffer = defaultPerSourceBuffer
+	l.MaxFollowConcurrency = 5
+
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[corev1.ObjectReference]rest.ResponseWrapper{}
+	for pod := range logs {
+		ref := corev1.ObjectReference{Namespace: "test", Name: pod, FieldPath: "spec.containers{app}"}
+		requests[ref] = clientset.CoreV1().Pods("test").GetLogs(pod, &corev1.PodLogOptions{Container: "app"})
+	}
+	return l, requests
+}
+
+// decodeRecords decodes every line of out as a MatchRecord, failing on unknown fields
+// and on a missing namespace, pod, container or line
+func decodeRecords(t *testing.T, out string) []MatchRecord {
+	t.Helper()
+	var records []MatchRecord
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("invalid JSON %q: %v", scanner.Text(), err)
+		}
+		for _, required := range []string{"namespace", "pod", "container", "line", "raw"} {
+			if _, ok := fields[required]; !ok {
+				t.Errorf("%q has no %s", scanner.Text(), required)
+			}
+		}
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		var record MatchRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("%q is not a record: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONOutputSingleSource(t *testing.T) {
+	l, requests := newOutputOptions(t, outputJSON, `ERROR (?P<code>\d+)`, map[string]string{
+		"api-1": "2024-06-12T10:04:05.123456789Z INFO ok\n2024-06-12T10:04:06Z ERROR 503 \"upstream\"\tdown\n",
+	})
+	l.Timestamps = true
+	// records are never colored
+	l.colorize = true
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("the JSON output is colored: %q", out.String())
+	}
+	records := decodeRecords(t, out.String())
+	want := MatchRecord{
+		Namespace: "test",
+		Pod:       "api-1",
+		Container: "app",
+		Timestamp: "2024-06-12T10:04:06Z",
+		Line:      "ERROR 503 \"upstream\"\tdown",
+		Groups:    map[string]string{"code": "503"},
+		Raw:       "2024-06-12T10:04:06Z ERROR 503 \"upstream\"\tdown",
+		Matches:   []MatchRange{{Start: 0, End: 9, Text: "ERROR 503"}},
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1: %q", len(records), out.String())
+	}
+	got, _ := json.Marshal(records[0])
+	expected, _ := json.Marshal(want)
+	if !bytes.Equal(got, expected) {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
+
+func TestJSONOutputMultiSource(t *testing.T) {
+	l, requests := newOutputOptions(t, outputJSON, "ERROR", map[string]string{
+		"api-1": "ERROR one\nINFO ok\nERROR two\n",
+		"api-2": "INFO ok\nERROR three\n",
+	})
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.parallelConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, record := range decodeRecords(t, out.String()) {
+		if record.Timestamp != "" || record.Context != "" {
+			t.Errorf("unexpected timestamp or context in %+v", record)
+		}
+		got = append(got, record.Pod+" "+record.Line)
+	}
+	slices.Sort(got)
+	want := []string{"api-1 ERROR one", "api-1 ERROR two", "api-2 ERROR three"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputTemplateErrorsBeforeStreaming(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		want  string
+	}{
+		{name: "missing template", flags: []string{"-o", "go-template"}, want: "requires a template"},
+		{name: "unclosed action", flags: []string{"-o", "go-template", "--template", "{{.Pod"}, want: "invalid --template"},
+		{name: "unknown function", flags: []string{"-o", "go-template={{upper .Line}}"}, want: "invalid --template"},
+		{name: "unclosed output template", flags: []string{"--output-template", "{{.Pod"}, want: "invalid --output-template"},
+		{name: "output template with json", flags: []string{"-o", "json", "--output-template", "{{.Line}}"}, want: "--output-template cannot be used with --template or -o json"},
+		{name: "output template with template", flags: []string{"--template", "{{.Pod}}", "--output-template", "{{.Line}}"}, want: "--output-template cannot be used"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := newMeshAPI()
+			l, cmd, _, _ := newFakeCommand(t, api)
+			err := completeFlags(l, cmd, append(test.flags, "-l", "app=api"))
+			if err == nil {
+				err = l.Vaildate()
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+			if logs := api.requests("/namespaces/test/pods/api-1/log"); len(logs) > 0 {
+				t.Errorf("the logs were requested despite the invalid template: %q", logs)
+			}
+		})
+	}
+}
+
+func TestOutputTemplateMultiSource(t *testing.T) {
+	l, requests := newOutputOptions(t, outputGoTemplate, `ERROR (?P<code>\d+)`, map[string]string{
+		"api-1": "ERROR 500 boom\nINFO ok\n",
+		"api-2": "ERROR 503 a very long line\n",
+	})
+	l.Template = `{{.Pod}}/{{.Container}} {{index .Groups "code"}} {{trunc 9 .Line}}`
+	if err := l.parseOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.parallelConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	slices.Sort(lines)
+	want := []string{"api-1/app 500 ERROR 500", "api-2/app 503 ERROR 503"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestOutputTemplateLineNumberAndMatch(t *testing.T) {
+	l, requests := newOutputOptions(t, outputText, `ERROR \d+`, map[string]string{"api-1": "ERROR 500 boom\nINFO ok\nERROR 503 again\n"})
+	l.OutputTemplate = `{{.LineNumber}} {{.Pod}}/{{.Container}}: {{.Match}}`
+	if err := l.parseOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "1 api-1/app: ERROR 500\n2 api-1/app: ERROR 503\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputTemplateMissingField(t *testing.T) {
+	l, requests := newOutputOptions(t, outputGoTemplate, "ERROR", map[string]string{"api-1": "ERROR boom\n"})
+	l.Template = "{{.Message}}"
+	if err := l.parseOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Out = &bytes.Buffer{}
+
+	err := l.sequentialConsumeRequest(requests)
+	if err == nil || !strings.Contains(err.Error(), "the fields are "+matchRecordFields) {
+		t.Errorf("expected an error listing the fields, got %v", err)
+	}
+}
+
+func TestOutputTemplateFuncs(t *testing.T) {
+	l, _ := newOutputOptions(t, outputGoTemplate, "ERROR", nil)
+	record := MatchRecord{Pod: "api-1", Line: "ERROR boom", Timestamp: "2024-06-12T10:04:05.5Z"}
+	tests := []struct {
+		template string
+		colorize bool
+		want     string
+	}{
+		{template: `{{date "15:04:05" .Timestamp}} {{.Line}}`, want: "10:04:05 ERROR boom"},
+		{template: `{{date "15:04" "not a time"}}`, want: "not a time"},
+		{template: `{{trunc 5 .Line}}|{{trunc 50 .Line}}`, want: "ERROR|ERROR boom"},
+		{template: `{{color "red" .Pod}}`, want: "api-1"},
+		{template: `{{color "red" .Pod}}`, colorize: true, want: "\x1b[31mapi-1\x1b[0m"},
+	}
+	for _, test := range tests {
+		l.Template = test.template
+		l.colorize = test.colorize
+		if err := l.parseOutputTemplate(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := l.executeTemplate(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestCSVOutputQuotesFields(t *testing.T) {
+	l, requests := newOutputOptions(t, outputCSV, `status=(?P<status>\d+)`, map[string]string{
+		"api-1": "INFO ok\nGET /a,b status=500 msg=\"failed, retrying\"\n",
+	})
+	if err := l.completeColumns(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.printRecordHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	want := "namespace,pod,container,timestamp,line,status\n" +
+		`test,api-1,app,,"GET /a,b status=500 msg=""failed, retrying""",500` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCSVOutputColumns(t *testing.T) {
+	l, _ := newOutputOptions(t, outputTSV, `status=(?P<status>\d+)`, nil)
+	l.Columns = []string{"pod", "status", "raw"}
+	if err := l.completeColumns(); err != nil {
+		t.Fatal(err)
+	}
+	record := MatchRecord{Pod: "api-1", Line: "status=500\tfailed", Raw: "status=500\tfailed", Groups: map[string]string{"status": "500"}}
+	got, err := l.encodeCSV(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "api-1\t500\t\"status=500\tfailed\""; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	l.Columns = []string{"pod", "level"}
+	if err := l.completeColumns(); err == nil || !strings.Contains(err.Error(), `unknown column "level"`) {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestCSVOutputQuotesNewlines(t *testing.T) {
+	l, _ := newOutputOptions(t, outputCSV, "ERROR", nil)
+	if err := l.completeColumns(); err != nil {
+		t.Fatal(err)
+	}
+	// e.g. a stack trace whose lines were joined into a single record
+	record := MatchRecord{Namespace: "test", Pod: "api-1", Container: "app", Line: "ERROR boom\n\tat main.go:12"}
+	got, err := l.encodeCSV(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "test,api-1,app,,\"ERROR boom\n\tat main.go:12\""; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// a CSV reader reads the record back as a single row
+	rows, err := csv.NewReader(bytes.NewReader(append(got, '\n'))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][4] != record.Line {
+		t.Errorf("got rows %q, want a single row with the line %q", rows, record.Line)
+	}
+}
+
+func TestPatternSubjectOfKlogLines(t *testing.T) {
+	l, _ := newOutputOptions(t, outputText, `\d+`, nil)
+	l.Klog = true
+	l.OnlyMatching = true
+	header := "E0612 10:04:05.123456       1 main.go:12] "
+
+	// without --timestamps, the header is skipped
+	if got := joinLines(l.outputLines([]byte(header + "retry 3 of 5\n"))); got != "3\n5\n" {
+		t.Errorf("got %q, want the numbers of the message", got)
+	}
+
+	// with --timestamps, the timestamp of the kubelet is skipped too, whether it is still in the line or not
+	l.Timestamps = true
+	for _, line := range []string{"2024-06-12T10:04:05.123456789Z " + header + "retry 3 of 5\n", header + "retry 3 of 5\n"} {
+		if got := joinLines(l.outputLines([]byte(line))); got != "3\n5\n" {
+			t.Errorf("%q: got %q, want the numbers of the message", line, got)
+		}
+	}
+	// the offsets are those of the line, timestamp included
+	line := []byte("2024-06-12T10:04:05.123456789Z " + header + "retry 3")
+	ranges := l.matchRanges(line)
+	if len(ranges) != 1 || string(line[ranges[0].Start:ranges[0].End]) != "3" {
+		t.Errorf("got ranges %+v, want the offset of 3 in the line", ranges)
+	}
+}
+
+func TestJSONOutputOfKlogLines(t *testing.T) {
+	l, requests := newOutputOptions(t, outputJSON, `retry (?P<attempt>\d+)`, map[string]string{
+		"api-1": "2024-06-12T10:04:05Z E0612 10:04:05.123456       1 main.go:12] retry 3 of 5\n",
+	})
+	l.Klog = true
+	l.Timestamps = true
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	records := decodeRecords(t, out.String())
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1: %q", len(records), out.String())
+	}
+	record := records[0]
+	if record.Groups["attempt"] != "3" || len(record.Matches) != 1 || record.Line[record.Matches[0].Start:record.Matches[0].End] != "retry 3" {
+		t.Errorf("got groups %v and matches %+v in %q", record.Groups, record.Matches, record.Line)
+	}
+}
+
+func TestNullTerminatesRecords(t *testing.T) {
+	l, requests := newOutputOptions(t, outputGoTemplate, `ERROR`, map[string]string{
+		"api-1": "ERROR 500 boom\nINFO ok\nERROR 503 no trailing line break",
+	})
+	// a record spanning several lines keeps its inner line breaks
+	l.Template = "{{.Pod}}:\n  {{.Line}}"
+	if err := l.parseOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	l.Out = &nullWriter{writer: &out}
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	want := "api-1:\n  ERROR 500 boom\x00api-1:\n  ERROR 503 no trailing line break\x00"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNullTerminatesLines(t *testing.T) {
+	l, requests := newOutputOptions(t, outputText, `ERROR`, map[string]string{
+		"api-1": "ERROR 500 boom\r\nINFO ok\nERROR 503 no trailing line break",
+	})
+	var out bytes.Buffer
+	l.Out = &nullWriter{writer: &out}
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR 500 boom\x00ERROR 503 no trailing line break\x00"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNullValidation(t *testing.T) {
+	for _, output := range []string{outputJSON, outputCSV, outputTSV} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, []string{"-0", "-o", output}, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected -0 with -o %s to be rejected", output)
+		}
+	}
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--null"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil || !l.Null {
+		t.Errorf("got %v, want --null to be accepted", err)
+	}
+}
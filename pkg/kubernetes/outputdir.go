@@ -0,0 +1,192 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// outputFlushInterval is how often the files of --output-dir, --output-file and --capture-raw are flushed to disk
+	outputFlushInterval = time.Second
+	// maxOutputFilenameLength keeps file names below the 255 bytes limit of most filesystems
+	maxOutputFilenameLength = 240
+)
+
+var unsafeFilenameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// outputDir writes the lines of every source to its own file in a directory.
+// Files are only created when the first line is written to them.
+type outputDir struct {
+	dir     string
+	summary io.Writer
+
+	mu    sync.Mutex
+	files map[string]*outputFile
+	done  chan struct{}
+}
+
+// outputFile is the lazily created file of a single source
+type outputFile struct {
+	source string
+	path   string
+
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	lines int
+}
+
+// newOutputDir creates dir if needed. The summary of the written files goes to summary when closed.
+func newOutputDir(dir string, summary io.Writer) (*outputDir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	o := &outputDir{
+		dir:     dir,
+		summary: summary,
+		files:   map[string]*outputFile{},
+		done:    make(chan struct{}),
+	}
+	flushUntilClosed(o.done, outputFlushInterval, o.flush)
+	return o, nil
+}
+
+// flushUntilClosed flushes an output file every interval until done is closed. Run also closes it when the
+// command is interrupted, so that its last lines are written and e.g. a gzip archive stays readable.
+func flushUntilClosed(done <-chan struct{}, interval time.Duration, flush func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+}
+
+// writerFor returns the writer of the file for the container referenced by ref, in the given kubeconfig context if any
+func (o *outputDir) writerFor(context string, ref corev1.ObjectReference, container string) io.Writer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	name := outputFilename(context, ref.Namespace, ref.Name, container)
+	if f, ok := o.files[name]; ok {
+		return f
+	}
+	f := &outputFile{
+		source: logTarget{Context: context, Namespace: ref.Namespace, Pod: ref.Name, Container: container}.String(),
+		path:   filepath.Join(o.dir, name),
+	}
+	o.files[name] = f
+	return f
+}
+
+func (o *outputDir) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, f := range o.files {
+		f.Flush()
+	}
+}
+
+// Close flushes and closes every file, then writes a summary of the line counts
+func (o *outputDir) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	select {
+	case <-o.done:
+		return nil
+	default:
+		close(o.done)
+	}
+
+	names := make([]string, 0, len(o.files))
+	for name := range o.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var closeErr error
+	for _, name := range names {
+		f := o.files[name]
+		if err := f.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+		if f.lines == 0 {
+			fmt.Fprintf(o.summary, "%s: no lines matched\n", f.source)
+			continue
+		}
+		fmt.Fprintf(o.summary, "%s: %d line(s) written to %s\n", f.source, f.lines, f.path)
+	}
+	return closeErr
+}
+
+func (f *outputFile) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		file, err := os.Create(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+		f.w = bufio.NewWriter(file)
+	}
+	f.lines++
+	return f.w.Write(p)
+}
+
+func (f *outputFile) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.w == nil {
+		return nil
+	}
+	return f.w.Flush()
+}
+
+func (f *outputFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	if err := f.w.Flush(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// outputFilename returns a safe file name for the logs of a container, e.g. "default_web-1_nginx.log",
+// prefixed with the context when given. Names that are too long are truncated and suffixed with a hash
+// of the full name to stay unique.
+func outputFilename(context, namespace, pod, container string) string {
+	name := namespace + "_" + pod + "_" + container
+	if context != "" {
+		name = context + "_" + name
+	}
+	name = unsafeFilenameRegexp.ReplaceAllString(name, "-")
+	if len(name) > maxOutputFilenameLength {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		name = fmt.Sprintf("%s-%08x", name[:maxOutputFilenameLength-9], h.Sum32())
+	}
+	return name + ".log"
+}
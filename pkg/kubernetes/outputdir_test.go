@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestOutputDirWritesEverySourceToItsFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "incident-42")
+	var summary bytes.Buffer
+	o, err := newOutputDir(dir, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := o.writerFor("", corev1.ObjectReference{Namespace: "default", Name: "web-1"}, "nginx")
+	// a source without lines is only listed in the summary
+	o.writerFor("", corev1.ObjectReference{Namespace: "default", Name: "api-1"}, "app")
+	for _, line := range []string{"ERROR one\n", "ERROR two\n"} {
+		if _, err := web.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "default_api-1_app.log")); !os.IsNotExist(err) {
+		t.Errorf("the file of a source without lines was created: %v", err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "default_web-1_nginx.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "ERROR one\nERROR two\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	wantSummary := "default/api-1/app: no lines matched\n" +
+		"default/web-1/nginx: 2 line(s) written to " + filepath.Join(dir, "default_web-1_nginx.log") + "\n"
+	if got := summary.String(); got != wantSummary {
+		t.Errorf("got summary\n%s\nwant\n%s", got, wantSummary)
+	}
+}
+
+func TestOutputFilename(t *testing.T) {
+	if got, want := outputFilename("", "default", "web.v2-1", "nginx"), "default_web.v2-1_nginx.log"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := outputFilename("prod/eu", "default", "web", "nginx"), "prod-eu_default_web_nginx.log"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("a", 300)
+	name := outputFilename("", "default", long, "nginx")
+	if len(name) > maxOutputFilenameLength+len(".log") {
+		t.Errorf("got a %d bytes name, want at most %d", len(name), maxOutputFilenameLength+len(".log"))
+	}
+	if other := outputFilename("", "default", long+"b", "nginx"); other == name {
+		t.Errorf("two long names were truncated to the same %q", name)
+	}
+}
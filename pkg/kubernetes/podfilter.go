@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var podPhases = []corev1.PodPhase{
+	corev1.PodPending,
+	corev1.PodRunning,
+	corev1.PodSucceeded,
+	corev1.PodFailed,
+	corev1.PodUnknown,
+}
+
+// parsePodPhases returns the set of phases given with --pod-status, matched case-insensitively
+func parsePodPhases(statuses []string) (map[corev1.PodPhase]bool, error) {
+	phases := map[corev1.PodPhase]bool{}
+	for _, status := range statuses {
+		found := false
+		for _, phase := range podPhases {
+			if strings.EqualFold(status, string(phase)) {
+				phases[phase] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid pod status %q, must be one of %s", status, joinPodPhases())
+		}
+	}
+	return phases, nil
+}
+
+func joinPodPhases() string {
+	names := make([]string, 0, len(podPhases))
+	for _, phase := range podPhases {
+		names = append(names, string(phase))
+	}
+	return strings.Join(names, ", ")
+}
+
+// podReady tells whether the Ready condition of the pod is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// annotationExclusion drops the pods with an annotation whose value matches a regex
+type annotationExclusion struct {
+	key   string
+	value *regexp.Regexp
+}
+
+// parseAnnotationExclusions parses the KEY=VALUE_REGEX values of --exclude-annotation
+func parseAnnotationExclusions(exprs []string) ([]annotationExclusion, error) {
+	exclusions := make([]annotationExclusion, 0, len(exprs))
+	for _, expr := range exprs {
+		key, value, found := strings.Cut(expr, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --exclude-annotation %q, must be KEY=VALUE_REGEX", expr)
+		}
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, newError(ErrInvalidPattern, fmt.Errorf("invalid --exclude-annotation %q: %w", expr, err))
+		}
+		exclusions = append(exclusions, annotationExclusion{key: key, value: re})
+	}
+	return exclusions, nil
+}
+
+// skipReason returns why the pod is filtered out by --pod-status, --only-ready, --exclude-selector
+// or --exclude-annotation, or "" if it is kept
+func (l LikeOptions) skipReason(pod *corev1.Pod) string {
+	if !l.podPhases[pod.Status.Phase] {
+		if pod.Status.Reason != "" {
+			return fmt.Sprintf("phase %s, %s", pod.Status.Phase, pod.Status.Reason)
+		}
+		return fmt.Sprintf("phase %s", pod.Status.Phase)
+	}
+	if l.OnlyReady && !podReady(pod) {
+		return "not ready"
+	}
+	if l.excludeSelector != nil && l.excludeSelector.Matches(labels.Set(pod.Labels)) {
+		return fmt.Sprintf("matches --exclude-selector %s", l.excludeSelector)
+	}
+	for _, exclude := range l.excludeAnnotations {
+		if value, ok := pod.Annotations[exclude.key]; ok && exclude.value.MatchString(value) {
+			return fmt.Sprintf("annotation %s=%s", exclude.key, value)
+		}
+	}
+	return ""
+}
+
+// jobPodPhases are the phases of the pods of a Job kept with the default --pod-status
+var jobPodPhases = map[corev1.PodPhase]bool{
+	corev1.PodRunning:   true,
+	corev1.PodSucceeded: true,
+	corev1.PodFailed:    true,
+}
+
+// defaultPodPhases tells whether --pod-status is the default Running
+func (l LikeOptions) defaultPodPhases() bool {
+	return len(l.podPhases) == 1 && l.podPhases[corev1.PodRunning]
+}
+
+// filtersPods tells whether a pod filter other than the default --pod-status Running is set
+func (l LikeOptions) filtersPods() bool {
+	return !l.defaultPodPhases() || l.OnlyReady || l.excludeSelector != nil || len(l.excludeAnnotations) > 0
+}
+
+// filterPods returns the pods of the list that are not filtered out by skipReason.
+// The skipped pods are listed on ErrOut when --verbose is set.
+func (l LikeOptions) filterPods(pods *corev1.PodList) *corev1.PodList {
+	filtered := &corev1.PodList{TypeMeta: pods.TypeMeta, ListMeta: pods.ListMeta}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		reason := l.skipReason(pod)
+		if reason == "" {
+			filtered.Items = append(filtered.Items, *pod)
+			continue
+		}
+		l.logger.Debug("skipped pod", "namespace", pod.Namespace, "pod", pod.Name, "reason", reason)
+		if l.Verbose {
+			fmt.Fprintf(l.ErrOut, "Skipping pod %s/%s (%s)\n", pod.Namespace, pod.Name, reason)
+		}
+	}
+	return filtered
+}
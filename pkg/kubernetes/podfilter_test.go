@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newMixedPodsAPI() *fakeAPI {
+	ready := testPod("web-ready", corev1.PodRunning, map[string]string{"app": "web"})
+	ready.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	evicted := testPod("web-evicted", corev1.PodFailed, map[string]string{"app": "web"})
+	evicted.Status.Reason = "Evicted"
+	failed := testPod("web-failed", corev1.PodFailed, map[string]string{"app": "web"})
+	return &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods": &corev1.PodList{Items: []corev1.Pod{
+			ready,
+			testPod("web-starting", corev1.PodRunning, map[string]string{"app": "web"}),
+			testPod("web-pending", corev1.PodPending, map[string]string{"app": "web"}),
+			testPod("web-completed", corev1.PodSucceeded, map[string]string{"app": "web"}),
+			failed,
+			evicted,
+		}},
+		"/namespaces/test/pods/web-failed": &failed,
+	}}
+}
+
+func TestPodStatusFilters(t *testing.T) {
+	tests := []struct {
+		flags []string
+		want  string
+	}{
+		{want: "web-ready,web-starting"},
+		{flags: []string{"--pod-status", "Running,Pending"}, want: "web-ready,web-starting,web-pending"},
+		{flags: []string{"--pod-status", "Failed"}, want: "web-failed,web-evicted"},
+		{flags: []string{"--only-ready"}, want: "web-ready"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.flags, " "), func(t *testing.T) {
+			l, cmd, _, errOut := newFakeCommand(t, newMixedPodsAPI())
+			flags := append([]string{"-l", "app=web", "--verbose"}, test.flags...)
+			if err := completeFlags(l, cmd, flags); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(resolvedPodNames(t, l.objects), ","); got != test.want {
+				t.Errorf("got pods %q, want %q", got, test.want)
+			}
+			if test.flags == nil && !strings.Contains(errOut.String(), "web-evicted (phase Failed, Evicted)") {
+				t.Errorf("the evicted pod is not listed as skipped: %q", errOut.String())
+			}
+		})
+	}
+}
+
+func TestPodStatusDoesNotFilterNamedPod(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newMixedPodsAPI())
+	if err := completeFlags(l, cmd, nil, "web-failed"); err != nil {
+		t.Fatal(err)
+	}
+	if pod, ok := l.Object.(*corev1.Pod); !ok || pod.Name != "web-failed" {
+		t.Errorf("got %T, want the named pod", l.Object)
+	}
+}
+
+func TestPodStatusSkipsEveryPod(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newMixedPodsAPI())
+	err := completeFlags(l, cmd, []string{"-l", "app=web", "--pod-status", "Unknown"})
+	if err == nil || !strings.Contains(err.Error(), "6 pod(s) skipped") {
+		t.Errorf("expected the skipped pods to be reported, got %v", err)
+	}
+}
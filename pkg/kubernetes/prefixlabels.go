@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkPrefixKeys checks the keys of --prefix-label and --prefix-annotation, which are looked up as given
+func (l LikeOptions) checkPrefixKeys() error {
+	for _, key := range l.PrefixLabels {
+		if len(strings.TrimSpace(key)) == 0 || strings.Contains(key, "=") {
+			return fmt.Errorf("invalid --prefix-label %q, must be the key of a label of the pods, e.g. version", key)
+		}
+	}
+	for _, key := range l.PrefixAnnotations {
+		if len(strings.TrimSpace(key)) == 0 || strings.Contains(key, "=") {
+			return fmt.Errorf("invalid --prefix-annotation %q, must be the key of an annotation of the pods", key)
+		}
+	}
+	return nil
+}
+
+// prefixMetadata returns the KEY=VALUE of every label of --prefix-label and annotation of --prefix-annotation of
+// the pod, separated by spaces, e.g. version=v2. The keys the pod does not have are left out. The pod is read when
+// its stream is opened, so that the pods selected while following are labelled too.
+func (l LikeOptions) prefixMetadata(ref corev1.ObjectReference) string {
+	if len(l.PrefixLabels) == 0 && len(l.PrefixAnnotations) == 0 {
+		return ""
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return ""
+	}
+	pod, err := clientset.CoreV1().Pods(ref.Namespace).Get(l.runContext(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		l.logger.Debug("cannot get the labels of the pod", "namespace", ref.Namespace, "pod", ref.Name, "error", err)
+		return ""
+	}
+	var metadata []string
+	for _, key := range l.PrefixLabels {
+		if value, ok := pod.Labels[key]; ok {
+			metadata = append(metadata, key+"="+value)
+		}
+	}
+	for _, key := range l.PrefixAnnotations {
+		if value, ok := pod.Annotations[key]; ok {
+			metadata = append(metadata, key+"="+value)
+		}
+	}
+	return strings.Join(metadata, " ")
+}
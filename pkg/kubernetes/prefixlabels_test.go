@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPrefixLabels(t *testing.T) {
+	canary := testPod("api-canary", corev1.PodRunning, map[string]string{"version": "v2", "track": "canary"})
+	canary.Annotations = map[string]string{"example.com/commit": "abc123"}
+	stable := testPod("api-stable", corev1.PodRunning, map[string]string{"version": "v1"})
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods/api-canary": &canary,
+		"/namespaces/test/pods/api-stable": &stable,
+	}}
+	l, cmd, _, _ := newFakeCommand(t, api)
+	flags := []string{"--pattern", "ERROR", "--prefix-label", "version,track", "--prefix-annotation", "example.com/commit"}
+	if err := completeFlags(l, cmd, flags, "api-canary"); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Prefix {
+		t.Error("--prefix-label did not set prefix")
+	}
+
+	var out bytes.Buffer
+	l.writerFor(corev1.ObjectReference{Namespace: "test", Name: "api-canary", FieldPath: "spec.containers{app}"}, &out).Write([]byte("ERROR new\n"))
+	// the keys a pod does not have are left out
+	l.writerFor(corev1.ObjectReference{Namespace: "test", Name: "api-stable", FieldPath: "spec.containers{app}"}, &out).Write([]byte("ERROR old\n"))
+	want := "[pod/api-canary/app version=v2 track=canary example.com/commit=abc123] ERROR new\n[pod/api-stable/app version=v1] ERROR old\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrefixLabelsValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--prefix-label", "version=v2"},
+		{"--prefix-label", "version,"},
+		{"--prefix-annotation", " "},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, append(flags, "--pattern", "ERROR"), "api-1"); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%v: got %v, want an ErrInvalidOptions", flags, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// preset is a named set of exclusions that expands into --exclude and --exclude-container
+type preset struct {
+	Description       string   `mapstructure:"description"`
+	Exclude           []string `mapstructure:"exclude"`
+	ExcludeContainers []string `mapstructure:"exclude-container"`
+}
+
+var builtinPresets = map[string]preset{
+	"mesh": {
+		Description:       "exclude the istio-proxy and linkerd-proxy sidecar containers",
+		ExcludeContainers: []string{"^istio-proxy$", "^linkerd-proxy$"},
+	},
+	"quiet-http": {
+		Description: "exclude health check requests",
+		Exclude:     []string{"/healthz|/readyz|/livez"},
+	},
+}
+
+// loadPresets returns the built-in presets and the ones defined under "presets" in the config file.
+// User-defined presets override built-in presets with the same name.
+func loadPresets() (map[string]preset, error) {
+	presets := map[string]preset{}
+	for name, p := range builtinPresets {
+		presets[name] = p
+	}
+	userPresets := map[string]preset{}
+	if err := viper.UnmarshalKey("presets", &userPresets); err != nil {
+		return nil, fmt.Errorf("invalid presets in config file: %w", err)
+	}
+	for name, p := range userPresets {
+		presets[name] = p
+	}
+	return presets, nil
+}
+
+// expandPresets appends the exclusions of the requested presets to --exclude and --exclude-container
+func (l *LikeOptions) expandPresets() error {
+	if len(l.Presets) == 0 {
+		return nil
+	}
+	presets, err := loadPresets()
+	if err != nil {
+		return err
+	}
+	for _, name := range l.Presets {
+		p, ok := presets[name]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, must be one of %s", name, strings.Join(presetNames(presets), ", "))
+		}
+		l.Exclude = append(l.Exclude, p.Exclude...)
+		l.ExcludeContainers = append(l.ExcludeContainers, p.ExcludeContainers...)
+	}
+	return nil
+}
+
+// PrintPresets writes the available presets and their exclusions to Out
+func (l LikeOptions) PrintPresets() error {
+	presets, err := loadPresets()
+	if err != nil {
+		return err
+	}
+	for _, name := range presetNames(presets) {
+		p := presets[name]
+		fmt.Fprintf(l.Out, "%s\t%s\n", name, p.Description)
+		for _, exclude := range p.Exclude {
+			fmt.Fprintf(l.Out, "  --exclude=%s\n", exclude)
+		}
+		for _, exclude := range p.ExcludeContainers {
+			fmt.Fprintf(l.Out, "  --exclude-container=%s\n", exclude)
+		}
+	}
+	return nil
+}
+
+func presetNames(presets map[string]preset) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// patternsFile is the file in the home directory listing the patterns offered by the completion of --pattern
+const patternsFile = ".kubectl-like-patterns"
+
+// loadPatterns returns the patterns of ~/.kubectl-like-patterns, one per line. Empty lines and lines
+// starting with # are ignored. Nothing is returned if the file cannot be read.
+func loadPatterns() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(home, patternsFile))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// readConfig makes the config file content the configuration of the test
+func readConfig(t *testing.T, content string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandPresets(t *testing.T) {
+	readConfig(t, "")
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.Exclude = []string{"DEBUG"}
+	l.Presets = []string{"mesh", "quiet-http"}
+	if err := l.expandPresets(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(l.Exclude, " "), "DEBUG /healthz|/readyz|/livez"; got != want {
+		t.Errorf("got --exclude %q, want %q", got, want)
+	}
+	if got, want := strings.Join(l.ExcludeContainers, " "), "^istio-proxy$ ^linkerd-proxy$"; got != want {
+		t.Errorf("got --exclude-container %q, want %q", got, want)
+	}
+
+	l.Presets = []string{"unknown"}
+	if err := l.expandPresets(); err == nil || !strings.Contains(err.Error(), "mesh, quiet-http") {
+		t.Errorf("expected an unknown preset error listing the presets, got %v", err)
+	}
+}
+
+func TestUserPresetsOverrideBuiltins(t *testing.T) {
+	readConfig(t, `
+presets:
+  mesh:
+    description: only istio
+    exclude-container: ["^istio-proxy$"]
+  batch:
+    exclude: ["progress"]
+`)
+	streams, _, out, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.Presets = []string{"mesh", "batch"}
+	if err := l.expandPresets(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(l.ExcludeContainers, " "); got != "^istio-proxy$" {
+		t.Errorf("got --exclude-container %q, want the user-defined ^istio-proxy$", got)
+	}
+	if got := strings.Join(l.Exclude, " "); got != "progress" {
+		t.Errorf("got --exclude %q, want progress", got)
+	}
+
+	if err := l.PrintPresets(); err != nil {
+		t.Fatal(err)
+	}
+	want := "batch\t\n  --exclude=progress\nmesh\tonly istio\n  --exclude-container=^istio-proxy$\nquiet-http\t"
+	if got := out.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("got\n%s\nwant it to start with\n%s", got, want)
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if patterns := loadPatterns(); patterns != nil {
+		t.Errorf("got %q without a patterns file, want nothing", patterns)
+	}
+	content := "# errors\nERROR|FATAL\n\n  timeout  \n"
+	if err := os.WriteFile(filepath.Join(home, patternsFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(loadPatterns(), ","), "ERROR|FATAL,timeout"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
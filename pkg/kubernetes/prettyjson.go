@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// prettyJSONLines indents the lines that are JSON with --pretty-json, after their timestamp if any.
+// The other lines are returned as they are.
+func (l LikeOptions) prettyJSONLines(lines [][]byte) [][]byte {
+	if !l.PrettyJSON {
+		return lines
+	}
+	for i, line := range lines {
+		lines[i] = l.prettyJSON(line)
+	}
+	return lines
+}
+
+func (l LikeOptions) prettyJSON(line []byte) []byte {
+	var timestamp []byte
+	message := line
+	if l.Timestamps {
+		if _, rest, ok := splitTimestamp(line); ok {
+			timestamp, message = line[:len(line)-len(rest)], rest
+		}
+	}
+	message = bytes.TrimRight(message, "\r\n")
+	// a number or a string alone is left as it is
+	if len(message) == 0 || message[0] != '{' && message[0] != '[' {
+		return line
+	}
+	var indented bytes.Buffer
+	indented.Write(timestamp)
+	if err := json.Indent(&indented, message, "", "  "); err != nil {
+		return line
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes()
+}
@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func TestPrettyJSONLines(t *testing.T) {
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.PrettyJSON = true
+	tests := map[string]string{
+		`{"level":"error","err":{"code":500}}` + "\n": "{\n  \"level\": \"error\",\n  \"err\": {\n    \"code\": 500\n  }\n}\n",
+		`[1,2]` + "\n":         "[\n  1,\n  2\n]\n",
+		"ERROR not json\n":     "ERROR not json\n",
+		`{"truncated":` + "\n": `{"truncated":` + "\n",
+		`"a string"` + "\n":    `"a string"` + "\n",
+	}
+	for line, want := range tests {
+		if got := joinLines(l.outputLines([]byte(line))); got != want {
+			t.Errorf("%q: got %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestPrettyJSONKeepsTimestamp(t *testing.T) {
+	streams, _, _, _ := genericiooptions.NewTestIOStreams()
+	l := NewLikeOptions(streams)
+	l.PrettyJSON = true
+	l.Timestamps = true
+	got := joinLines(l.outputLines([]byte(`2024-06-12T10:04:05.123456789Z {"msg":"boom"}` + "\n")))
+	if want := "2024-06-12T10:04:05.123456789Z {\n  \"msg\": \"boom\"\n}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrettyJSONIndentsJQResults(t *testing.T) {
+	l := newJQOptions(t, ".err")
+	l.PrettyJSON = true
+	got := joinLines(l.outputLines([]byte(`{"msg":"boom","err":{"code":500}}` + "\n")))
+	if want := "{\n  \"code\": 500\n}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// latin1 returns the line with every byte replaced by the character of the same code, so that a pattern matches
+// its bytes whether they are UTF-8 or not: \xNN matches the byte NN and . a single byte. An ASCII line is its
+// own decoding and is matched as is.
+func latin1(line []byte) []byte {
+	if isASCII(line) {
+		return line
+	}
+	decoded := make([]byte, 0, len(line)+len(line)/4)
+	for _, b := range line {
+		decoded = utf8.AppendRune(decoded, rune(b))
+	}
+	return decoded
+}
+
+func isASCII(line []byte) bool {
+	for _, b := range line {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// rawOffset returns the offset in the line of the offset in its latin1 decoding
+func rawOffset(line []byte, offset int) int {
+	decoded := 0
+	for i, b := range line {
+		if decoded >= offset {
+			return i
+		}
+		decoded += utf8.RuneLen(rune(b))
+	}
+	return len(line)
+}
+
+// regexpMatch reports whether re matches b, byte by byte with --raw-bytes
+func (l LikeOptions) regexpMatch(re *regexp.Regexp, b []byte) bool {
+	if l.RawBytes {
+		return re.Match(latin1(b))
+	}
+	return re.Match(b)
+}
+
+// regexpFindAllIndex returns the byte offsets in b of every match of re, matched byte by byte with --raw-bytes
+func (l LikeOptions) regexpFindAllIndex(re *regexp.Regexp, b []byte) [][]int {
+	if !l.RawBytes {
+		return re.FindAllIndex(b, -1)
+	}
+	locs := re.FindAllIndex(latin1(b), -1)
+	for _, loc := range locs {
+		loc[0], loc[1] = rawOffset(b, loc[0]), rawOffset(b, loc[1])
+	}
+	return locs
+}
+
+// regexpFindSubmatch returns the leftmost match of re in b and its submatches, matched byte by byte with
+// --raw-bytes
+func (l LikeOptions) regexpFindSubmatch(re *regexp.Regexp, b []byte) [][]byte {
+	if !l.RawBytes {
+		return re.FindSubmatch(b)
+	}
+	loc := re.FindSubmatchIndex(latin1(b))
+	if loc == nil {
+		return nil
+	}
+	match := make([][]byte, len(loc)/2)
+	for i := range match {
+		if loc[2*i] >= 0 {
+			match[i] = b[rawOffset(b, loc[2*i]):rawOffset(b, loc[2*i+1])]
+		}
+	}
+	return match
+}
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// useFactory makes the logs helpers build their clients from the factory of l. --request-timeout bounds every
+// request of a client, the reading of its body included, so when following it is left out of the clients of
+// the logs, which would otherwise end the streams after it, and only bounds opening them.
+func (l *LikeOptions) useFactory() error {
+	l.RESTClientGetter = l.factory
+	if !l.Follow {
+		return nil
+	}
+	config, err := l.factory.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	l.requestTimeout = config.Timeout
+	l.RESTClientGetter = followRESTClientGetter{RESTClientGetter: l.factory}
+	return nil
+}
+
+// followRESTClientGetter builds clients without the timeout of --request-timeout
+type followRESTClientGetter struct {
+	genericclioptions.RESTClientGetter
+}
+
+func (g followRESTClientGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	config.Timeout = 0
+	return config, nil
+}
+
+// connectTimeoutRequest fails when its stream is not opened within timeout. Once opened, the stream is read
+// for as long as it is followed.
+type connectTimeoutRequest struct {
+	rest.ResponseWrapper
+	timeout time.Duration
+}
+
+func (r *connectTimeoutRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(r.timeout, cancel)
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if !timer.Stop() {
+		// the stream opened meanwhile is canceled already
+		if err == nil {
+			stream.Close()
+		}
+		return nil, connectTimeoutError{timeout: r.timeout}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnCloseStream{ReadCloser: stream, cancel: cancel}, nil
+}
+
+// cancelOnCloseStream releases the context of its stream once it is closed
+type cancelOnCloseStream struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (s *cancelOnCloseStream) Close() error {
+	defer s.cancel()
+	return s.ReadCloser.Close()
+}
+
+// connectTimeoutError is the error of a stream not opened within --request-timeout, a timeout retried by --retries
+type connectTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e connectTimeoutError) Error() string {
+	return fmt.Sprintf("opening the log stream timed out after --request-timeout=%s", e.timeout)
+}
+
+func (connectTimeoutError) Timeout() bool {
+	return true
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+// hungRequest is a log request whose stream never opens, until its context is canceled
+type hungRequest struct{}
+
+func (hungRequest) DoRaw(context.Context) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (hungRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestTimeoutOnlyBoundsOpeningFollowedStreams(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	l.factory.(*cmdtesting.TestFactory).ClientConfigVal.Timeout = 5 * time.Second
+	if err := completeFlags(l, cmd, []string{"-f", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.requestTimeout != 5*time.Second {
+		t.Errorf("got a request timeout of %s, want 5s", l.requestTimeout)
+	}
+	config, err := l.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Timeout != 0 {
+		t.Errorf("got a timeout of %s for the clients of the followed logs, want none", config.Timeout)
+	}
+}
+
+func TestRequestTimeoutBoundsReadingLogsOnce(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	l.factory.(*cmdtesting.TestFactory).ClientConfigVal.Timeout = 5 * time.Second
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.requestTimeout != 0 {
+		t.Errorf("got a request timeout of %s, want the one of the clients", l.requestTimeout)
+	}
+	config, err := l.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Timeout != 5*time.Second {
+		t.Errorf("got a timeout of %s for the clients of the logs, want 5s", config.Timeout)
+	}
+}
+
+func TestConnectTimeoutRequest(t *testing.T) {
+	request := &connectTimeoutRequest{ResponseWrapper: hungRequest{}, timeout: 10 * time.Millisecond}
+	_, err := request.Stream(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "--request-timeout=10ms") {
+		t.Fatalf("got %v, want the stream timed out", err)
+	}
+
+	// an opened stream is read for longer than the timeout
+	reader, writer := io.Pipe()
+	request = &connectTimeoutRequest{ResponseWrapper: pipeRequest{reader: reader}, timeout: 10 * time.Millisecond}
+	stream, err := request.Stream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	time.Sleep(50 * time.Millisecond)
+	go io.WriteString(writer, "ERROR boom\n")
+	line := make([]byte, len("ERROR boom\n"))
+	if _, err := io.ReadFull(stream, line); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCanceledContextUnblocksFollowedStream(t *testing.T) {
+	l, cmd, out, _ := newFakeCommand(t, newPodAPI("api-1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd.SetContext(ctx)
+	if err := completeFlags(l, cmd, []string{"-f", "--no-reattach", "--pattern", "ERROR", "--flush-interval", "0", "--no-banner"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	writer := pipeLogs(t, l)
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+
+	io.WriteString(writer, "ERROR one\n")
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Fatalf("got %v, want ErrInterrupted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the followed stream was still read after its context was canceled")
+	}
+	if got, want := out.String(), "ERROR one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// resolveObjects looks up the objects the logs are requested for, retrying on transient errors of the API server.
+// Every POD or TYPE/NAME argument resolves to that object, while selectors resolve to a single pod list.
+func (l *LikeOptions) resolveObjects() ([]runtime.Object, error) {
+	var objects []runtime.Object
+	err := l.retry("resolving the pods", func() (err error) {
+		objects, err = l.resolveObjectsOnce()
+		return err
+	})
+	return objects, err
+}
+
+func (l *LikeOptions) resolveObjectsOnce() ([]runtime.Object, error) {
+	if err := l.checkResourceArgs(); err != nil {
+		return nil, err
+	}
+	builder := l.factory.NewBuilder().
+		WithScheme(scheme.Scheme, scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(l.Namespace).DefaultNamespace()
+	if len(l.ResourceArgs) > 0 {
+		builder.ResourceNames("pods", l.ResourceArgs...)
+	} else {
+		builder.SingleResourceType().
+			ResourceTypes("pods").
+			AllNamespaces(l.AllNamespaces).
+			LabelSelectorParam(l.Selector).
+			FieldSelectorParam(l.FieldSelector)
+		// --all-pods without a selector selects every pod of the namespace
+		if len(l.Selector) == 0 && len(l.FieldSelector) == 0 {
+			builder.SelectAllParam(true)
+		}
+	}
+	l.logger.Debug("resolving objects", "args", l.ResourceArgs, "selector", l.Selector, "fieldSelector", l.FieldSelector, "allNamespaces", l.AllNamespaces)
+	infos, err := builder.Do().Infos()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			if containerErr := l.containerArgError(); containerErr != nil {
+				return nil, containerErr
+			}
+			err = newError(ErrTargetNotFound, fmt.Errorf("error from server (NotFound): %w in namespace %q", err, l.Namespace))
+		}
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, newError(ErrTargetNotFound, errors.New("expected a resource"))
+	}
+
+	objects := make([]runtime.Object, 0, len(infos))
+	for _, info := range infos {
+		l.logger.Debug("resolved object", "kind", info.Mapping.GroupVersionKind.Kind, "namespace", info.Namespace, "name", info.Name)
+		object := info.Object
+		if _, isDeployment := object.(*appsv1.Deployment); isDeployment {
+			l.byReplicaSet = true
+		}
+		if cronJob, isCronJob := object.(*batchv1.CronJob); isCronJob {
+			object, err = l.latestJob(cronJob)
+			if err != nil {
+				return nil, err
+			}
+		}
+		_, isPod := object.(*corev1.Pod)
+		if isPod && l.FieldSelector != "" {
+			return nil, errors.New("--field-selector cannot be used with a POD name")
+		}
+		// workloads are resolved to their pods by the logs helpers using only the label selector,
+		// so the field selector and the pod filters have to be applied here.
+		if len(l.ResourceArgs) > 0 && !isPod && (l.FieldSelector != "" || l.AllPods || l.filtersPods()) {
+			object, err = l.podsForObject(object)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if pods, ok := object.(*corev1.PodList); ok {
+			filter := *l
+			// the pods of a job have usually completed, they are kept unless --pod-status is given
+			if isJob(info.Object) && l.defaultPodPhases() {
+				filter.podPhases = jobPodPhases
+			}
+			filtered := filter.filterPods(pods)
+			// pods created later are picked up when following selected pods
+			followSelected := l.Follow && len(l.ResourceArgs) == 0
+			if len(filtered.Items) == 0 && !followSelected {
+				if len(pods.Items) > 0 {
+					return nil, newError(ErrTargetNotFound, fmt.Errorf("%w, %d pod(s) skipped by --pod-status %s, --only-ready or the pod exclusions", l.noPodsMatchedError(), len(pods.Items), strings.Join(l.PodStatus, ",")))
+				}
+				return nil, newError(ErrTargetNotFound, l.noPodsMatchedError())
+			}
+			object = filtered
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// containerArgError returns an error pointing to -c when the arguments look like the POD CONTAINER form
+// of kubectl logs, i.e. the second argument is not a pod but a container of the first one
+func (l *LikeOptions) containerArgError() error {
+	if len(l.ResourceArgs) != 2 || strings.Contains(l.ResourceArgs[0], "/") || strings.Contains(l.ResourceArgs[1], "/") {
+		return nil
+	}
+	podName, container := l.ResourceArgs[0], l.ResourceArgs[1]
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil
+	}
+	pod, err := clientset.CoreV1().Pods(l.Namespace).Get(l.runContext(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if c.Name == container {
+			return fmt.Errorf("pod %q not found in namespace %q: every argument is a POD or TYPE/NAME, use -c %s to get the logs of container %q of pod %q", container, l.Namespace, container, container, podName)
+		}
+	}
+	return nil
+}
+
+// checkResourceArgs makes sure every TYPE/NAME argument names a resource type,
+// so that a namespace/pod argument gets a clear error instead of an unknown resource type.
+func (l *LikeOptions) checkResourceArgs() error {
+	mapper, err := l.factory.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	for _, arg := range l.ResourceArgs {
+		resource, _, found := strings.Cut(arg, "/")
+		if !found {
+			continue
+		}
+		if _, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: resource}); err != nil {
+			return fmt.Errorf("%q is not a TYPE/NAME reference: pods in other namespaces are not supported as arguments, use -n NAMESPACE or -A with a selector instead", arg)
+		}
+	}
+	return nil
+}
+
+// latestJob returns the most recently created Job of the CronJob
+func (l *LikeOptions) latestJob(cronJob *batchv1.CronJob) (*batchv1.Job, error) {
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := clientset.BatchV1().Jobs(cronJob.Namespace).List(l.runContext(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if owner := metav1.GetControllerOf(job); owner == nil || owner.UID != cronJob.UID {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("cronjob %q has no jobs in namespace %q", cronJob.Name, cronJob.Namespace)
+	}
+	l.logger.Debug("resolved the latest job of the cronjob", "cronjob", cronJob.Name, "job", latest.Name)
+	return latest, nil
+}
+
+// isJob tells whether the object is a Job or a CronJob
+func isJob(object runtime.Object) bool {
+	switch object.(type) {
+	case *batchv1.Job, *batchv1.CronJob:
+		return true
+	}
+	return false
+}
+
+// podsForObject lists the pods selected by a workload, narrowed by the field selector.
+func (l *LikeOptions) podsForObject(object runtime.Object) (*corev1.PodList, error) {
+	namespace, selector, err := polymorphichelpers.SelectorsForObject(object)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the logs from %T: %v", object, err)
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return clientset.CoreV1().Pods(namespace).List(l.runContext(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+		FieldSelector: l.FieldSelector,
+	})
+}
+
+func (l *LikeOptions) noPodsMatchedError() error {
+	where := fmt.Sprintf("in namespace %q", l.Namespace)
+	if l.AllNamespaces {
+		where = "in any namespace"
+	}
+	switch {
+	case l.Selector != "" && l.FieldSelector != "":
+		return fmt.Errorf("no pods matched label selector %q and field selector %q %s", l.Selector, l.FieldSelector, where)
+	case l.FieldSelector != "":
+		return fmt.Errorf("no pods matched field selector %q %s", l.FieldSelector, where)
+	case l.Selector != "":
+		return fmt.Errorf("no pods matched label selector %q %s", l.Selector, where)
+	default:
+		return fmt.Errorf("no pods found %s", where)
+	}
+}
@@ -0,0 +1,286 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func apiDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		},
+	}
+}
+
+func newDeploymentAPI(pods ...corev1.Pod) *fakeAPI {
+	return &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/deployments/api": apiDeployment(),
+		"/namespaces/test/pods":            &corev1.PodList{Items: pods},
+	}}
+}
+
+func resolvedPodNames(t *testing.T, objects []runtime.Object) []string {
+	t.Helper()
+	if len(objects) != 1 {
+		t.Fatalf("expected a single object, got %d", len(objects))
+	}
+	pods, ok := objects[0].(*corev1.PodList)
+	if !ok {
+		t.Fatalf("expected a pod list, got %T", objects[0])
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestResolveObjectsForwardsFieldSelector(t *testing.T) {
+	api := newDeploymentAPI(testPod("api-1", corev1.PodRunning, map[string]string{"app": "api"}))
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"deployments/api"}
+	l.FieldSelector = "spec.nodeName=worker-3"
+
+	objects, err := l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resolvedPodNames(t, objects), ","); got != "api-1" {
+		t.Errorf("got pods %q, want api-1", got)
+	}
+	queries := strings.Join(api.requests("/namespaces/test/pods"), "&")
+	if !strings.Contains(queries, "fieldSelector=spec.nodeName%3Dworker-3") || !strings.Contains(queries, "labelSelector=app%3Dapi") {
+		t.Errorf("the pods were not listed with both selectors: %q", queries)
+	}
+}
+
+func TestResolveObjectsKeepsWorkloadWithoutPodFilters(t *testing.T) {
+	l, _, _ := newFakeOptions(t, newDeploymentAPI())
+	l.ResourceArgs = []string{"deployments/api"}
+
+	objects, err := l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects[0].(*appsv1.Deployment); !ok {
+		t.Errorf("expected the deployment to be left to the logs helpers, got %T", objects[0])
+	}
+}
+
+func TestResolveObjectsAppliesPodFiltersToWorkloads(t *testing.T) {
+	pods := []corev1.Pod{
+		testPod("api-stable", corev1.PodRunning, map[string]string{"app": "api", "track": "stable"}),
+		testPod("api-canary", corev1.PodRunning, map[string]string{"app": "api", "track": "canary"}),
+		testPod("api-pending", corev1.PodPending, map[string]string{"app": "api", "track": "stable"}),
+	}
+	tests := []struct {
+		name  string
+		setup func(l *LikeOptions)
+		want  string
+	}{
+		{
+			name: "exclude-selector",
+			setup: func(l *LikeOptions) {
+				l.excludeSelector = labels.SelectorFromSet(labels.Set{"track": "canary"})
+			},
+			want: "api-stable",
+		},
+		{
+			name: "exclude-annotation",
+			setup: func(l *LikeOptions) {
+				exclusions, err := parseAnnotationExclusions([]string{"nothing=.*"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.excludeAnnotations = exclusions
+			},
+			want: "api-stable,api-canary",
+		},
+		{
+			name: "pod-status",
+			setup: func(l *LikeOptions) {
+				l.podPhases = map[corev1.PodPhase]bool{corev1.PodPending: true}
+			},
+			want: "api-pending",
+		},
+		{
+			name: "only-ready",
+			setup: func(l *LikeOptions) {
+				l.OnlyReady = true
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, _, _ := newFakeOptions(t, newDeploymentAPI(pods...))
+			l.ResourceArgs = []string{"deployments/api"}
+			test.setup(&l)
+
+			objects, err := l.resolveObjects()
+			if test.want == "" {
+				if err == nil || !strings.Contains(err.Error(), "skipped") {
+					t.Fatalf("expected every pod to be skipped, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(resolvedPodNames(t, objects), ","); got != test.want {
+				t.Errorf("got pods %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveObjectsRejectsFieldSelectorWithPod(t *testing.T) {
+	pod := testPod("api-1", corev1.PodRunning, nil)
+	api := &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": &pod}}
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"api-1"}
+	l.FieldSelector = "spec.nodeName=worker-3"
+
+	if _, err := l.resolveObjects(); err == nil || !strings.Contains(err.Error(), "--field-selector") {
+		t.Errorf("expected a --field-selector error, got %v", err)
+	}
+}
+
+func TestResolveObjectsPointsContainerArgumentToFlag(t *testing.T) {
+	pod := testPod("mypod", corev1.PodRunning, nil)
+	pod.Spec.Containers = []corev1.Container{{Name: "nginx"}, {Name: "sidecar"}}
+	api := &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/mypod": &pod}}
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"mypod", "nginx"}
+
+	_, err := l.resolveObjects()
+	if err == nil || !strings.Contains(err.Error(), "use -c nginx") {
+		t.Errorf("expected a hint to use -c, got %v", err)
+	}
+
+	l.ResourceArgs = []string{"mypod", "other"}
+	_, err = l.resolveObjects()
+	if err == nil || strings.Contains(err.Error(), "-c") || !strings.Contains(err.Error(), "NotFound") {
+		t.Errorf("expected a plain not found error for a pod that is not a container, got %v", err)
+	}
+}
+
+func TestResolveObjectsMultiplePods(t *testing.T) {
+	podA, podB := testPod("pod-a", corev1.PodRunning, nil), testPod("pod-b", corev1.PodRunning, nil)
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods/pod-a": &podA,
+		"/namespaces/test/pods/pod-b": &podB,
+	}}
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"pod-a", "pod-b"}
+
+	objects, err := l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, object := range objects {
+		names = append(names, object.(*corev1.Pod).Name)
+	}
+	if got := strings.Join(names, ","); got != "pod-a,pod-b" {
+		t.Errorf("got %q, want pod-a,pod-b", got)
+	}
+}
+
+func TestCheckResourceArgsRejectsNamespacedPods(t *testing.T) {
+	l, _, _ := newFakeOptions(t, &fakeAPI{})
+	l.ResourceArgs = []string{"pod-a", "other-ns/pod-b"}
+	if err := l.checkResourceArgs(); err == nil || !strings.Contains(err.Error(), "-n NAMESPACE") {
+		t.Errorf("expected the ns/pod form to be rejected, got %v", err)
+	}
+	l.ResourceArgs = []string{"pod-a", "deployments/api"}
+	if err := l.checkResourceArgs(); err != nil {
+		t.Errorf("a TYPE/NAME argument was rejected: %v", err)
+	}
+}
+
+func migrateJob(name string, uid types.UID, created time.Time) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", CreationTimestamp: metav1.NewTime(created)},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": name}},
+		},
+	}
+	if uid != "" {
+		controller := true
+		job.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: "nightly", UID: uid, Controller: &controller}}
+	}
+	return job
+}
+
+func TestResolveObjectsKeepsCompletedJobPods(t *testing.T) {
+	job := migrateJob("migrate", "", time.Now())
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/jobs/migrate": &job,
+		"/namespaces/test/pods": &corev1.PodList{Items: []corev1.Pod{
+			testPod("migrate-1", corev1.PodFailed, map[string]string{"job-name": "migrate"}),
+			testPod("migrate-2", corev1.PodSucceeded, map[string]string{"job-name": "migrate"}),
+			testPod("migrate-3", corev1.PodPending, map[string]string{"job-name": "migrate"}),
+		}},
+	}}
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"jobs/migrate"}
+	l.AllPods = true
+
+	objects, err := l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resolvedPodNames(t, objects), ","); got != "migrate-1,migrate-2" {
+		t.Errorf("got pods %q, want the completed pods of the job", got)
+	}
+
+	l.podPhases = map[corev1.PodPhase]bool{corev1.PodPending: true}
+	objects, err = l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resolvedPodNames(t, objects), ","); got != "migrate-3" {
+		t.Errorf("got pods %q with --pod-status Pending, want migrate-3", got)
+	}
+}
+
+func TestResolveObjectsCronJobLatestJob(t *testing.T) {
+	cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "test", UID: "cron-uid"}}
+	now := time.Now()
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/cronjobs/nightly": cronJob,
+		"/namespaces/test/jobs": &batchv1.JobList{Items: []batchv1.Job{
+			migrateJob("nightly-1", "cron-uid", now.Add(-48*time.Hour)),
+			migrateJob("nightly-3", "cron-uid", now),
+			migrateJob("nightly-2", "cron-uid", now.Add(-24*time.Hour)),
+			migrateJob("other", "other-uid", now.Add(time.Hour)),
+		}},
+	}}
+	l, _, _ := newFakeOptions(t, api)
+	l.ResourceArgs = []string{"cronjobs/nightly"}
+
+	objects, err := l.resolveObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, ok := objects[0].(*batchv1.Job)
+	if !ok || job.Name != "nightly-3" {
+		t.Errorf("got %v, want the latest job nightly-3", objects[0])
+	}
+
+	api.objects["/namespaces/test/jobs"] = &batchv1.JobList{}
+	if _, err := l.resolveObjects(); err == nil || !strings.Contains(err.Error(), `cronjob "nightly" has no jobs`) {
+		t.Errorf("expected a no jobs error, got %v", err)
+	}
+}
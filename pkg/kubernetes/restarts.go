@@ -0,0 +1,356 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// reattachInterval is how often the container status is polled while waiting for a restart
+var reattachInterval = 2 * time.Second
+
+const (
+	defaultReconnectMaxAttempts = 10
+	defaultReconnectMaxBackoff  = 30 * time.Second
+)
+
+var (
+	// reconnectBackoff is the delay before reopening a failed stream, doubled after every failed attempt
+	reconnectBackoff = time.Second
+	// reconnectSleep waits for the backoff, returning ErrInterrupted when the command is interrupted meanwhile
+	reconnectSleep = LikeOptions.sleep
+)
+
+// reconnectDelay returns the backoff before the attempt-th reconnection, at most --reconnect-max-backoff
+func (l LikeOptions) reconnectDelay(attempt int) time.Duration {
+	delay := reconnectBackoff
+	for i := 1; i < attempt && delay < l.ReconnectMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, l.ReconnectMaxBackoff)
+}
+
+// watchedRequest records whether opening or reading its stream failed, to tell a dropped stream from an error
+// writing the lines, e.g. --max-bytes reached
+type watchedRequest struct {
+	rest.ResponseWrapper
+	failed atomic.Bool
+	// read tells whether a line was read, i.e. the stream was opened
+	read atomic.Bool
+}
+
+func (r *watchedRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		r.failed.Store(true)
+		return nil, err
+	}
+	return &watchedStream{ReadCloser: stream, request: r}, nil
+}
+
+type watchedStream struct {
+	io.ReadCloser
+	request *watchedRequest
+}
+
+func (s *watchedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.request.read.Store(true)
+	}
+	if err != nil && err != io.EOF {
+		s.request.failed.Store(true)
+	}
+	return n, err
+}
+
+// reconnect waits for the backoff and returns nil when the stream of the container failed with err and is to be
+// opened again, i.e. the error came from the stream, the pod still exists and the attempts of
+// --reconnect-max-attempts are not used up. Otherwise it returns err, or ErrInterrupted when the command was
+// interrupted during the backoff.
+func (l LikeOptions) reconnect(ref corev1.ObjectReference, request *watchedRequest, err error, attempt int) error {
+	if !request.failed.Load() || errors.Is(err, errIdleTimeout) || errors.Is(err, ErrInterrupted) || attempt > l.ReconnectMaxAttempts {
+		return err
+	}
+	if _, statusErr := l.containerStatus(ref); apierrors.IsNotFound(statusErr) {
+		return err
+	}
+	delay := l.reconnectDelay(attempt)
+	fmt.Fprintf(l.ErrOut, "warning: the log stream of %s failed: %v, reconnecting in %s (attempt %d of %d)\n", l.sourceName(ref), err, delay, attempt, l.ReconnectMaxAttempts)
+	return reconnectSleep(l, delay)
+}
+
+// containerStatus fetches the pod referenced by ref and returns the status of its container
+func (l LikeOptions) containerStatus(ref corev1.ObjectReference) (*corev1.ContainerStatus, error) {
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	pod, err := clientset.CoreV1().Pods(ref.Namespace).Get(l.runContext(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	_, container := l.containerFromRef(ref)
+	if status := findContainerStatus(pod, container); status != nil {
+		return status, nil
+	}
+	return nil, fmt.Errorf("container %s not found in the status of pod %s", container, ref.Name)
+}
+
+// findContainerStatus returns the status of the named container, or nil if the pod has none for it
+func findContainerStatus(pod *corev1.Pod, container string) *corev1.ContainerStatus {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == container {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// followWithReattach follows the logs of a container and reopens the stream every time the container restarts.
+// A stream reopened on the same instance resumes from the timestamp of the last line read, and the lines of that
+// timestamp already read are dropped, so that no line is printed twice or lost.
+func (l LikeOptions) followWithReattach(ref corev1.ObjectReference, request rest.ResponseWrapper, out io.Writer) error {
+	status, err := l.containerStatus(ref)
+	if err != nil {
+		return err
+	}
+	restartCount := status.RestartCount
+	attempt := 0
+	// the mark of --resume-file, if set, so that the first stream also resumes after the line read by the last run
+	last := l.lastMarkOf(ref)
+	for {
+		opened := time.Now()
+		resumed := &resumedRequest{ResponseWrapper: request, mark: last.get(), timestamps: l.Timestamps, last: last, stats: l.stats, source: l.sourceName(ref)}
+		watched := &watchedRequest{ResponseWrapper: resumed}
+		if err := l.ConsumeRequestFn(watched, out); err != nil {
+			// a stream ended by --head is not reopened
+			if errors.Is(err, errHeadReached) {
+				return nil
+			}
+			if watched.read.Load() {
+				// the attempts count the failures in a row
+				attempt = 0
+			}
+			failed := metav1.Now()
+			attempt++
+			if err := l.reconnect(ref, watched, err, attempt); err != nil {
+				return err
+			}
+			// follow the same instance again from the last line read
+			request, err = l.instanceRequest(ref, last.since(failed))
+			if err != nil {
+				return err
+			}
+			l.countReconnect(ref)
+			continue
+		}
+		attempt = 0
+		ended := metav1.Now()
+		l.logger.Debug("log stream ended, waiting for a restart", "namespace", ref.Namespace, "pod", ref.Name, "restartCount", restartCount)
+		status, err := l.waitForRestart(ref, restartCount)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			// the container will not be restarted, tell how it ended
+			return l.reportExit(ref)
+		}
+		if status.RestartCount == restartCount {
+			// the stream was closed while the container kept running, e.g. by an idle timeout of the kubelet
+			// or a restart of the API server, so follow the same instance again from the last line read
+			l.logger.Info("log stream closed while the container is running, reopening", "namespace", ref.Namespace, "pod", ref.Name, "container", status.Name)
+			// a container that is exiting may still be reported as running, do not reopen its stream in a loop
+			if ended.Sub(opened) < reattachInterval {
+				if err := l.sleep(reattachInterval); err != nil {
+					return err
+				}
+			}
+			request, err = l.instanceRequest(ref, last.since(ended))
+			if err != nil {
+				return err
+			}
+			l.countReconnect(ref)
+			continue
+		}
+		restartCount = status.RestartCount
+		l.logger.Info("container restarted, reattaching", "namespace", ref.Namespace, "pod", ref.Name, "container", status.Name, "restartCount", restartCount)
+
+		exitCode := "unknown"
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			exitCode = strconv.Itoa(int(terminated.ExitCode))
+		}
+		// the marker is not a line of the container, see noticeWriter
+		if _, err := fmt.Fprintf(l.noticeWriter(), "=== container restarted (exit code %s) ===\n", exitCode); err != nil {
+			return err
+		}
+
+		// the new instance is read from its start
+		last.reset()
+		request, err = l.instanceRequest(ref, nil)
+		if err != nil {
+			return err
+		}
+		l.countReconnect(ref)
+	}
+}
+
+// countReconnect counts a stream of the container reopened for --metrics-addr and the stats snapshots
+func (l LikeOptions) countReconnect(ref corev1.ObjectReference) {
+	if l.streamMetrics != nil {
+		l.streamMetrics.reconnects.add(1, l.sourceName(ref))
+	}
+	if l.snapshot != nil {
+		l.snapshot.reconnect(l.sourceName(ref))
+	}
+}
+
+// waitForRestart polls the pod until the container runs again after restartCount restarts.
+// It returns the status right away when the container still runs after restartCount restarts,
+// i.e. the stream ended without a restart, and a nil status when it will not be restarted anymore.
+func (l LikeOptions) waitForRestart(ref corev1.ObjectReference, restartCount int32) (*corev1.ContainerStatus, error) {
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	_, container := l.containerFromRef(ref)
+	for {
+		if l.runContext().Err() != nil {
+			return nil, ErrInterrupted
+		}
+		pod, err := clientset.CoreV1().Pods(ref.Namespace).Get(l.runContext(), ref.Name, metav1.GetOptions{})
+		if l.runContext().Err() != nil {
+			return nil, ErrInterrupted
+		}
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return nil, nil
+		}
+		status := findContainerStatus(pod, container)
+		if status == nil {
+			return nil, nil
+		}
+		if status.RestartCount >= restartCount && status.State.Running != nil {
+			return status, nil
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			switch pod.Spec.RestartPolicy {
+			case corev1.RestartPolicyNever:
+				return nil, nil
+			case corev1.RestartPolicyOnFailure:
+				if terminated.ExitCode == 0 {
+					return nil, nil
+				}
+			}
+		}
+		if err := l.sleep(reattachInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// instanceRequest returns a follow request for the log of the current instance of the container,
+// from since or, when since is nil, the whole log of the new instance
+func (l LikeOptions) instanceRequest(ref corev1.ObjectReference, since *metav1.Time) (rest.ResponseWrapper, error) {
+	logOptions, ok := l.requestOptions().(*corev1.PodLogOptions)
+	if !ok {
+		return nil, errors.New("unexpected logs options object")
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	opts := logOptions.DeepCopy()
+	_, opts.Container = l.containerFromRef(ref)
+	// a new instance starts with an empty log, so read all of it
+	opts.TailLines = nil
+	opts.SinceSeconds = nil
+	opts.SinceTime = since
+	return clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts), nil
+}
+
+// sinceLastRestartRequest returns the request of the container referenced by ref from the start of its
+// current instance, which is its last restart or its start if it never restarted. A container that is not
+// running nor terminated, e.g. waiting to restart, keeps its request.
+func (l LikeOptions) sinceLastRestartRequest(ref corev1.ObjectReference, request rest.ResponseWrapper) (rest.ResponseWrapper, error) {
+	status, err := l.containerStatus(ref)
+	if err != nil {
+		return nil, err
+	}
+	var started metav1.Time
+	switch {
+	case status.State.Running != nil:
+		started = status.State.Running.StartedAt
+	case status.State.Terminated != nil:
+		started = status.State.Terminated.StartedAt
+	}
+	if started.IsZero() {
+		return request, nil
+	}
+	logOptions, ok := l.requestOptions().(*corev1.PodLogOptions)
+	if !ok {
+		return nil, errors.New("unexpected logs options object")
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	l.logger.Debug("streaming since the last restart", "namespace", ref.Namespace, "pod", ref.Name, "container", status.Name, "since", started)
+	opts := logOptions.DeepCopy()
+	opts.Container = status.Name
+	opts.SinceSeconds = nil
+	opts.SinceTime = &started
+	return clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts), nil
+}
+
+// consumePreviousInstance writes the filtered logs of the previous instance of the container to out,
+// then a separator telling when the container restarted to the notices of the stream.
+func (l LikeOptions) consumePreviousInstance(ref corev1.ObjectReference, out io.Writer) error {
+	status, err := l.containerStatus(ref)
+	if err != nil {
+		return err
+	}
+	if status.LastTerminationState.Terminated == nil {
+		fmt.Fprintf(l.ErrOut, "no previous instance of container %s in pod %s, skipping\n", status.Name, ref.Name)
+		return nil
+	}
+
+	logOptions, ok := l.Options.(*corev1.PodLogOptions)
+	if !ok {
+		return errors.New("unexpected logs options object")
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	opts := logOptions.DeepCopy()
+	opts.Container = status.Name
+	opts.Previous = true
+	opts.Follow = false
+	if err := endOfHead(l.ConsumeRequestFn(clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts), out)); err != nil {
+		return err
+	}
+
+	restartedAt := status.LastTerminationState.Terminated.FinishedAt
+	if status.State.Running != nil {
+		restartedAt = status.State.Running.StartedAt
+	}
+	_, err = fmt.Fprintf(l.noticeWriter(), "---- restarted at %s ----\n", restartedAt.Format(time.RFC3339))
+	return err
+}
@@ -0,0 +1,606 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var appRef = corev1.ObjectReference{Namespace: "test", Name: "api-1", FieldPath: "spec.containers{app}"}
+
+// restartedPod returns a pod whose app container runs again after restarts restarts
+func restartedPod(restarts int32, startedAt time.Time) *corev1.Pod {
+	pod := testPod("api-1", corev1.PodRunning, nil)
+	status := corev1.ContainerStatus{
+		Name:         "app",
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(startedAt)}},
+	}
+	if restarts > 0 {
+		status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{ExitCode: 137, FinishedAt: metav1.NewTime(startedAt.Add(-time.Second))}
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{status}
+	return &pod
+}
+
+// newRestartOptions returns options matching ERROR whose request of the current instance of the app container
+// is answered by api
+func newRestartOptions(t *testing.T, api *fakeAPI) (LikeOptions, *bytes.Buffer) {
+	t.Helper()
+	l, _, errOut := newFakeOptions(t, api)
+	l.Options = &corev1.PodLogOptions{Container: "app"}
+	l.patternRegexp = regexp.MustCompile("ERROR")
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	l.PreviousAndCurrent = true
+	return l, errOut
+}
+
+func TestPreviousAndCurrentOrdersInstances(t *testing.T) {
+	startedAt := time.Date(2024, 6, 12, 10, 4, 5, 0, time.UTC)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(1, startedAt)},
+		logs: map[string]string{
+			"/namespaces/test/pods/api-1/log?previous": "INFO starting\nERROR out of memory\n",
+			"/namespaces/test/pods/api-1/log":          "INFO starting again\nERROR still failing\n",
+		},
+	}
+	l, errOut := newRestartOptions(t, api)
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := clientset.CoreV1().Pods("test").GetLogs("api-1", &corev1.PodLogOptions{Container: "app"})
+
+	var out bytes.Buffer
+	if err := l.consumeRequest(appRef, request, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR out of memory\nERROR still failing\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got, want := errOut.String(), "---- restarted at 2024-06-12T10:04:05Z ----\n"; got != want {
+		t.Errorf("got separator %q, want %q", got, want)
+	}
+}
+
+func TestPreviousAndCurrentSeparatorIsNotAMatch(t *testing.T) {
+	command, received := execScript(t)
+	startedAt := time.Date(2024, 6, 12, 10, 4, 5, 0, time.UTC)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(1, startedAt)},
+		logs: map[string]string{
+			"/namespaces/test/pods/api-1/log?previous": "ERROR out of memory\n",
+			"/namespaces/test/pods/api-1/log":          "ERROR still failing\n",
+		},
+	}
+	l, errOut := newRestartOptions(t, api)
+	l.Output = outputJSON
+	l.lineExec = newLineExec(command, 0, io.Discard)
+	l.stats = newMatchCounts("container", nil)
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := clientset.CoreV1().Pods("test").GetLogs("api-1", &corev1.PodLogOptions{Container: "app"})
+
+	var out bytes.Buffer
+	if err := l.consumeRequest(appRef, request, l.writerFor(appRef, &out)); err != nil {
+		t.Fatal(err)
+	}
+	l.lineExec.Close()
+
+	records := decodeRecords(t, out.String())
+	if len(records) != 2 || records[0].Line != "ERROR out of memory" || records[1].Line != "ERROR still failing" {
+		t.Errorf("got records %+v, want the two matching lines only", records)
+	}
+	if got := strings.Count(readFile(t, received), "\n"); got != 2 {
+		t.Errorf("--exec ran %d times, want 2", got)
+	}
+	if got := l.stats.matched.get(l.sourceName(appRef)); got != 2 {
+		t.Errorf("--stats counted %d matches, want 2", got)
+	}
+	if !strings.Contains(errOut.String(), "---- restarted at") {
+		t.Errorf("the separator is not on stderr: %q", errOut.String())
+	}
+}
+
+func TestPreviousAndCurrentWithoutPreviousInstance(t *testing.T) {
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(0, time.Now())},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR first run\n"},
+	}
+	l, errOut := newRestartOptions(t, api)
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := clientset.CoreV1().Pods("test").GetLogs("api-1", &corev1.PodLogOptions{Container: "app"})
+
+	var out bytes.Buffer
+	if err := l.consumeRequest(appRef, request, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR first run\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.Contains(errOut.String(), "no previous instance of container app") {
+		t.Errorf("expected a note on stderr, got %q", errOut.String())
+	}
+	for _, query := range api.requests("/namespaces/test/pods/api-1/log") {
+		if strings.Contains(query, "previous=true") {
+			t.Errorf("the previous instance was requested although there is none: %q", query)
+		}
+	}
+}
+
+func shortReattachInterval(t *testing.T) {
+	interval := reattachInterval
+	reattachInterval = time.Millisecond
+	t.Cleanup(func() { reattachInterval = interval })
+}
+
+func succeededPod() *corev1.Pod {
+	pod := restartedPod(1, time.Now())
+	pod.Status.Phase = corev1.PodSucceeded
+	return pod
+}
+
+func TestFollowWithReattachAfterRestart(t *testing.T) {
+	shortReattachInterval(t)
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {
+			restartedPod(0, time.Now()),
+			restartedPod(1, time.Now()),
+			succeededPod(),
+		}},
+		logs: map[string]string{"/namespaces/test/pods/api-1/log": "INFO serving\nERROR crashed\n"},
+	}
+	l, errOut := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := l.followWithReattach(appRef, request, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR crashed\nERROR crashed\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got, want := errOut.String(), "=== container restarted (exit code 137) ===\n"; got != want {
+		t.Errorf("got marker %q, want %q", got, want)
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 2 {
+		t.Errorf("got %d log requests, want 2", got)
+	}
+}
+
+func TestRestartMarkerIsNotAMatch(t *testing.T) {
+	shortReattachInterval(t)
+	command, received := execScript(t)
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {
+			restartedPod(0, time.Now()),
+			restartedPod(1, time.Now()),
+			succeededPod(),
+		}},
+		logs: map[string]string{"/namespaces/test/pods/api-1/log": "ERROR crashed\n"},
+	}
+	l, errOut := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	l.Output = outputJSON
+	l.lineExec = newLineExec(command, 0, io.Discard)
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := l.consumeRequest(appRef, request, l.writerFor(appRef, &out)); err != nil {
+		t.Fatal(err)
+	}
+	l.lineExec.Close()
+
+	for _, record := range decodeRecords(t, out.String()) {
+		if record.Line != "ERROR crashed" {
+			t.Errorf("unexpected record %+v", record)
+		}
+	}
+	if lines := readFile(t, received); strings.Contains(lines, "restarted") || strings.Count(lines, "\n") != 2 {
+		t.Errorf("--exec ran for other lines than the two matching ones: %q", lines)
+	}
+	if !strings.Contains(errOut.String(), "=== container restarted (exit code 137) ===") {
+		t.Errorf("the marker is not on stderr: %q", errOut.String())
+	}
+}
+
+func TestFollowWithReattachReopensClosedStream(t *testing.T) {
+	shortReattachInterval(t)
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {
+			restartedPod(0, time.Now()),
+			// the stream was closed but the same instance still runs
+			restartedPod(0, time.Now()),
+			succeededPod(),
+		}},
+		logs: map[string]string{"/namespaces/test/pods/api-1/log": "ERROR timeout\n"},
+	}
+	l, _ := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	var out bytes.Buffer
+	go func() { done <- l.followWithReattach(appRef, request, &out) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("following a closed stream hangs")
+	}
+
+	if strings.Contains(out.String(), "restarted") {
+		t.Errorf("a restart marker was printed without a restart: %q", out.String())
+	}
+	queries := api.requests("/namespaces/test/pods/api-1/log")
+	if len(queries) != 2 {
+		t.Fatalf("got %d log requests, want the stream to be reopened once", len(queries))
+	}
+	if strings.Contains(queries[0], "sinceTime") || !strings.Contains(queries[1], "sinceTime") {
+		t.Errorf("the stream was not reopened from the time it was closed: %q", queries)
+	}
+}
+
+func TestFollowWithReattachStopsWaitingWhenInterrupted(t *testing.T) {
+	// the stream is reopened after a whole reattachInterval, unless the command is interrupted
+	interval := reattachInterval
+	reattachInterval = time.Hour
+	t.Cleanup(func() { reattachInterval = interval })
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(0, time.Now())},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR timeout\n"},
+	}
+	l, _ := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.ctx = ctx
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- l.followWithReattach(appRef, request, io.Discard) }()
+	// the pod was fetched once the stream ended, the container still running
+	for len(api.requests("/namespaces/test/pods/api-1")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("got %v, want %v", err, ErrInterrupted)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("an interrupted command waits for the stream to be reopened")
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 1 {
+		t.Errorf("got %d log requests, want the stream not to be reopened", got)
+	}
+}
+
+func TestFollowWithReattachStopsReconnectingWhenInterrupted(t *testing.T) {
+	// the dropped stream is reopened after a whole reconnectBackoff, unless the command is interrupted
+	backoff := reconnectBackoff
+	reconnectBackoff = time.Hour
+	t.Cleanup(func() { reconnectBackoff = backoff })
+	l, api, _ := newReconnectOptions(t, []string{"ERROR one\n"}, "ERROR two\n")
+	l.ReconnectMaxBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.ctx = ctx
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- l.followWithReattach(appRef, request, io.Discard) }()
+	// the pod was fetched once more after the stream failed, the backoff started
+	for len(api.requests("/namespaces/test/pods/api-1")) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("got %v, want %v", err, ErrInterrupted)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("an interrupted command waits for the dropped stream to be reopened")
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 1 {
+		t.Errorf("got %d log requests, want the stream not to be reopened", got)
+	}
+}
+
+// recordReconnectSleeps replaces the sleeps of the reconnections with a fake clock recording them
+func recordReconnectSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	sleep := reconnectSleep
+	reconnectSleep = func(_ LikeOptions, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	t.Cleanup(func() { reconnectSleep = sleep })
+	return &sleeps
+}
+
+func newReconnectOptions(t *testing.T, drops []string, log string) (LikeOptions, *fakeAPI, *bytes.Buffer) {
+	t.Helper()
+	shortReattachInterval(t)
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{"/namespaces/test/pods/api-1": {
+			restartedPod(0, time.Now()),
+			restartedPod(0, time.Now()),
+			restartedPod(0, time.Now()),
+			restartedPod(0, time.Now()),
+			succeededPod(),
+		}},
+		drops: map[string][]string{"/namespaces/test/pods/api-1/log": drops},
+		logs:  map[string]string{"/namespaces/test/pods/api-1/log": log},
+	}
+	l, errOut := newRestartOptions(t, api)
+	l.PreviousAndCurrent = false
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	l.ReconnectMaxAttempts = defaultReconnectMaxAttempts
+	l.ReconnectMaxBackoff = 3 * time.Second
+	return l, api, errOut
+}
+
+func TestFollowReconnectsDroppedStream(t *testing.T) {
+	sleeps := recordReconnectSleeps(t)
+	l, api, errOut := newReconnectOptions(t, []string{"ERROR one\n", "", ""}, "ERROR two\n")
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := l.followWithReattach(appRef, request, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR one\nERROR two\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the backoff doubles while the stream cannot be opened again, up to --reconnect-max-backoff
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !slices.Equal(*sleeps, want) {
+		t.Errorf("got sleeps %v, want %v", *sleeps, want)
+	}
+	queries := api.requests("/namespaces/test/pods/api-1/log")
+	if len(queries) != 4 || !strings.Contains(queries[3], "sinceTime") {
+		t.Errorf("got requests %q, want the stream to be reopened 3 times from where it failed", queries)
+	}
+	if want := "warning: the log stream of test/api-1/app failed: unexpected EOF, reconnecting in 2s (attempt 2 of 10)"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestReconnectStopsWaitingWhenInterrupted(t *testing.T) {
+	l, api, _ := newReconnectOptions(t, nil, "")
+	// the backoff of the last attempt is more than 8 minutes, unless the command is interrupted
+	l.ReconnectMaxBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.ctx = ctx
+	request := &watchedRequest{}
+	request.failed.Store(true)
+
+	done := make(chan error)
+	go func() { done <- l.reconnect(appRef, request, io.ErrUnexpectedEOF, defaultReconnectMaxAttempts) }()
+	// the pod was fetched, the backoff started
+	for len(api.requests("/namespaces/test/pods/api-1")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("got %v, want %v", err, ErrInterrupted)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("an interrupted command waits for the backoff of the reconnection")
+	}
+}
+
+func TestFollowResumesAfterLastLineRead(t *testing.T) {
+	recordReconnectSleeps(t)
+	dropped := "2024-06-12T10:00:01Z ERROR one\n2024-06-12T10:00:03.5Z ERROR two\n2024-06-12T10:00:03.5Z ERROR three\n"
+	for _, test := range []struct {
+		name string
+		// log is answered to the reopened request, the server only keeps the seconds of sinceTime
+		log        string
+		want       string
+		duplicates int
+		gaps       int
+	}{
+		{
+			name:       "no duplicate nor loss",
+			log:        "2024-06-12T10:00:03.5Z ERROR two\n2024-06-12T10:00:03.5Z ERROR three\n2024-06-12T10:00:03.5Z ERROR four\n2024-06-12T10:00:04Z ERROR five\n",
+			want:       "ERROR one\nERROR two\nERROR three\nERROR four\nERROR five\n",
+			duplicates: 2,
+		},
+		{
+			name: "rotated log",
+			log:  "2024-06-12T10:00:04Z ERROR five\n",
+			want: "ERROR one\nERROR two\nERROR three\nERROR five\n",
+			gaps: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, api, _ := newReconnectOptions(t, []string{dropped}, test.log)
+			// the pod runs until the reopened stream ends
+			api.sequences["/namespaces/test/pods/api-1"] = []runtime.Object{restartedPod(0, time.Now()), restartedPod(0, time.Now()), succeededPod()}
+			l.Follow = true
+			l.stats = newMatchCounts("container", nil)
+			request, err := l.instanceRequest(appRef, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := l.followWithReattach(appRef, request, &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			queries := api.requests("/namespaces/test/pods/api-1/log")
+			// the lines of 10:00:03 already read are dropped from the reopened stream
+			if len(queries) != 2 || !strings.Contains(queries[1], "sinceTime=2024-06-12T10%3A00%3A03Z") || !strings.Contains(queries[1], "timestamps=true") {
+				t.Errorf("got requests %q, want the stream to be reopened from the last line read", queries)
+			}
+			source := l.sourceName(appRef)
+			if got := l.stats.duplicates.get(source); got != test.duplicates {
+				t.Errorf("got %d duplicates dropped, want %d", got, test.duplicates)
+			}
+			if got := l.stats.gaps.get(source); got != test.gaps {
+				t.Errorf("got %d gaps, want %d", got, test.gaps)
+			}
+		})
+	}
+}
+
+func TestFollowGivesUpAfterReconnectMaxAttempts(t *testing.T) {
+	sleeps := recordReconnectSleeps(t)
+	l, _, _ := newReconnectOptions(t, []string{"", ""}, "ERROR never read\n")
+	l.ReconnectMaxAttempts = 1
+	request, err := l.instanceRequest(appRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := l.followWithReattach(appRef, request, &out); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want the error of the stream", err)
+	}
+	if len(*sleeps) != 1 {
+		t.Errorf("got sleeps %v, want a single reconnection", *sleeps)
+	}
+}
+
+func TestReconnectValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"-f", "--reconnect-max-attempts", "-1"},
+		{"-f", "--reconnect-max-backoff", "0s"},
+		{"--reconnect-max-attempts", "3"},
+		{"-f", "--no-reattach", "--reconnect-max-backoff", "1m"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	l := LikeOptions{ReconnectMaxBackoff: 30 * time.Second}
+	var got []time.Duration
+	for attempt := 1; attempt <= 7; attempt++ {
+		got = append(got, l.reconnectDelay(attempt))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSinceLastRestart(t *testing.T) {
+	startedAt := time.Date(2024, 6, 12, 10, 4, 5, 0, time.UTC)
+	for restarts, want := range map[int32]string{
+		2: "sinceTime=2024-06-12T10%3A04%3A05Z",
+		// a container that never restarted is read since it started
+		0: "sinceTime=2024-06-12T10%3A04%3A05Z",
+	} {
+		api := &fakeAPI{
+			objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": restartedPod(restarts, startedAt)},
+			logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR since the restart\n"},
+		}
+		l, _ := newRestartOptions(t, api)
+		l.PreviousAndCurrent = false
+		l.Options = &corev1.PodLogOptions{Container: "app", SinceSeconds: new(int64)}
+		request, err := l.sinceLastRestartRequest(appRef, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := l.consumeRequest(appRef, request, &out); err != nil {
+			t.Fatal(err)
+		}
+		queries := api.requests("/namespaces/test/pods/api-1/log")
+		if len(queries) != 1 || !strings.Contains(queries[0], want) || strings.Contains(queries[0], "sinceSeconds") {
+			t.Errorf("%d restarts: got queries %q, want %s", restarts, queries, want)
+		}
+	}
+}
+
+func TestSinceLastRestartOfWaitingContainer(t *testing.T) {
+	pod := restartedPod(3, time.Now())
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	api := &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": pod}}
+	l, _ := newRestartOptions(t, api)
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := clientset.CoreV1().Pods("test").GetLogs("api-1", &corev1.PodLogOptions{Container: "app"})
+	request, err := l.sinceLastRestartRequest(appRef, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request != original {
+		t.Error("the request of a waiting container was replaced")
+	}
+}
+
+func TestSinceLastRestartValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--since-last-restart", "--since", "1h"},
+		{"--since-last-restart", "--since-time", "2024-06-12T10:04:05Z"},
+		{"--since-last-restart", "--previous"},
+		{"--since-last-restart", "--previous-and-current"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// defaultResumeMaxAge is how old the entries of --resume-file can be before they are ignored
+const defaultResumeMaxAge = 24 * time.Hour
+
+// resumeEntry is the last line read from a source, as saved in --resume-file
+type resumeEntry struct {
+	Time time.Time `json:"time"`
+	// Seen is the number of lines read with exactly that timestamp, dropped when the source is read again
+	Seen int `json:"seen"`
+}
+
+// resumeFile is the content of --resume-file, the entries being keyed by source, e.g. test/api-1/app
+type resumeFile struct {
+	Sources map[string]resumeEntry `json:"sources"`
+}
+
+// resumeState is the mark of the last line read from every source, loaded from --resume-file when the command
+// starts and saved to it when it ends, so that the next run resumes every source right after that line
+type resumeState struct {
+	path  string
+	mu    sync.Mutex
+	marks map[string]*lastMark
+}
+
+// loadResumeState reads the marks of path, if it exists, ignoring the ones older than maxAge unless it is 0
+func loadResumeState(path string, maxAge time.Duration) (*resumeState, error) {
+	state := &resumeState{path: path, marks: map[string]*lastMark{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file resumeFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("invalid --resume-file %s: %w", path, err)
+	}
+	for source, entry := range file.Sources {
+		// a stale entry falls back to --since like a new source
+		if entry.Time.IsZero() || maxAge > 0 && time.Since(entry.Time) > maxAge {
+			continue
+		}
+		state.marks[source] = &lastMark{mark: backlogMark{time: entry.Time, seen: entry.Seen}}
+	}
+	return state, nil
+}
+
+// mark returns the mark of the last line read from source, recording the lines read from it from now on
+func (s *resumeState) mark(source string) *lastMark {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mark, ok := s.marks[source]
+	if !ok {
+		mark = &lastMark{}
+		s.marks[source] = mark
+	}
+	return mark
+}
+
+// save writes the marks of the sources a line was read from, in this run or a previous one, to the file.
+// It is written to a temporary file renamed over it, so that an interrupted save does not lose the marks.
+func (s *resumeState) save() error {
+	s.mu.Lock()
+	file := resumeFile{Sources: make(map[string]resumeEntry, len(s.marks))}
+	for source, mark := range s.marks {
+		if mark := mark.get(); !mark.time.IsZero() {
+			file.Sources[source] = resumeEntry{Time: mark.time, Seen: mark.seen}
+		}
+	}
+	s.mu.Unlock()
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// resumeRequest returns the request of the container referenced by ref from the last line read by a previous
+// run, or request when no line of that source was saved, read with --since like a new source
+func (l LikeOptions) resumeRequest(ref corev1.ObjectReference, request rest.ResponseWrapper) (rest.ResponseWrapper, error) {
+	mark := l.resume.mark(l.sourceName(ref)).get()
+	if mark.time.IsZero() {
+		return request, nil
+	}
+	logOptions, ok := l.requestOptions().(*corev1.PodLogOptions)
+	if !ok {
+		return nil, errors.New("unexpected logs options object")
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	l.logger.Debug("resuming from --resume-file", "namespace", ref.Namespace, "pod", ref.Name, "since", mark.time)
+	opts := logOptions.DeepCopy()
+	_, opts.Container = l.containerFromRef(ref)
+	// the server only keeps the seconds of sinceTime, the lines up to the mark are dropped by resumedRequest
+	opts.TailLines = nil
+	opts.SinceSeconds = nil
+	opts.SinceTime = &metav1.Time{Time: mark.time}
+	return clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts), nil
+}
+
+// lastMarkOf returns the mark recording the last line read from the container referenced by ref, the one of
+// --resume-file if set
+func (l LikeOptions) lastMarkOf(ref corev1.ObjectReference) *lastMark {
+	if l.resume == nil {
+		return &lastMark{}
+	}
+	return l.resume.mark(l.sourceName(ref))
+}
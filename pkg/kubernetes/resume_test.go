@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestResumeStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	state, err := loadResumeState(path, defaultResumeMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Now().UTC().Add(-time.Minute)
+	state.mark("test/api-1/app").see(seen)
+	state.mark("test/api-1/app").see(seen)
+	// no line was read from it, it has nothing to resume from
+	state.mark("test/api-2/app")
+	if err := state.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadResumeState(path, defaultResumeMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mark := loaded.mark("test/api-1/app").get(); !mark.time.Equal(seen) || mark.seen != 2 {
+		t.Errorf("got mark %+v, want %s seen twice", mark, seen)
+	}
+	if _, ok := loaded.marks["test/api-2/app"]; ok {
+		t.Error("a source without any line read was saved")
+	}
+}
+
+func TestResumeStateIgnoresStaleEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	stale := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339Nano)
+	if err := os.WriteFile(path, []byte(`{"sources": {"test/api-1/app": {"time": "`+stale+`", "seen": 1}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadResumeState(path, defaultResumeMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.marks) > 0 {
+		t.Errorf("got marks %v, want the entry older than %s ignored", state.marks, defaultResumeMaxAge)
+	}
+	if state, err = loadResumeState(path, 0); err != nil || len(state.marks) != 1 {
+		t.Errorf("got marks %v and %v, want the entry kept without a maximum age", state.marks, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadResumeState(path, 0); err == nil || !strings.Contains(err.Error(), "invalid --resume-file") {
+		t.Errorf("got %v, want an invalid --resume-file", err)
+	}
+}
+
+func TestResumeFileAppliesSinceTimePerSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	if err := os.WriteFile(path, []byte(`{"sources": {"test/api-1/app": {"time": "2024-06-12T10:04:05.000000001Z", "seen": 1}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	api1, api2 := testPod("api-1", corev1.PodRunning, nil), testPod("api-2", corev1.PodRunning, nil)
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": &api1, "/namespaces/test/pods/api-2": &api2},
+		logs: map[string]string{
+			"/namespaces/test/pods/api-1/log": "2024-06-12T10:04:05.000000001Z ERROR old\n2024-06-12T10:04:06Z ERROR new\n",
+			"/namespaces/test/pods/api-2/log": "2024-06-12T10:04:05.5Z ERROR other\n",
+		},
+	}
+	l, cmd, out, _ := newFakeCommand(t, api)
+	flags := []string{"--pattern", "ERROR", "--no-banner", "--since", "1h", "--resume-file", path, "--resume-max-age", "0"}
+	if err := completeFlags(l, cmd, flags, "api-1", "api-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.String(); !strings.Contains(got, "ERROR new") || !strings.Contains(got, "ERROR other") || strings.Contains(got, "ERROR old") {
+		t.Errorf("got %q, want the lines after the saved one of api-1 and every line of api-2", got)
+	}
+	if query := strings.Join(api.requests("/namespaces/test/pods/api-1/log"), "&"); !strings.Contains(query, "sinceTime=2024-06-12T10%3A04%3A05Z") || strings.Contains(query, "sinceSeconds") {
+		t.Errorf("got query %q, want api-1 read from its saved line", query)
+	}
+	if query := strings.Join(api.requests("/namespaces/test/pods/api-2/log"), "&"); !strings.Contains(query, "sinceSeconds=3600") {
+		t.Errorf("got query %q, want api-2 read with --since", query)
+	}
+
+	state, err := loadResumeState(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for source, want := range map[string]string{"test/api-1/app": "2024-06-12T10:04:06Z", "test/api-2/app": "2024-06-12T10:04:05.5Z"} {
+		if got := state.mark(source).get().time.Format(time.RFC3339Nano); got != want {
+			t.Errorf("%s: saved %s, want %s", source, got, want)
+		}
+	}
+}
+
+func TestResumeFileValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--resume-file", "resume.json", "--previous"},
+		{"--resume-file", "resume.json", "--ordered-backlog"},
+		{"--resume-max-age", "1h"},
+		{"--resume-file", "resume.json", "--resume-max-age", "-1h"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, append(flags, "--pattern", "ERROR"), "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("%v: expected an error", flags)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// defaultRetries is how many times a request failing with a transient error is retried
+	defaultRetries = 3
+	// retryBackoff is the wait before the first retry, doubled after every failed attempt up to maxRetryBackoff
+	retryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff = 10 * time.Second
+)
+
+// retrySleep waits between two attempts, returning ErrInterrupted when the command is interrupted meanwhile
+var retrySleep = LikeOptions.sleep
+
+// retryDelay returns the wait before the attempt-th retry: the exponential backoff, half of it random so that the
+// commands started together, e.g. by a script, do not retry together
+func retryDelay(attempt int) time.Duration {
+	delay := retryBackoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// isRetriable tells whether err is a transient error of the API server or of the connection to it: a timeout,
+// too many requests, an error 5xx or a refused or dropped connection. Errors like NotFound or Forbidden are not.
+func isRetriable(err error) bool {
+	// the context of the command or of --timeout ended, retrying would not help
+	if err == nil || errors.Is(err, ErrInterrupted) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		code := status.Status().Code
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError ||
+			apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err)
+	}
+	// e.g. a *url.Error, a net.Error or the connectTimeoutError of --request-timeout
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	return utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
+
+// retry calls request until it succeeds, fails with an error that is not transient, or --retries retries failed
+// too. The retries are reported on ErrOut, the last error is returned as is.
+func (l LikeOptions) retry(what string, request func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := request()
+		if attempt > l.Retries || !isRetriable(err) {
+			return err
+		}
+		delay := retryDelay(attempt)
+		fmt.Fprintf(l.ErrOut, "warning: %s failed: %v, retrying in %s (retry %d of %d)\n", what, err, delay.Round(time.Millisecond), attempt, l.Retries)
+		if err := retrySleep(l, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// retriedRequest retries opening its stream on a transient error
+type retriedRequest struct {
+	rest.ResponseWrapper
+	l      LikeOptions
+	source string
+}
+
+func (r *retriedRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	var stream io.ReadCloser
+	err := r.l.retry("opening the log stream of "+r.source, func() (err error) {
+		stream, err = r.ResponseWrapper.Stream(ctx)
+		return err
+	})
+	return stream, err
+}
@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// recordRetrySleeps makes the retries wait for nothing and returns the waits they asked for
+func recordRetrySleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var sleeps []time.Duration
+	sleep := retrySleep
+	retrySleep = func(_ LikeOptions, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = sleep })
+	return &sleeps
+}
+
+func TestIsRetriable(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: apierrors.NewServiceUnavailable("unavailable"), want: true},
+		{err: apierrors.NewTooManyRequests("slow down", 1), want: true},
+		{err: apierrors.NewInternalError(errors.New("boom")), want: true},
+		{err: apierrors.NewTimeoutError("timeout", 1), want: true},
+		{err: fmt.Errorf("listing: %w", refused), want: true},
+		{err: connectTimeoutError{timeout: time.Second}, want: true},
+		{err: apierrors.NewNotFound(pods, "api-1")},
+		{err: apierrors.NewForbidden(pods, "api-1", errors.New("denied"))},
+		{err: ErrInterrupted},
+		{err: context.DeadlineExceeded},
+		{err: errors.New("expected a resource")},
+	}
+	for _, test := range tests {
+		if got := isRetriable(test.err); got != test.want {
+			t.Errorf("%v: got retriable %t, want %t", test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryDelayGrowsWithJitter(t *testing.T) {
+	for attempt, base := range map[int]time.Duration{1: retryBackoff, 2: 2 * retryBackoff, 3: 4 * retryBackoff, 10: maxRetryBackoff} {
+		if delay := retryDelay(attempt); delay < base/2 || delay > base {
+			t.Errorf("attempt %d: got %s, want between %s and %s", attempt, delay, base/2, base)
+		}
+	}
+}
+
+func TestResolveRetriesTransientErrors(t *testing.T) {
+	sleeps := recordRetrySleeps(t)
+	api := newPodAPI("api-1")
+	api.failures = map[string]int{"/namespaces/test/pods/api-1": 2}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if pod, ok := l.Object.(*corev1.Pod); !ok || pod.Name != "api-1" {
+		t.Errorf("got %v, want pod api-1", l.Object)
+	}
+	if got := len(*sleeps); got != 2 {
+		t.Errorf("got %d retries, want 2", got)
+	}
+	if got := strings.Count(errOut.String(), "warning: resolving the pods failed"); got != 2 {
+		t.Errorf("got %q, want a warning per retry", errOut.String())
+	}
+}
+
+func TestResolveFailsFastOnNotFound(t *testing.T) {
+	sleeps := recordRetrySleeps(t)
+	api := &fakeAPI{}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	err := completeFlags(l, cmd, []string{"--pattern", "ERROR"}, "api-1")
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("got %v, want the NotFound error", err)
+	}
+	if len(*sleeps) > 0 || len(api.requests("/namespaces/test/pods/api-1")) != 1 || strings.Contains(errOut.String(), "retrying") {
+		t.Errorf("a NotFound error was retried: %q", errOut.String())
+	}
+}
+
+func TestResolveGivesUpAfterRetries(t *testing.T) {
+	sleeps := recordRetrySleeps(t)
+	api := newPodAPI("api-1")
+	api.failures = map[string]int{"/namespaces/test/pods/api-1": 5}
+	l, cmd, _, _ := newFakeCommand(t, api)
+	err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--retries", "1"}, "api-1")
+	if !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("got %v, want the last error", err)
+	}
+	if got := len(*sleeps); got != 1 {
+		t.Errorf("got %d retries, want 1", got)
+	}
+}
+
+func TestStreamRetriesTransientErrors(t *testing.T) {
+	sleeps := recordRetrySleeps(t)
+	api := newPodAPI("api-1")
+	api.logs = map[string]string{"/namespaces/test/pods/api-1/log": "ERROR boom\nINFO ok\n"}
+	api.failures = map[string]int{"/namespaces/test/pods/api-1/log": 2}
+	l, cmd, out, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--no-banner"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := len(*sleeps); got != 2 {
+		t.Errorf("got %d retries, want 2", got)
+	}
+	if want := "warning: opening the log stream of test/api-1/app failed"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+}
@@ -0,0 +1,156 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultMaxFiles is the number of rotated files of --output-file kept by default
+const defaultMaxFiles = 5
+
+// parseFileSize parses a size like 50MB, 50M or 64Mi into bytes. The B suffix is optional.
+func parseFileSize(size string) (int64, error) {
+	q, err := resource.ParseQuantity(strings.TrimSuffix(size, "B"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q, e.g. 50MB or 64Mi", size)
+	}
+	return q.Value(), nil
+}
+
+// rotatingFile appends the lines written to it to a file. When the file would grow beyond maxSize, it is
+// renamed to PATH.1, the previous PATH.1 to PATH.2 and so on, keeping maxFiles old files.
+// A maxSize of 0 never rotates.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	size int64
+	done chan struct{}
+}
+
+// newRotatingFile opens path for appending
+func newRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles, done: make(chan struct{})}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	flushUntilClosed(f.done, outputFlushInterval, f.flush)
+	return f, nil
+}
+
+// newTeeFile truncates path, like tee, then opens it for writing without rotating it
+func newTeeFile(path string) (*rotatingFile, error) {
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		return nil, err
+	}
+	return newRotatingFile(path, 0, 0)
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.w = bufio.NewWriter(file)
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.w.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate closes the file, shifts the old files and opens a new empty file
+func (f *rotatingFile) rotate() error {
+	if err := f.w.Flush(); err != nil {
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if f.maxFiles > 0 {
+		for i := f.maxFiles - 1; i > 0; i-- {
+			if err := os.Rename(rotatedName(f.path, i), rotatedName(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(f.path, rotatedName(f.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func rotatedName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+func (f *rotatingFile) flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		f.w.Flush()
+	}
+}
+
+// syncEvery flushes the file and fsyncs it every interval until it is closed, so that the lines of a long follow
+// reach the disk also when the node crashes, and not only when the command ends
+func (f *rotatingFile) syncEvery(interval time.Duration) {
+	flushUntilClosed(f.done, interval, f.sync)
+}
+
+func (f *rotatingFile) sync() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil && f.w.Flush() == nil {
+		f.file.Sync()
+	}
+}
+
+// Close flushes and closes the file, once
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	close(f.done)
+	file := f.file
+	f.file = nil
+	if err := f.w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,211 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotatingFileKeepsMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	// every file holds two lines of 8 bytes
+	f, err := newRotatingFile(path, 16, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		if _, err := fmt.Fprintf(f, "line %02d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		path:                 "line 07\n",
+		rotatedName(path, 1): "line 05\nline 06\n",
+		rotatedName(path, 2): "line 03\nline 04\n",
+	}
+	for name, content := range want {
+		if got := readFile(t, name); got != content {
+			t.Errorf("%s: got %q, want %q", filepath.Base(name), got, content)
+		}
+	}
+	if _, err := os.Stat(rotatedName(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files, got %s: %v", rotatedName(path, 3), err)
+	}
+}
+
+func TestRotatingFileWithoutOldFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	f, err := newRotatingFile(path, 16, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(f, "line %02d\n", i)
+	}
+	f.Close()
+
+	if got := readFile(t, path); got != "line 03\n" {
+		t.Errorf("got %q, want the last line only", got)
+	}
+	if _, err := os.Stat(rotatedName(path, 1)); !os.IsNotExist(err) {
+		t.Errorf("expected no rotated file with --max-files 0, got %v", err)
+	}
+}
+
+func TestRotatingFileAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	if err := os.WriteFile(path, []byte("line 01\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := newRotatingFile(path, 16, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(f, "line 02\nline 03\n")
+	f.Close()
+
+	// the existing size counts, so the second write rotates
+	if got := readFile(t, rotatedName(path, 1)); got != "line 01\n" {
+		t.Errorf("got rotated %q, want the existing line", got)
+	}
+	if got := readFile(t, path); got != "line 02\nline 03\n" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := f.Write([]byte("line 04\n")); err != os.ErrClosed {
+		t.Errorf("expected a write after Close to fail with ErrClosed, got %v", err)
+	}
+}
+
+func TestRotatingFileConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	f, err := newRotatingFile(path, 256, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				fmt.Fprintf(f, "writer %d line %02d\n", w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	files, _ := filepath.Glob(path + "*")
+	for _, name := range files {
+		content := readFile(t, name)
+		if len(content) > 256 {
+			t.Errorf("%s has %d bytes, more than the limit", filepath.Base(name), len(content))
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(content, "\n"), "\n")...)
+	}
+	slices.Sort(lines)
+	var want []string
+	for w := 0; w < 4; w++ {
+		for i := 0; i < 50; i++ {
+			want = append(want, fmt.Sprintf("writer %d line %02d", w, i))
+		}
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("lines were lost or split across the files: got %d lines, want %d", len(lines), len(want))
+	}
+}
+
+func TestParseFileSize(t *testing.T) {
+	for size, want := range map[string]int64{"50MB": 50_000_000, "50M": 50_000_000, "64Mi": 64 << 20, "1024": 1024} {
+		got, err := parseFileSize(size)
+		if err != nil || got != want {
+			t.Errorf("parseFileSize(%q) = %d, %v, want %d", size, got, err, want)
+		}
+	}
+	if _, err := parseFileSize("lots"); err == nil {
+		t.Error("expected an invalid size error")
+	}
+}
+
+func TestTeeWritesStdoutAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	if err := os.WriteFile(path, []byte("an old line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	api := newPodAPI("api-1")
+	api.logs = map[string]string{"/namespaces/test/pods/api-1/log": "INFO starting\nERROR boom\n"}
+	l, cmd, out, _ := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--tee", path}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	useFakeLogs(t, l)
+	if err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR boom\n"; got != want {
+		t.Errorf("got %q on stdout, want %q", got, want)
+	}
+	// like tee, the file is truncated first
+	if got, want := readFile(t, path), "ERROR boom\n"; got != want {
+		t.Errorf("got %q in the file, want %q", got, want)
+	}
+}
+
+func TestTeeValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--tee", "a.log", "--output-file", "b.log"},
+		{"--tee", "a.log", "--output-dir", "logs"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
+
+func TestRotatingFileSyncEvery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.log")
+	f, err := newRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.syncEvery(10 * time.Millisecond)
+	fmt.Fprintln(f, "ERROR boom")
+
+	// the line reaches the file without waiting for the flush of every second nor for Close
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for readFile(t, path) != "ERROR boom\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want the line synced", readFile(t, path))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
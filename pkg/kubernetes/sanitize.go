@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// needsEscape reports whether the line has a control character that sanitizeLine escapes
+func needsEscape(line []byte) bool {
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\t' || c == '\n':
+		case c == '\r' && i+1 < len(line) && line[i+1] == '\n':
+		case c < 0x20 || c == 0x7f:
+			return true
+		case c == 0xc2 && i+1 < len(line) && line[i+1] >= 0x80 && line[i+1] <= 0x9f:
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeLine replaces the control characters of the line with visible escapes, e.g. ESC with \x1b, so that
+// the escape sequences written by an application, like moving the cursor or retitling the window, are printed
+// instead of run by the terminal. Tabs and line breaks are kept.
+func sanitizeLine(line []byte) []byte {
+	if !needsEscape(line) {
+		return line
+	}
+	sanitized := make([]byte, 0, len(line)+16)
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		switch {
+		case r == '\t' || r == '\n':
+			sanitized = append(sanitized, line[i])
+		case r == '\r' && i+1 < len(line) && line[i+1] == '\n':
+			sanitized = append(sanitized, line[i])
+		case r < 0x20 || r == 0x7f:
+			sanitized = fmt.Appendf(sanitized, `\x%02x`, r)
+		case r >= 0x80 && r <= 0x9f && size > 1:
+			sanitized = fmt.Appendf(sanitized, `\u%04x`, r)
+		default:
+			sanitized = append(sanitized, line[i:i+size]...)
+		}
+		i += size
+	}
+	return sanitized
+}
+
+// escapesControls tells whether the control characters of the printed lines are escaped, unless --raw-output
+// is given or --no-filter prints the lines exactly as kubectl logs would
+func (l LikeOptions) escapesControls() bool {
+	return !l.RawOutput && !l.NoFilter
+}
+
+// sanitizingWriter escapes the control characters of the lines written to it
+type sanitizingWriter struct {
+	writer io.Writer
+}
+
+func (sw *sanitizingWriter) Write(p []byte) (int, error) {
+	if _, err := sw.writer.Write(sanitizeLine(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
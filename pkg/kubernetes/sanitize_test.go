@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSanitizeLine(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want string
+	}{
+		{"plain\tline\n", "plain\tline\n"},
+		{"windows line\r\n", "windows line\r\n"},
+		// cursor movements, clearing the screen and retitling the window
+		{"\x1b[2J\x1b[H\x1b[10;20Hspoofed\n", `\x1b[2J\x1b[H\x1b[10;20Hspoofed` + "\n"},
+		{"\x1b]0;pwned\x07title\n", `\x1b]0;pwned\x07title` + "\n"},
+		{"progress\rdone\n", `progress\x0ddone` + "\n"},
+		{"null\x00del\x7f\n", `null\x00del\x7f` + "\n"},
+		// the C1 CSI in UTF-8, while other characters are kept
+		{"\u009b2Jhéllo\n", `\u009b2Jhéllo` + "\n"},
+		{"{\n  \"a\": 1\n}\n", "{\n  \"a\": 1\n}\n"},
+	} {
+		if got := string(sanitizeLine([]byte(test.line))); got != test.want {
+			t.Errorf("sanitizeLine(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestControlCharactersEscapedBeforeColors(t *testing.T) {
+	logs := map[string]string{"api-1": "\x1b[1A\x1b[2KERROR boom\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.colorize = true
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `\x1b[1A\x1b[2K`+sgrMatch+"ERROR"+sgrReset+" boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	l.RawOutput = true
+	out.Reset()
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "\x1b[1A\x1b[2K"+sgrMatch+"ERROR"+sgrReset+" boom\n"; got != want {
+		t.Errorf("with --raw-output got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// severity is the level of a log line, ordered from the least to the most severe
+type severity int
+
+const (
+	severityUnknown severity = iota
+	severityTrace
+	severityDebug
+	severityInfo
+	severityWarning
+	severityError
+	severityFatal
+)
+
+// severityParser detects the severity of a log line, returning severityUnknown if it has none
+type severityParser func(line []byte) severity
+
+var (
+	// levelPrefixRegexp matches a level token at the start of a line, optionally after the timestamp of the
+	// kubelet and the one of the application, e.g. "ERROR something failed" or
+	// "2024-06-12T10:04:05Z [warn] disk is almost full". Level words further in the line are not levels,
+	// e.g. "GET /info: 200".
+	levelPrefixRegexp = regexp.MustCompile(`(?i)^(?:\d{4}[-/]\d{2}[-/]\d{2}(?:[T ]\d{2}:\d{2}:\d{2}\S*)?\s+){0,2}\[?(trace|debug|info|warn|warning|error|err|fatal|panic|critical|crit)\]?[\s:]`)
+	// logfmtLevelRegexp matches the level key of logfmt lines, e.g. `level=error msg="something failed"`
+	logfmtLevelRegexp = regexp.MustCompile(`(?i)(?:^|\s)(?:level|lvl|severity)="?(\w+)"?`)
+	// jsonLevelRegexp matches the level field of JSON lines, e.g. `{"level":"error","msg":"something failed"}`
+	jsonLevelRegexp = regexp.MustCompile(`(?i)"(?:level|lvl|severity)"\s*:\s*"(\w+)"`)
+
+	severityFormats = []string{"auto", "klog", "level", "logfmt", "json"}
+)
+
+// parseSeverity parses a severity name such as "warning" or its klog letter "W"
+func parseSeverity(s string) (severity, error) {
+	switch strings.ToUpper(s) {
+	case "TRACE":
+		return severityTrace, nil
+	case "DEBUG":
+		return severityDebug, nil
+	case "I", "INFO":
+		return severityInfo, nil
+	case "W", "WARN", "WARNING":
+		return severityWarning, nil
+	case "E", "ERR", "ERROR":
+		return severityError, nil
+	case "F", "FATAL", "PANIC", "CRIT", "CRITICAL":
+		return severityFatal, nil
+	}
+	return severityUnknown, fmt.Errorf("unknown severity %q, must be one of trace, debug, info, warning, error or fatal", s)
+}
+
+// newSeverityParser returns the parser for the given severity format.
+// The "auto" format tries klog, json, logfmt and level prefixes in that order.
+func newSeverityParser(format string, timestamps bool) (severityParser, error) {
+	klog := func(line []byte) severity {
+		entry, _ := parseKlogLine(line, timestamps)
+		return entry.severity
+	}
+	level := regexpSeverityParser(levelPrefixRegexp)
+	logfmt := regexpSeverityParser(logfmtLevelRegexp)
+	json := regexpSeverityParser(jsonLevelRegexp)
+
+	switch format {
+	case "klog":
+		return klog, nil
+	case "level":
+		return level, nil
+	case "logfmt":
+		return logfmt, nil
+	case "json":
+		return json, nil
+	case "auto":
+		return func(line []byte) severity {
+			for _, parse := range []severityParser{klog, json, logfmt, level} {
+				if s := parse(line); s != severityUnknown {
+					return s
+				}
+			}
+			return severityUnknown
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown severity format %q, must be one of %s", format, strings.Join(severityFormats, ", "))
+}
+
+// regexpSeverityParser returns a parser reading the severity from the first submatch of re
+func regexpSeverityParser(re *regexp.Regexp) severityParser {
+	return func(line []byte) severity {
+		matches := re.FindSubmatch(line)
+		if len(matches) != 2 {
+			return severityUnknown
+		}
+		s, err := parseSeverity(string(matches[1]))
+		if err != nil {
+			return severityUnknown
+		}
+		return s
+	}
+}
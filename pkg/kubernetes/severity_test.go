@@ -0,0 +1,59 @@
+package kubernetes
+
+import "testing"
+
+func TestSeverityFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		line   string
+		want   severity
+	}{
+		{format: "klog", line: "E0612 10:04:05.123456       1 main.go:12] boom", want: severityError},
+		{format: "klog", line: "W0612 10:04:05.123456       1 main.go:12] slow", want: severityWarning},
+		{format: "klog", line: "ERROR not a klog header"},
+		{format: "level", line: "ERROR something failed", want: severityError},
+		{format: "level", line: "warn: disk is almost full", want: severityWarning},
+		{format: "level", line: "2024-06-12T10:04:05Z [debug] cache hit", want: severityDebug},
+		{format: "level", line: "2024-06-12 10:04:05,123 FATAL out of memory", want: severityFatal},
+		{format: "level", line: "2024-06-12T10:04:05.123456789Z 2024-06-12T10:04:05Z INFO starting", want: severityInfo},
+		{format: "level", line: "GET /info: 200"},
+		{format: "level", line: "user warn: x"},
+		{format: "level", line: "information leaked"},
+		{format: "logfmt", line: `ts=2024-06-12T10:04:05Z level=error msg="boom"`, want: severityError},
+		{format: "logfmt", line: `lvl="warn" msg=slow`, want: severityWarning},
+		{format: "logfmt", line: "ERROR not logfmt"},
+		{format: "json", line: `{"level":"error","msg":"boom"}`, want: severityError},
+		{format: "json", line: `{"msg":"slow","severity":"WARNING"}`, want: severityWarning},
+		{format: "json", line: "level=error"},
+		{format: "auto", line: "I0612 10:04:05.123456       1 main.go:12] starting", want: severityInfo},
+		{format: "auto", line: `{"level":"debug"}`, want: severityDebug},
+		{format: "auto", line: "level=fatal", want: severityFatal},
+		{format: "auto", line: "[trace] entering", want: severityTrace},
+		{format: "auto", line: "GET /error: 500"},
+	}
+	for _, test := range tests {
+		parse, err := newSeverityParser(test.format, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parse([]byte(test.line + "\n")); got != test.want {
+			t.Errorf("%s: %q: got severity %d, want %d", test.format, test.line, got, test.want)
+		}
+	}
+}
+
+func TestSeverityFormatKlogWithTimestamps(t *testing.T) {
+	parse, err := newSeverityParser("klog", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parse([]byte("2024-06-12T10:04:05.123456789Z E0612 10:04:05.123456       1 main.go:12] boom\n")); got != severityError {
+		t.Errorf("got severity %d, want %d", got, severityError)
+	}
+}
+
+func TestUnknownSeverityFormat(t *testing.T) {
+	if _, err := newSeverityParser("syslog", false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
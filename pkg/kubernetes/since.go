@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sinceTimeLayouts are the layouts --since-time accepts besides RFC3339, in the local timezone
+var sinceTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// sinceValue replaces the --since flag of kubectl logs, so that a duration in days is accepted and a duration
+// without unit is rejected with an example instead of the error of time.ParseDuration
+type sinceValue struct {
+	since *time.Duration
+}
+
+func (v *sinceValue) String() string {
+	return v.since.String()
+}
+
+func (v *sinceValue) Set(s string) error {
+	since, err := parseSince(s)
+	if err != nil {
+		return err
+	}
+	*v.since = since
+	return nil
+}
+
+func (v *sinceValue) Type() string {
+	return "duration"
+}
+
+// parseSince parses a duration of --since, e.g. 90m, 1h30m, 1.5h, or in days like 2d or 1d12h. 0 means no limit.
+func parseSince(s string) (time.Duration, error) {
+	if s != "0" && len(s) > 0 && strings.Trim(s, "0123456789.") == "" {
+		return 0, fmt.Errorf("missing unit in %q, e.g. %ss or %sm", s, s, s)
+	}
+	invalid := fmt.Errorf("invalid duration %q, e.g. 90m, 1h30m, 1.5h or 2d", s)
+	var days time.Duration
+	hours := s
+	if number, rest, found := strings.Cut(s, "d"); found {
+		n, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, invalid
+		}
+		days, hours = time.Duration(n*float64(24*time.Hour)), cmp.Or(rest, "0s")
+	}
+	since, err := time.ParseDuration(hours)
+	if err != nil {
+		return 0, invalid
+	}
+	since += days
+	switch {
+	case since < 0:
+		return 0, errors.New("must be greater than 0, e.g. 1h")
+	case since > 0 && since < time.Second:
+		return 0, errors.New("must be at least 1s, the logs are requested by the second")
+	}
+	return since, nil
+}
+
+// parseSinceTime parses a time of --since-time, RFC3339 or a date and time without timezone in the local one, and
+// returns it in RFC3339 as kubectl logs expects it
+func parseSinceTime(s string) (string, error) {
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return s, nil
+	}
+	for _, layout := range sinceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("invalid --since-time %q, must be a RFC3339 time or a local date and time, e.g. 2024-06-12T10:04:05Z, 2024-06-12T10:04:05 or 2024-06-12", s)
+}
+
+// completeSince checks --since and --since-time before kubectl logs parses them and before any request, and
+// normalizes --since-time to RFC3339
+func (l *LikeOptions) completeSince() error {
+	if len(l.SinceTime) == 0 {
+		return nil
+	}
+	if l.SinceSeconds != 0 {
+		return fmt.Errorf("--since and --since-time cannot be used together, e.g. --since 1h or --since-time 2024-06-12T10:04:05Z")
+	}
+	sinceTime, err := parseSinceTime(l.SinceTime)
+	if err != nil {
+		return err
+	}
+	l.SinceTime = sinceTime
+	return nil
+}
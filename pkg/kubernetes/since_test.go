@@ -0,0 +1,104 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr string
+	}{
+		{value: "90m", want: 90 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "1.5h", want: 90 * time.Minute},
+		{value: "2d", want: 48 * time.Hour},
+		{value: "1d12h", want: 36 * time.Hour},
+		{value: "0s"},
+		{value: "0"},
+		{value: "90", wantErr: `missing unit in "90", e.g. 90s or 90m`},
+		{value: "1.5", wantErr: `missing unit in "1.5"`},
+		{value: "an hour", wantErr: `invalid duration "an hour", e.g. 90m, 1h30m, 1.5h or 2d`},
+		{value: "xd", wantErr: `invalid duration "xd"`},
+		{value: "2d3", wantErr: `invalid duration "2d3"`},
+		{value: "-1h", wantErr: "must be greater than 0"},
+		{value: "500ms", wantErr: "must be at least 1s"},
+	}
+	for _, test := range tests {
+		got, err := parseSince(test.value)
+		switch {
+		case len(test.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+			t.Errorf("%q: got %v, want an error containing %q", test.value, err, test.wantErr)
+		case len(test.wantErr) == 0 && err != nil:
+			t.Errorf("%q: got %v", test.value, err)
+		case got != test.want:
+			t.Errorf("%q: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseSinceTime(t *testing.T) {
+	local := time.Date(2024, 6, 12, 10, 4, 5, 0, time.Local).Format(time.RFC3339)
+	midnight := time.Date(2024, 6, 12, 0, 0, 0, 0, time.Local).Format(time.RFC3339)
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "2024-06-12T10:04:05Z", want: "2024-06-12T10:04:05Z"},
+		{value: "2024-06-12T10:04:05.123+02:00", want: "2024-06-12T10:04:05.123+02:00"},
+		{value: "2024-06-12T10:04:05", want: local},
+		{value: "2024-06-12 10:04:05", want: local},
+		{value: "2024-06-12", want: midnight},
+		{value: "2024-13-12", wantErr: true},
+		{value: "12/06/2024", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseSinceTime(test.value)
+		switch {
+		case test.wantErr && (err == nil || !strings.Contains(err.Error(), "invalid --since-time")):
+			t.Errorf("%q: got %v, want an error naming --since-time", test.value, err)
+		case !test.wantErr && err != nil:
+			t.Errorf("%q: got %v", test.value, err)
+		case got != test.want:
+			t.Errorf("%q: got %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestSinceFlags(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	err := completeFlags(l, cmd, []string{"--since", "90", "--pattern", "ERROR"}, "api-1")
+	if err == nil || !strings.Contains(err.Error(), `"--since" flag: missing unit in "90", e.g. 90s or 90m`) {
+		t.Errorf("got %v, want the error to name --since with an example", err)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--since", "2d", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.SinceSeconds; got != 48*time.Hour {
+		t.Errorf("got --since %s, want 48h", got)
+	}
+
+	// the conflict is reported before resolving the pod, which does not exist
+	api := &fakeAPI{}
+	l, cmd, _, _ = newFakeCommand(t, api)
+	err = completeFlags(l, cmd, []string{"--since", "1h", "--since-time", "2024-06-12", "--pattern", "ERROR"}, "api-1")
+	if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), "--since and --since-time cannot be used together") {
+		t.Errorf("got %v, want the conflict of --since and --since-time", err)
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1")); got != 0 {
+		t.Errorf("got %d requests, want the conflict reported before any", got)
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--since-time", "2024-06-12T10:04:05Z", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,155 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// statsSnapshot prints the counts of the followed streams on the signals of statsSignals, SIGUSR1 and SIGINFO
+// (Ctrl-T) where they exist, without stopping them. Unlike --stats, the streams only add to atomic counters, so
+// that following without asking for any statistics stays as cheap as before.
+type statsSnapshot struct {
+	out   io.Writer
+	start time.Time
+
+	mu      sync.Mutex
+	sources map[string]*sourceCounts
+}
+
+// sourceCounts are the counts of the streams of a single source, updated while the snapshot reads them
+type sourceCounts struct {
+	read       atomic.Int64
+	matched    atomic.Int64
+	reconnects atomic.Int64
+}
+
+func newStatsSnapshot(out io.Writer) *statsSnapshot {
+	return &statsSnapshot{out: out, start: time.Now(), sources: map[string]*sourceCounts{}}
+}
+
+// source returns the counts of source, created on its first stream
+func (s *statsSnapshot) source(source string) *sourceCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.sources[source]
+	if !ok {
+		counts = &sourceCounts{}
+		s.sources[source] = counts
+	}
+	return counts
+}
+
+// counted returns the request of source, counting the lines read from its stream
+func (s *statsSnapshot) counted(source string, request rest.ResponseWrapper) rest.ResponseWrapper {
+	return &snapshotRequest{ResponseWrapper: request, counts: s.source(source)}
+}
+
+// writer returns a writer counting the matching lines of source written to w
+func (s *statsSnapshot) writer(source string, w io.Writer) io.Writer {
+	return &snapshotWriter{counts: s.source(source), writer: w}
+}
+
+// reconnect counts a stream of source reopened
+func (s *statsSnapshot) reconnect(source string) {
+	s.source(source).reconnects.Add(1)
+}
+
+// notify prints a snapshot on every signal of statsSignals until stop is called
+func (s *statsSnapshot) notify() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, statsSignals...)
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-signals:
+				s.print()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			// a snapshot being printed is not mixed with what is printed after the streams ended
+			wg.Wait()
+		})
+	}
+}
+
+// print writes the lines read and matched and the reconnects of every source so far, in a single write so that
+// it is not mixed with the markers of the streams
+func (s *statsSnapshot) print() {
+	s.mu.Lock()
+	sources := make([]string, 0, len(s.sources))
+	for source := range s.sources {
+		sources = append(sources, source)
+	}
+	s.mu.Unlock()
+	sort.Strings(sources)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== stats after %s ===\n", time.Since(s.start).Round(time.Second))
+	if len(sources) == 0 {
+		b.WriteString("  no log stream opened yet\n")
+	}
+	for _, source := range sources {
+		counts := s.source(source)
+		fmt.Fprintf(&b, "  %s: %d read, %d matched, %d reconnects\n", source, counts.read.Load(), counts.matched.Load(), counts.reconnects.Load())
+	}
+	io.WriteString(s.out, b.String())
+}
+
+type snapshotRequest struct {
+	rest.ResponseWrapper
+	counts *sourceCounts
+}
+
+func (r *snapshotRequest) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stream, err := r.ResponseWrapper.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotStream{ReadCloser: stream, counts: r.counts}, nil
+}
+
+type snapshotStream struct {
+	io.ReadCloser
+	counts *sourceCounts
+}
+
+func (s *snapshotStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.counts.read.Add(int64(bytes.Count(p[:n], []byte("\n"))))
+	}
+	return n, err
+}
+
+type snapshotWriter struct {
+	counts *sourceCounts
+	writer io.Writer
+}
+
+func (w *snapshotWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.counts.matched.Add(int64(bytes.Count(p[:n], []byte("\n"))))
+	return n, err
+}
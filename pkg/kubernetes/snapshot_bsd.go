@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package kubernetes
+
+import (
+	"os"
+	"syscall"
+)
+
+// statsSignals print a snapshot of the counters of the followed streams, SIGINFO being sent by Ctrl-T
+var statsSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGINFO}
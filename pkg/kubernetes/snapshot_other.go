@@ -0,0 +1,8 @@
+//go:build !unix
+
+package kubernetes
+
+import "os"
+
+// statsSignals is empty where there is no SIGUSR1, e.g. on Windows, no snapshot is printed
+var statsSignals []os.Signal
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForOutput waits until b contains want
+func waitForOutput(t *testing.T, b *lockedBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(b.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want %q", b.String(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStatsSnapshotOnSignal(t *testing.T) {
+	if len(statsSignals) == 0 {
+		t.Skip("no signal prints the stats on this platform")
+	}
+	var out, errOut lockedBuffer
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	l.Out, l.ErrOut = &out, &errOut
+	if err := completeFlags(l, cmd, []string{"-f", "--no-reattach", "--pattern", "ERROR", "--no-banner"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Vaildate(); err != nil {
+		t.Fatal(err)
+	}
+	writer := pipeLogs(t, l)
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+
+	io.WriteString(writer, "ERROR one\nINFO two\n")
+	waitForOutput(t, &out, "ERROR one\n")
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(statsSignals[0]); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, &errOut, "  test/api-1/app: 2 read, 1 matched, 0 reconnects\n")
+	if !strings.HasPrefix(errOut.String(), "=== stats after ") {
+		t.Errorf("got %q, want the snapshot to start with its uptime", errOut.String())
+	}
+
+	// the stream goes on after the snapshot
+	io.WriteString(writer, "ERROR three\n")
+	waitForOutput(t, &out, "ERROR three\n")
+	writer.Close()
+	if err := waitForRun(t, done); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ERROR one\nERROR three\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStatsSnapshotBeforeAnyStream(t *testing.T) {
+	var errOut strings.Builder
+	newStatsSnapshot(&errOut).print()
+	if want := "  no log stream opened yet\n"; !strings.HasSuffix(errOut.String(), want) {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestFollowWithoutStatsOnlyCountsForTheSnapshot(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"-f", "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := l.startCounters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if l.stats != nil || l.streamMetrics != nil {
+		t.Error("the counters of --stats were set up without --stats nor --metrics-addr")
+	}
+	if len(statsSignals) > 0 && l.snapshot == nil {
+		t.Error("the counts of the snapshot were not set up")
+	}
+
+	l, cmd, _, _ = newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--pattern", "ERROR", "--stats"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	stop, err = l.startCounters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if l.stats == nil || l.snapshot != nil {
+		t.Error("got the counts of a snapshot without --follow, or no counters of --stats")
+	}
+}
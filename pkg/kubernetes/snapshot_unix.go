@@ -0,0 +1,11 @@
+//go:build unix && !(darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package kubernetes
+
+import (
+	"os"
+	"syscall"
+)
+
+// statsSignals print a snapshot of the counters of the followed streams
+var statsSignals = []os.Signal{syscall.SIGUSR1}
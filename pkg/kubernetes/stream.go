@@ -0,0 +1,533 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	containerKindInit      = "initContainers"
+	containerKindEphemeral = "ephemeralContainers"
+)
+
+// logRequests returns the log requests for the resolved objects, one per pod and container
+func (l LikeOptions) logRequests() (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+	requests := map[corev1.ObjectReference]rest.ResponseWrapper{}
+	for _, object := range l.objects {
+		objectRequests, err := l.logRequestsForObject(object)
+		if err != nil {
+			return nil, err
+		}
+		for ref, request := range objectRequests {
+			requests[ref] = request
+		}
+	}
+	return requests, nil
+}
+
+// logRequestsForObject returns the log requests for a single object, one per pod and container
+func (l LikeOptions) logRequestsForObject(object runtime.Object) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+	var requests map[corev1.ObjectReference]rest.ResponseWrapper
+	err := l.retry("listing the containers", func() (err error) {
+		if l.AllPods {
+			requests, err = l.AllPodLogsForObject(l.RESTClientGetter, object, l.requestOptions(), l.GetPodTimeout, l.allContainers())
+		} else {
+			requests, err = l.LogsForObject(l.RESTClientGetter, object, l.requestOptions(), l.GetPodTimeout, l.allContainers())
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ref := range requests {
+		kind, container := l.containerFromRef(ref)
+		// the logs helpers return every container of the pod, only keep init and ephemeral containers when asked for
+		if l.allContainers() && (kind == containerKindInit && !l.InitContainers || kind == containerKindEphemeral && !l.EphemeralContainers) {
+			delete(requests, ref)
+			continue
+		}
+		// --container-regexp keeps the matching containers and the one given with -c
+		if l.containerRegexp != nil && !l.containerRegexp.MatchString(container) && !(l.ContainerNameSpecified && container == l.Container) {
+			delete(requests, ref)
+			continue
+		}
+		for _, re := range l.excludeContainerRegexps {
+			if re.MatchString(container) {
+				delete(requests, ref)
+				break
+			}
+		}
+	}
+	if l.SinceLastRestart {
+		for ref := range requests {
+			if requests[ref], err = l.sinceLastRestartRequest(ref, requests[ref]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if l.resume != nil {
+		for ref := range requests {
+			if requests[ref], err = l.resumeRequest(ref, requests[ref]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return requests, nil
+}
+
+// requestOptions returns the logs options of the requests. When following with reattach, the timestamps of the
+// server are always requested so that a reopened stream resumes right after the last line read, and
+// followWithReattach removes them unless they were asked for. They are also requested to save the last line read
+// to --resume-file.
+func (l LikeOptions) requestOptions() runtime.Object {
+	logOptions, ok := l.Options.(*corev1.PodLogOptions)
+	if !ok || (!l.Follow || l.NoReattach) && l.resume == nil {
+		return l.Options
+	}
+	opts := logOptions.DeepCopy()
+	opts.Timestamps = true
+	return opts
+}
+
+// allContainers tells whether every container of the pods is requested, to be filtered afterwards
+func (l LikeOptions) allContainers() bool {
+	return l.AllContainers || l.containerRegexp != nil
+}
+
+// printTargets writes the namespace, pods and containers that are about to be streamed to ErrOut
+func (l LikeOptions) printTargets(targets []logTarget) {
+	fmt.Fprintf(l.ErrOut, "Streaming %d container(s):\n", len(targets))
+	for _, target := range targets {
+		fmt.Fprintf(l.ErrOut, "  %s\n", target)
+	}
+}
+
+// terminatedInitContainerRequests splits off the requests of init containers that already terminated
+// and replaces them with non-follow requests, so their whole log can be read before streaming the others.
+func (l LikeOptions) terminatedInitContainerRequests(requests map[corev1.ObjectReference]rest.ResponseWrapper) (map[corev1.ObjectReference]rest.ResponseWrapper, error) {
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	logOptions, ok := l.requestOptions().(*corev1.PodLogOptions)
+	if !ok {
+		return nil, fmt.Errorf("unexpected logs options object")
+	}
+
+	pods := map[string]*corev1.Pod{}
+	terminated := map[corev1.ObjectReference]rest.ResponseWrapper{}
+	for ref := range requests {
+		kind, container := l.containerFromRef(ref)
+		if kind != containerKindInit {
+			continue
+		}
+		key := ref.Namespace + "/" + ref.Name
+		pod, ok := pods[key]
+		if !ok {
+			pod, err = clientset.CoreV1().Pods(ref.Namespace).Get(l.runContext(), ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			pods[key] = pod
+		}
+		for _, status := range pod.Status.InitContainerStatuses {
+			if status.Name != container || status.State.Terminated == nil {
+				continue
+			}
+			opts := logOptions.DeepCopy()
+			opts.Container = container
+			opts.Follow = false
+			terminated[ref] = clientset.CoreV1().Pods(ref.Namespace).GetLogs(ref.Name, opts)
+			delete(requests, ref)
+		}
+	}
+	return terminated, nil
+}
+
+// compileContainerRefRegexp compiles the regexp of --container-ref-regexp, whose two capture groups are the kind
+// and the name of the container
+func compileContainerRefRegexp(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, newError(ErrInvalidPattern, fmt.Errorf("invalid --container-ref-regexp %q: %w", expr, err))
+	}
+	if re.NumSubexp() != 2 {
+		return nil, fmt.Errorf("invalid --container-ref-regexp %q: it must have two capture groups, the kind and the name of the container, not %d", expr, re.NumSubexp())
+	}
+	return re, nil
+}
+
+// containerFromRef returns the kind and the name of the container referenced by ref.FieldPath
+func (l LikeOptions) containerFromRef(ref corev1.ObjectReference) (kind string, name string) {
+	// We rely on ref.FieldPath to contain a reference to a container
+	// including a container name (not an index) so we can get a container name
+	// without making an extra API request.
+	matches := l.containerNameFromRefSpecRegexp.FindStringSubmatch(ref.FieldPath)
+	if len(matches) != 3 {
+		return "", ""
+	}
+	return matches[1], matches[2]
+}
+
+// consumeRequest writes the logs of a single container to out
+func (l LikeOptions) consumeRequest(ref corev1.ObjectReference, request rest.ResponseWrapper, out io.Writer) error {
+	l.logger.Debug("opening log stream", "namespace", ref.Namespace, "pod", ref.Name, "fieldPath", ref.FieldPath)
+	if l.SplitStreams || l.streamMetrics != nil || l.snapshot != nil || l.capture != nil || l.heartbeat != nil || l.requestTimeout > 0 || l.Retries > 0 || l.ctx != nil {
+		// the consume function of this container is replaced on a copy of the shared logs options
+		logsOptions := *l.LogsOptions
+		l.LogsOptions = &logsOptions
+	}
+	// the markers of the stream are not lines of the container, they go to ErrOut with the prefix of its lines so
+	// that they are neither records nor matches counted, run by --exec or sent to the notifiers
+	l.notices = l.addPrefixIfNeeded(ref, l.ErrOut, "")
+	if l.SplitStreams {
+		// the lines that do not match go to ErrOut, with the prefix of the matching ones
+		l.unmatched = l.addPrefixIfNeeded(ref, l.ErrOut, "")
+		if l.escapesControls() {
+			l.unmatched = &sanitizingWriter{writer: l.unmatched}
+		}
+		l.ConsumeRequestFn = l.DefaultConsumeRequest
+	}
+	if l.streamMetrics != nil {
+		source := l.sourceName(ref)
+		consume := l.ConsumeRequestFn
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			err := consume(l.streamMetrics.counted(source, request), out)
+			if endOfHead(err) != nil {
+				l.streamMetrics.errors.add(1, source)
+			}
+			return err
+		}
+	}
+	if l.snapshot != nil {
+		source := l.sourceName(ref)
+		consume := l.ConsumeRequestFn
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(l.snapshot.counted(source, request), out)
+		}
+	}
+	if l.heartbeat != nil {
+		consume := l.ConsumeRequestFn
+		pod := ref.Name
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(l.heartbeat.counted(pod, request), out)
+		}
+	}
+	if l.capture != nil {
+		// the requests of the previous instance and of restarts are captured too
+		consume := l.ConsumeRequestFn
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(l.captured(ref, request), out)
+		}
+	}
+	if l.Retries > 0 {
+		// wrapping the timeout of --request-timeout, so that every attempt has the whole of it
+		consume := l.ConsumeRequestFn
+		source := l.sourceName(ref)
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(&retriedRequest{ResponseWrapper: request, l: l, source: source}, out)
+		}
+	}
+	if l.requestTimeout > 0 {
+		// --request-timeout bounds opening the followed streams, also when they are opened again after a restart
+		consume := l.ConsumeRequestFn
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(&connectTimeoutRequest{ResponseWrapper: request, timeout: l.requestTimeout}, out)
+		}
+	}
+	if l.ctx != nil {
+		// the streams end when the command is interrupted, also those read by the consume function of kubectl
+		consume := l.ConsumeRequestFn
+		l.ConsumeRequestFn = func(request rest.ResponseWrapper, out io.Writer) error {
+			return consume(&interruptibleRequest{ResponseWrapper: request, ctx: l.ctx}, out)
+		}
+	}
+	if l.PreviousAndCurrent {
+		if err := l.consumePreviousInstance(ref, out); err != nil {
+			return err
+		}
+	}
+	if l.Follow && !l.NoReattach {
+		return l.followWithReattach(ref, request, out)
+	}
+	if l.resume != nil {
+		// like a reopened stream, the lines up to the mark of the previous run are dropped
+		last := l.lastMarkOf(ref)
+		request = &resumedRequest{ResponseWrapper: request, mark: last.get(), timestamps: l.Timestamps, last: last, stats: l.stats, source: l.sourceName(ref)}
+	}
+	err := l.ConsumeRequestFn(request, out)
+	if err == nil && l.Follow {
+		// the followed stream ends when the container exits, or restarts with --no-reattach
+		return l.reportExit(ref)
+	}
+	return endOfHead(err)
+}
+
+// noticeWriter returns the writer of the markers of the stream, ErrOut when it is not set by consumeRequest
+func (l LikeOptions) noticeWriter() io.Writer {
+	if l.notices != nil {
+		return l.notices
+	}
+	return l.ErrOut
+}
+
+// logStream is a container to stream with the options of its context or group of pods
+type logStream struct {
+	options *LikeOptions
+	ref     corev1.ObjectReference
+	request rest.ResponseWrapper
+	// wrap, if set, wraps the writer of the stream, e.g. to count its lines
+	wrap func(io.Writer) io.Writer
+}
+
+// logStreams returns the streams of the requests with the options l
+func (l *LikeOptions) logStreams(requests map[corev1.ObjectReference]rest.ResponseWrapper) []logStream {
+	streams := make([]logStream, 0, len(requests))
+	for ref, request := range requests {
+		streams = append(streams, logStream{options: l, ref: ref, request: request})
+	}
+	return streams
+}
+
+// checkFollowConcurrency returns an error when following n streams exceeds --max-log-requests
+func (l LikeOptions) checkFollowConcurrency(n int) error {
+	if n > l.MaxFollowConcurrency {
+		return fmt.Errorf(
+			"you are attempting to follow %d log streams, but maximum allowed concurrency is %d, use --max-log-requests to increase the limit",
+			n, l.MaxFollowConcurrency,
+		)
+	}
+	return nil
+}
+
+// consumeStream consumes the stream to its own source of mux in a goroutine tracked by wg.
+// An error closes the multiplexer, or is written to the source with --ignore-errors.
+func consumeStream(mux *multiplexer, wg *sync.WaitGroup, s logStream) {
+	c := s.options
+	// the stream is tracked by the merger before it starts, so that the held lines are not written
+	// when the first streams are done before the others start
+	var merged io.Writer
+	if c.merger != nil {
+		merged = c.merger.source(mux)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		source := mux.source(c.sourceName(s.ref))
+		var target io.Writer = source
+		if merged != nil {
+			target = merged
+			defer c.merger.done()
+		}
+		out := c.writerFor(s.ref, target)
+		if s.wrap != nil {
+			out = s.wrap(out)
+		}
+		if err := c.consumeRequest(s.ref, s.request, out); err != nil {
+			if !c.IgnoreLogErrors || errors.Is(err, ErrInterrupted) || errors.Is(err, ErrBrokenPipe) {
+				// It's important to return here to propagate the error via the multiplexer
+				mux.CloseWithError(err)
+				return
+			}
+			if c.contextName != "" {
+				err = fmt.Errorf("[%s] %w", c.contextName, err)
+			}
+			fmt.Fprintf(source, "error: %v\n", err)
+		}
+	}()
+}
+
+// consumeStreams consumes every stream in parallel through mux, which is closed once they are all done.
+// It returns the error of the first failing stream.
+func consumeStreams(mux *multiplexer, streams []logStream) error {
+	wg := &sync.WaitGroup{}
+	for _, s := range streams {
+		consumeStream(mux, wg, s)
+	}
+	go func() {
+		wg.Wait()
+		mux.Close()
+	}()
+	return mux.Run()
+}
+
+func (l LikeOptions) parallelConsumeRequest(requests map[corev1.ObjectReference]rest.ResponseWrapper) error {
+	return consumeStreams(newMultiplexer(l.Out, l.ErrOut, l.PerSourceBuffer, l.stats), l.logStreams(requests))
+}
+
+func (l LikeOptions) sequentialConsumeRequest(requests map[corev1.ObjectReference]rest.ResponseWrapper) error {
+	for objRef, request := range requests {
+		out := l.writerFor(objRef, l.Out)
+		if err := l.consumeRequest(objRef, request, out); err != nil {
+			if !l.IgnoreLogErrors || errors.Is(err, ErrInterrupted) || errors.Is(err, ErrBrokenPipe) {
+				return err
+			}
+
+			fmt.Fprintf(l.Out, "error: %v\n", err)
+		}
+	}
+	if l.merger != nil {
+		// the containers read one after the other are ordered once they are all read
+		l.merger.Flush()
+	}
+
+	return nil
+}
+
+// writerFor returns the writer the lines of the container referenced by ref go to
+func (l LikeOptions) writerFor(ref corev1.ObjectReference, writer io.Writer) io.Writer {
+	if l.merger != nil && l.merger.hide {
+		// the timestamps were only requested to order the lines, the merger removes them
+		l.Timestamps = false
+	}
+	var group string
+	if l.byReplicaSet && (l.Prefix || l.matchCounts != nil) {
+		group = l.replicaSetGroup(ref)
+	}
+
+	var w io.Writer
+	switch {
+	case l.outputs != nil:
+		_, container := l.containerFromRef(ref)
+		w = l.outputs.writerFor(l.contextName, ref, container)
+		if l.budget != nil {
+			w = l.budget.writer(w)
+		}
+		if l.escapesControls() {
+			w = &sanitizingWriter{writer: w}
+		}
+	case l.usesRecords():
+		// the source is part of every record
+		w = writer
+	default:
+		w = l.addPrefixIfNeeded(ref, writer, group)
+		if l.colorize {
+			w = &colorWriter{l: l, pod: podColor(ref.Namespace, ref.Name), writer: w}
+		}
+		// the lines are escaped before our own colors are added
+		if l.escapesControls() {
+			w = &sanitizingWriter{writer: w}
+		}
+	}
+	if l.usesRecords() {
+		w = l.newRecordWriter(ref, w)
+	} else if l.Timestamps && (l.TimestampsFormat != timestampsRaw || len(l.TimestampFormat) > 0) {
+		w = &timestampWriter{
+			format: l.TimestampsFormat,
+			layout: l.TimestampFormat,
+			origin: l.timestampOrigin,
+			stats:  l.stats,
+			source: l.sourceName(ref),
+			writer: w,
+		}
+	}
+	if l.matchCounts != nil {
+		w = l.matchCounts.writer(group, w)
+	}
+	if l.stats != nil {
+		w = l.stats.writer(l.sourceName(ref), w)
+	}
+	if l.snapshot != nil {
+		w = l.snapshot.writer(l.sourceName(ref), w)
+	}
+	if l.heartbeat != nil {
+		w = l.heartbeat.writer(w)
+	}
+	if l.noMatch != nil {
+		w = l.noMatch.writer(w)
+	}
+	if l.lineGroups != nil {
+		// the lines are written to w when the groups are printed
+		w = l.lineGroups.writer(l, w)
+	}
+	if l.lineExec != nil {
+		// the command runs as soon as the line matched, even when it is printed later
+		_, container := l.containerFromRef(ref)
+		w = l.lineExec.writer(l.contextName, ref.Namespace, ref.Name, container, w)
+	}
+	if len(l.notifySinks) > 0 || l.bell != nil || l.desktopNotifier != nil {
+		_, container := l.containerFromRef(ref)
+		source := notification{
+			Context:   l.contextName,
+			Namespace: ref.Namespace,
+			Pod:       ref.Name,
+			Container: container,
+			Pattern:   l.Pattern,
+			source:    l.sourceName(ref),
+		}
+		for _, sink := range l.notifySinks {
+			w = sink.writer(source, l.Timestamps, w)
+		}
+		if l.bell != nil || l.desktopNotifier != nil {
+			w = &alertWriter{bell: l.bell, notifier: l.desktopNotifier, source: source, timestamps: l.Timestamps, writer: w}
+		}
+	}
+	if l.merger != nil {
+		w = l.merger.writer(w)
+	}
+	return w
+}
+
+// sourceName returns the [CONTEXT|]NAMESPACE/POD/CONTAINER of the container referenced by ref
+func (l LikeOptions) sourceName(ref corev1.ObjectReference) string {
+	_, container := l.containerFromRef(ref)
+	return logTarget{Context: l.contextName, Namespace: ref.Namespace, Pod: ref.Name, Container: container}.String()
+}
+
+// addPrefixIfNeeded prefixes the lines with their pod and container, followed by the group of the pod if any and
+// the labels and annotations of --prefix-label and --prefix-annotation
+func (l LikeOptions) addPrefixIfNeeded(ref corev1.ObjectReference, writer io.Writer, group string) io.Writer {
+	if !l.Prefix || ref.FieldPath == "" || ref.Name == "" {
+		return writer
+	}
+
+	_, containerName := l.containerFromRef(ref)
+	source := fmt.Sprintf("pod/%s/%s", ref.Name, containerName)
+	if l.contextName != "" {
+		source = fmt.Sprintf("%s|%s/%s/%s", l.contextName, ref.Namespace, ref.Name, containerName)
+	}
+	if group != "" {
+		source += " " + group
+	}
+	if metadata := l.prefixMetadata(ref); metadata != "" {
+		source += " " + metadata
+	}
+	prefix := "[" + source + "] "
+	if l.colorize {
+		prefix = podColor(ref.Namespace, ref.Name) + "[" + source + "]" + sgrReset + " "
+	}
+	return &prefixingWriter{
+		prefix: []byte(prefix),
+		writer: writer,
+	}
+}
+
+type prefixingWriter struct {
+	prefix []byte
+	writer io.Writer
+}
+
+func (pw *prefixingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Perform an "atomic" write of a prefix and p to make sure that it doesn't interleave
+	// sub-line when used concurrently with io.PipeWrite.
+	n, err := pw.writer.Write(append(pw.prefix, p...))
+	if n > len(p) {
+		// To comply with the io.Writer interface requirements we must
+		// return a number of bytes written from p (0 <= n <= len(p)),
+		// so we are ignoring the length of the prefix here.
+		return len(p), err
+	}
+	return n, err
+}
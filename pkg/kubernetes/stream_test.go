@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
+)
+
+// podWithAllContainerKinds returns a pod whose init container terminated,
+// with a regular and an ephemeral container
+func podWithAllContainerKinds() *corev1.Pod {
+	pod := testPod("api-1", corev1.PodRunning, nil)
+	pod.Spec.InitContainers = []corev1.Container{{Name: "migrate"}}
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger"}}}
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "migrate", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}},
+	}
+	return &pod
+}
+
+func newContainerOptions(t *testing.T, api *fakeAPI) LikeOptions {
+	t.Helper()
+	l, _, _ := newFakeOptions(t, api)
+	l.RESTClientGetter = l.factory
+	l.LogsForObject = polymorphichelpers.LogsForObjectFn
+	l.Options = &corev1.PodLogOptions{Follow: true}
+	return l
+}
+
+func requestedContainers(l LikeOptions, requests map[corev1.ObjectReference]rest.ResponseWrapper) string {
+	var containers []string
+	for ref := range requests {
+		_, container := l.containerFromRef(ref)
+		containers = append(containers, container)
+	}
+	slices.Sort(containers)
+	return strings.Join(containers, ",")
+}
+
+func TestLogRequestsForObjectContainerKinds(t *testing.T) {
+	tests := []struct {
+		name                string
+		initContainers      bool
+		ephemeralContainers bool
+		want                string
+	}{
+		{name: "regular only", want: "app"},
+		{name: "init", initContainers: true, want: "app,migrate"},
+		{name: "ephemeral", ephemeralContainers: true, want: "app,debugger"},
+		{name: "every kind", initContainers: true, ephemeralContainers: true, want: "app,debugger,migrate"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newContainerOptions(t, &fakeAPI{})
+			l.AllContainers = true
+			l.InitContainers = test.initContainers
+			l.EphemeralContainers = test.ephemeralContainers
+
+			requests, err := l.logRequestsForObject(podWithAllContainerKinds())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := requestedContainers(l, requests); got != test.want {
+				t.Errorf("got containers %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTerminatedInitContainerRequests(t *testing.T) {
+	pod := podWithAllContainerKinds()
+	l := newContainerOptions(t, &fakeAPI{objects: map[string]runtime.Object{"/namespaces/test/pods/api-1": pod}})
+	l.AllContainers = true
+	l.InitContainers = true
+	l.EphemeralContainers = true
+
+	requests, err := l.logRequestsForObject(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminated, err := l.terminatedInitContainerRequests(requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requestedContainers(l, terminated); got != "migrate" {
+		t.Errorf("got terminated init containers %q, want migrate", got)
+	}
+	if got := requestedContainers(l, requests); got != "app,debugger" {
+		t.Errorf("got streamed containers %q, want app,debugger", got)
+	}
+}
+
+func TestContainerRefRegexp(t *testing.T) {
+	for _, args := range [][]string{
+		{"--container-ref-regexp", `spec\.(containers{(.+)}`},
+		{"--container-ref-regexp", `spec\.containers{(.+)}`},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, append(args, "--pattern", "ERROR"), "api-1"); err == nil || !strings.Contains(err.Error(), "invalid --container-ref-regexp") {
+			t.Errorf("%v: got %v, want the regexp to be rejected", args, err)
+		}
+	}
+
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--container-ref-regexp", `spec\.template\.(containers)\[(\w+)\]`, "--pattern", "ERROR"}, "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	if kind, name := l.containerFromRef(corev1.ObjectReference{FieldPath: "spec.template.containers[worker]"}); kind != "containers" || name != "worker" {
+		t.Errorf("got kind %q and name %q, want containers and worker", kind, name)
+	}
+}
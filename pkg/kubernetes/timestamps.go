@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	timestampsRaw      = "raw"
+	timestampsRelative = "relative"
+	timestampsElapsed  = "elapsed"
+)
+
+var timestampsFormats = []string{timestampsRaw, timestampsRelative, timestampsElapsed}
+
+// keywords of --timestamp-format, any other value is a Go time layout applied in the local timezone
+const (
+	timestampLayoutLocal   = "local"
+	timestampLayoutUnix    = "unix"
+	timestampLayoutRFC3339 = "rfc3339"
+)
+
+var timestampLayouts = []string{timestampLayoutLocal, timestampLayoutUnix, timestampLayoutRFC3339}
+
+// timestampsValue replaces the --timestamps flag of kubectl logs, so that it accepts a format as well as a boolean:
+// --timestamps and --timestamps=raw print the RFC3339 timestamps of the server, relative prints them relative to now
+// and elapsed relative to the first printed line
+type timestampsValue struct {
+	timestamps *bool
+	format     *string
+}
+
+func (v *timestampsValue) String() string {
+	if v.timestamps == nil || !*v.timestamps {
+		return "false"
+	}
+	if *v.format == timestampsRaw {
+		return "true"
+	}
+	return *v.format
+}
+
+func (v *timestampsValue) Set(s string) error {
+	switch s {
+	case "true", timestampsRaw:
+		*v.timestamps, *v.format = true, timestampsRaw
+	case "false":
+		*v.timestamps, *v.format = false, timestampsRaw
+	case timestampsRelative, timestampsElapsed:
+		*v.timestamps, *v.format = true, s
+	default:
+		return fmt.Errorf("must be true, false or one of %s", strings.Join(timestampsFormats, ", "))
+	}
+	return nil
+}
+
+func (v *timestampsValue) Type() string {
+	return "string"
+}
+
+// timestampOrigin is the time of the first printed line, shared by every source for --timestamps=elapsed
+type timestampOrigin struct {
+	once sync.Once
+	time time.Time
+}
+
+func (o *timestampOrigin) since(t time.Time) time.Duration {
+	o.once.Do(func() { o.time = t })
+	return t.Sub(o.time)
+}
+
+// timestampWriter rewrites the timestamp the server prefixes the lines with according to --timestamps
+// and --timestamp-format. Lines without a timestamp, e.g. the rest of a partial line, are written untouched
+// and counted by --stats.
+type timestampWriter struct {
+	format string
+	layout string
+	origin *timestampOrigin
+	stats  *matchCounts
+	source string
+	writer io.Writer
+}
+
+func (tw *timestampWriter) Write(p []byte) (int, error) {
+	t, rest, ok := splitTimestamp(p)
+	if !ok {
+		if tw.stats != nil {
+			tw.stats.unparsedTimestamp(tw.source)
+		}
+		return tw.writer.Write(p)
+	}
+	line := append([]byte(tw.formatTime(t)+" "), rest...)
+	if _, err := tw.writer.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (tw *timestampWriter) formatTime(t time.Time) string {
+	switch tw.format {
+	case timestampsRelative:
+		return formatTimestampDuration(t.Sub(time.Now()))
+	case timestampsElapsed:
+		return formatTimestampDuration(tw.origin.since(t))
+	}
+	switch tw.layout {
+	case timestampLayoutLocal:
+		return t.Local().Format(time.RFC3339Nano)
+	case timestampLayoutUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case timestampLayoutRFC3339:
+		return t.Format(time.RFC3339)
+	}
+	return t.Local().Format(tw.layout)
+}
+
+// formatTimestampDuration formats d to the second with an explicit sign, e.g. -3m12s or +1h0m5s
+func formatTimestampDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
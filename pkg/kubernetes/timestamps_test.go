@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestTimestampsValue(t *testing.T) {
+	tests := []struct {
+		args       []string
+		timestamps bool
+		format     string
+		value      string
+	}{
+		{args: nil, format: timestampsRaw, value: "false"},
+		{args: []string{"--timestamps"}, timestamps: true, format: timestampsRaw, value: "true"},
+		{args: []string{"--timestamps=raw"}, timestamps: true, format: timestampsRaw, value: "true"},
+		{args: []string{"--timestamps=relative"}, timestamps: true, format: timestampsRelative, value: "relative"},
+		{args: []string{"--timestamps=elapsed"}, timestamps: true, format: timestampsElapsed, value: "elapsed"},
+		{args: []string{"--timestamps=elapsed", "--timestamps=false"}, format: timestampsRaw, value: "false"},
+	}
+	for _, test := range tests {
+		timestamps, format := false, timestampsRaw
+		flags := pflag.NewFlagSet("like", pflag.ContinueOnError)
+		flag := flags.VarPF(&timestampsValue{timestamps: &timestamps, format: &format}, "timestamps", "", "")
+		flag.NoOptDefVal = "true"
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if timestamps != test.timestamps || format != test.format || flag.Value.String() != test.value {
+			t.Errorf("%v: got %v, %q and value %q, want %v, %q and %q", test.args, timestamps, format, flag.Value.String(), test.timestamps, test.format, test.value)
+		}
+	}
+
+	timestamps, format := false, timestampsRaw
+	if err := (&timestampsValue{timestamps: &timestamps, format: &format}).Set("absolute"); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
+
+func TestTimestampWriterRaw(t *testing.T) {
+	l, _ := newOutputOptions(t, outputText, "", nil)
+	l.Timestamps = true
+	var out bytes.Buffer
+	w := l.writerFor(appRef, &out)
+	line := "2024-06-12T10:04:05.123456789Z ERROR boom\n"
+	if _, err := w.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != line {
+		t.Errorf("got %q, want the line untouched", out.String())
+	}
+}
+
+func TestTimestampWriterRelative(t *testing.T) {
+	var out bytes.Buffer
+	tw := &timestampWriter{format: timestampsRelative, writer: &out}
+	at := time.Now().Add(-(3*time.Minute + 12*time.Second)).UTC().Format(time.RFC3339Nano)
+	if _, err := tw.Write([]byte(at + " ERROR boom\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "-3m12s ERROR boom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTimestampWriterElapsed(t *testing.T) {
+	origin := &timestampOrigin{}
+	var out bytes.Buffer
+	first := &timestampWriter{format: timestampsElapsed, origin: origin, writer: &out}
+	second := &timestampWriter{format: timestampsElapsed, origin: origin, writer: &out}
+	lines := []struct {
+		w    *timestampWriter
+		line string
+	}{
+		{first, "2024-06-12T10:04:05Z starting\n"},
+		{second, "2024-06-12T10:04:47.4Z ERROR boom\n"},
+		{first, "2024-06-12T11:04:10Z done\n"},
+	}
+	for _, line := range lines {
+		if _, err := line.w.Write([]byte(line.line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every source is relative to the first printed line
+	if got, want := out.String(), "+0s starting\n+42s ERROR boom\n+1h0m5s done\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTimestampWriterMalformedPrefixes(t *testing.T) {
+	for _, line := range []string{
+		"ERROR no timestamp\n",
+		"rest of a partial line\n",
+		"2024-06-12 10:04:05 ERROR not RFC3339\n",
+		"2024-06-12T10:04:05Z\n",
+		"\n",
+	} {
+		var out bytes.Buffer
+		tw := &timestampWriter{format: timestampsRelative, writer: &out}
+		if _, err := tw.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != line {
+			t.Errorf("got %q, want %q untouched", out.String(), line)
+		}
+	}
+}
+
+func TestFormatTimestampDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                                 "+0s",
+		1400 * time.Millisecond:           "+1s",
+		-(3*time.Minute + 12*time.Second): "-3m12s",
+		time.Hour + 5*time.Second:         "+1h0m5s",
+		-(500*time.Millisecond + time.Nanosecond): "-1s",
+	} {
+		if got := formatTimestampDuration(d); got != want {
+			t.Errorf("formatTimestampDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// pinLocal makes loc the local timezone of the test. TZ is only read when the process starts,
+// so setting it in a test does not change time.Local.
+func pinLocal(t *testing.T, loc *time.Location) {
+	t.Helper()
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestTimestampFormat(t *testing.T) {
+	pinLocal(t, time.FixedZone("KST", 9*60*60))
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{layout: timestampLayoutLocal, want: "2024-06-12T19:04:05.5+09:00"},
+		{layout: timestampLayoutUnix, want: "1718186645"},
+		{layout: timestampLayoutRFC3339, want: "2024-06-12T10:04:05Z"},
+		{layout: "2006-01-02 15:04:05 MST", want: "2024-06-12 19:04:05 KST"},
+	}
+	for _, test := range tests {
+		l, _ := newOutputOptions(t, outputText, "", nil)
+		l.Timestamps = true
+		l.TimestampFormat = test.layout
+		var out bytes.Buffer
+		if _, err := l.writerFor(appRef, &out).Write([]byte("2024-06-12T10:04:05.5Z ERROR boom\n")); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), test.want+" ERROR boom\n"; got != want {
+			t.Errorf("--timestamp-format %s: got %q, want %q", test.layout, got, want)
+		}
+	}
+}
+
+func TestTimestampFormatCountsUnparsedTimestamps(t *testing.T) {
+	l, _ := newOutputOptions(t, outputText, "", nil)
+	l.Timestamps = true
+	l.TimestampFormat = timestampLayoutUnix
+	var stats bytes.Buffer
+	l.stats = newMatchCounts("source", &stats)
+	var out bytes.Buffer
+	w := l.writerFor(appRef, &out)
+	for _, line := range []string{"2024-06-12T10:04:05Z ERROR boom\n", "not a timestamp ERROR\n", "tomorrow ERROR\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := out.String(), "1718186645 ERROR boom\nnot a timestamp ERROR\ntomorrow ERROR\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	l.stats.Print()
+	if want := "test/api-1/app: 3 (2 unparsed timestamps)"; !bytes.Contains(stats.Bytes(), []byte(want)) {
+		t.Errorf("got stats %q, want %q", stats.String(), want)
+	}
+}
+
+func TestTimestampFormatRequiresRawTimestamps(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--timestamp-format", "unix"},
+		{"--timestamps=relative", "--timestamp-format", "unix"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
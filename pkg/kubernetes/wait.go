@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+const (
+	defaultWaitTimeout = 5 * time.Minute
+	// defaultContainerAnnotation names the container kubectl logs reads when none is given
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+)
+
+// podWaiter tells whether a pod waited for by --wait has a started container, and reports why it has not to
+// ErrOut when the reason changes, e.g. ImagePullBackOff or CrashLoopBackOff
+type podWaiter struct {
+	l      *LikeOptions
+	target string
+	last   map[string]string
+}
+
+// waitedPodName returns the name of the pod given as argument, or "" for a selector. Other arguments cannot be
+// waited for.
+func (l *LikeOptions) waitedPodName() (string, error) {
+	switch len(l.ResourceArgs) {
+	case 0:
+		return "", nil
+	case 1:
+		resource, name, found := strings.Cut(l.ResourceArgs[0], "/")
+		if !found {
+			return resource, nil
+		}
+		if resource == "pod" || resource == "pods" || resource == "po" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("--wait can only be used with a single POD or a selector")
+}
+
+// waitForPods waits for the pod given as argument, or for a pod selected by the selectors, to exist and run its
+// container, so that its logs can be read. Its progress is written to ErrOut.
+func (l *LikeOptions) waitForPods() error {
+	name, err := l.waitedPodName()
+	if err != nil {
+		return err
+	}
+	clientset, err := l.factory.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	namespace := l.Namespace
+	if l.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	options := metav1.ListOptions{LabelSelector: l.Selector, FieldSelector: l.FieldSelector}
+	w := &podWaiter{l: l, target: "a pod", last: map[string]string{}}
+	switch {
+	case len(name) > 0:
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		w.target = "pod " + name
+	case len(l.Selector) > 0:
+		w.target = "a pod matching " + l.Selector
+	}
+
+	ctx, cancel := context.WithTimeout(l.runContext(), l.WaitTimeout)
+	defer cancel()
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, options)
+	if err != nil {
+		return l.waitError(ctx, w, err)
+	}
+	for i := range pods.Items {
+		if started, _ := w.started(&pods.Items[i]); started {
+			return nil
+		}
+	}
+	fmt.Fprintf(l.ErrOut, "waiting for %s to start...\n", w.target)
+	for i := range pods.Items {
+		w.check(&pods.Items[i])
+	}
+
+	watcher, err := watchtools.NewRetryWatcher(pods.ResourceVersion, &cache.ListWatch{
+		WatchFunc: func(watchOptions metav1.ListOptions) (watch.Interface, error) {
+			watchOptions.LabelSelector = options.LabelSelector
+			watchOptions.FieldSelector = options.FieldSelector
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, watchOptions)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	stop := context.AfterFunc(ctx, watcher.Stop)
+	defer stop()
+
+	for event := range watcher.ResultChan() {
+		switch event.Type {
+		case watch.Error:
+			return fmt.Errorf("waiting for %s to start: %w", w.target, apierrors.FromObject(event.Object))
+		case watch.Added, watch.Modified:
+			if pod, ok := event.Object.(*corev1.Pod); ok && w.check(pod) {
+				return nil
+			}
+		}
+	}
+	return l.waitError(ctx, w, nil)
+}
+
+// waitError returns the error of a wait that ended without a started pod
+func (l *LikeOptions) waitError(ctx context.Context, w *podWaiter, err error) error {
+	if l.runContext().Err() != nil {
+		return ErrInterrupted
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s waiting for %s to start", l.WaitTimeout, w.target)
+	}
+	if err == nil {
+		err = fmt.Errorf("the watch ended")
+	}
+	return fmt.Errorf("waiting for %s to start: %w", w.target, err)
+}
+
+// check tells whether the pod started, and reports why not otherwise
+func (w *podWaiter) check(pod *corev1.Pod) bool {
+	started, reason := w.started(pod)
+	if !started {
+		w.report(pod, reason)
+	}
+	return started
+}
+
+// started tells whether the container of the pod whose logs are read runs or ran, or why not
+func (w *podWaiter) started(pod *corev1.Pod) (bool, string) {
+	if pod.DeletionTimestamp != nil {
+		return false, "being deleted"
+	}
+	containers := w.containers(pod)
+	for _, status := range pod.Status.ContainerStatuses {
+		if !containers[status.Name] {
+			continue
+		}
+		if status.State.Running != nil || status.State.Terminated != nil {
+			return true, ""
+		}
+		if waiting := status.State.Waiting; waiting != nil && len(waiting.Reason) > 0 {
+			reason := fmt.Sprintf("container %s is waiting: %s", status.Name, waiting.Reason)
+			if len(waiting.Message) > 0 {
+				reason += " (" + waiting.Message + ")"
+			}
+			return false, reason
+		}
+	}
+	return false, "phase " + string(pod.Status.Phase)
+}
+
+// containers returns the containers of the pod whose logs are read: the one given with -c, every one with
+// --all-containers or --container-regexp, or the default one of kubectl logs
+func (w *podWaiter) containers(pod *corev1.Pod) map[string]bool {
+	containers := map[string]bool{}
+	switch {
+	case len(w.l.Container) > 0:
+		containers[w.l.Container] = true
+	case w.l.allContainers():
+		for _, c := range pod.Spec.Containers {
+			containers[c.Name] = true
+		}
+	case len(pod.Annotations[defaultContainerAnnotation]) > 0:
+		containers[pod.Annotations[defaultContainerAnnotation]] = true
+	case len(pod.Spec.Containers) > 0:
+		containers[pod.Spec.Containers[0].Name] = true
+	}
+	return containers
+}
+
+// report writes why the pod did not start yet, when it changed
+func (w *podWaiter) report(pod *corev1.Pod, reason string) {
+	if w.last[pod.Name] == reason {
+		return
+	}
+	w.last[pod.Name] = reason
+	fmt.Fprintf(w.l.ErrOut, "waiting for pod %s to start: %s\n", pod.Name, reason)
+}
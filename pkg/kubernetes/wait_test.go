@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// startingPod returns the pod api-xyz whose app container waits for reason, or runs without a reason
+func startingPod(reason, message string) *corev1.Pod {
+	pod := testPod("api-xyz", corev1.PodPending, nil)
+	pod.ResourceVersion = "2"
+	state := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}}
+	if reason == "" {
+		pod.Status.Phase = corev1.PodRunning
+		state = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "app", State: state}}
+	return &pod
+}
+
+func TestWaitForPodToStart(t *testing.T) {
+	api := &fakeAPI{
+		objects: map[string]runtime.Object{
+			"/namespaces/test/pods":         &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}},
+			"/namespaces/test/pods/api-xyz": startingPod("", ""),
+		},
+		watches: map[string][]watch.Event{"/namespaces/test/pods": {
+			{Type: watch.Added, Object: startingPod("ContainerCreating", "")},
+			{Type: watch.Modified, Object: startingPod("ImagePullBackOff", `Back-off pulling image "api:v2"`)},
+			{Type: watch.Modified, Object: startingPod("ImagePullBackOff", `Back-off pulling image "api:v2"`)},
+			{Type: watch.Modified, Object: startingPod("", "")},
+		}},
+	}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--wait"}, "api-xyz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// every reason is reported once
+	want := "waiting for pod api-xyz to start...\n" +
+		"waiting for pod api-xyz to start: container app is waiting: ContainerCreating\n" +
+		"waiting for pod api-xyz to start: container app is waiting: ImagePullBackOff (Back-off pulling image \"api:v2\")\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if pod, ok := l.Object.(*corev1.Pod); !ok || pod.Name != "api-xyz" {
+		t.Errorf("got the object %v, want the pod api-xyz", l.Object)
+	}
+	queries := api.requests("/namespaces/test/pods")
+	if len(queries) != 2 || !strings.Contains(queries[1], "watch=true") || !strings.Contains(queries[1], "fieldSelector=metadata.name%3Dapi-xyz") {
+		t.Errorf("got the queries %q, want a list then a watch of the pod", queries)
+	}
+}
+
+func TestWaitForStartedPod(t *testing.T) {
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods":         &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: []corev1.Pod{*startingPod("", "")}},
+		"/namespaces/test/pods/api-xyz": startingPod("", ""),
+	}}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	if err := completeFlags(l, cmd, []string{"--wait"}, "api-xyz"); err != nil {
+		t.Fatal(err)
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("waited for a started pod: %q", errOut.String())
+	}
+	if queries := api.requests("/namespaces/test/pods"); len(queries) != 1 {
+		t.Errorf("got the queries %q, want the pods to be listed once", queries)
+	}
+}
+
+func TestWaitForSelectedPodTimesOut(t *testing.T) {
+	api := &fakeAPI{objects: map[string]runtime.Object{
+		"/namespaces/test/pods": &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: []corev1.Pod{*startingPod("CrashLoopBackOff", "back-off 5m0s restarting failed container")}},
+	}}
+	l, cmd, _, errOut := newFakeCommand(t, api)
+	err := completeFlags(l, cmd, []string{"--wait", "--wait-timeout", "100ms", "-l", "app=api"})
+	if err == nil || err.Error() != `timed out after 100ms waiting for a pod matching app=api to start` {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	want := "waiting for a pod matching app=api to start...\n" +
+		"waiting for pod api-xyz to start: container app is waiting: CrashLoopBackOff (back-off 5m0s restarting failed container)\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWaitValidation(t *testing.T) {
+	l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+	if err := completeFlags(l, cmd, []string{"--wait"}, "deployment/api"); err == nil || !strings.Contains(err.Error(), "--wait can only be used") {
+		t.Errorf("got %v, want --wait to be rejected for a deployment", err)
+	}
+
+	for _, flags := range [][]string{
+		{"--wait-timeout", "1m"},
+		{"--wait-timeout", "-1s"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// podStreams keeps track of the containers that are streamed while following selected pods,
+// so that pods created later are picked up and no container is streamed twice.
+type podStreams struct {
+	l   LikeOptions
+	mux *multiplexer
+
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	streaming map[string]bool
+}
+
+// parallelConsumeRequestAndWatch follows the given requests like parallelConsumeRequest,
+// and keeps watching the selected pods to follow the ones that are created or start running later.
+// Once the watch ends, it returns when all the followed streams are done.
+func (l LikeOptions) parallelConsumeRequestAndWatch(requests map[corev1.ObjectReference]rest.ResponseWrapper) error {
+	mux := newMultiplexer(l.Out, l.ErrOut, l.PerSourceBuffer, l.stats)
+	streams := &podStreams{
+		l:         l,
+		mux:       mux,
+		streaming: map[string]bool{},
+	}
+	for ref, request := range requests {
+		streams.start(ref, request)
+	}
+
+	go func() {
+		if err := streams.watch(); err != nil {
+			mux.CloseWithError(err)
+			return
+		}
+		streams.wg.Wait()
+		mux.Close()
+	}()
+
+	return mux.Run()
+}
+
+// start streams the container referenced by ref unless it is already streamed
+func (s *podStreams) start(ref corev1.ObjectReference, request rest.ResponseWrapper) {
+	key := string(ref.UID) + "/" + ref.FieldPath
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streaming[key] {
+		return
+	}
+	if len(s.streaming) >= s.l.MaxFollowConcurrency {
+		fmt.Fprintf(s.l.ErrOut, "not following pod/%s: maximum allowed concurrency of %d reached, use --max-log-requests to increase the limit\n", ref.Name, s.l.MaxFollowConcurrency)
+		return
+	}
+	s.streaming[key] = true
+	consumeStream(s.mux, &s.wg, logStream{options: &s.l, ref: ref, request: request})
+}
+
+// watch starts streaming the pods matching the selectors once they are running
+func (s *podStreams) watch() error {
+	clientset, err := s.l.factory.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	namespace := s.l.Namespace
+	if s.l.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	var resourceVersion string
+	if pods, ok := s.l.Object.(*corev1.PodList); ok {
+		resourceVersion = pods.ResourceVersion
+	}
+
+	watcher, err := watchtools.NewRetryWatcher(resourceVersion, &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = s.l.Selector
+			options.FieldSelector = s.l.FieldSelector
+			return clientset.CoreV1().Pods(namespace).Watch(s.l.runContext(), options)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	// no new pod is followed once the command is interrupted
+	stop := context.AfterFunc(s.l.runContext(), watcher.Stop)
+	defer stop()
+
+	for event := range watcher.ResultChan() {
+		switch event.Type {
+		case watch.Error:
+			// the pods that are already followed keep being streamed
+			s.l.logger.Warn("stopped watching for new pods", "error", apierrors.FromObject(event.Object))
+			return nil
+		case watch.Added, watch.Modified:
+			pod, ok := event.Object.(*corev1.Pod)
+			// only running pods have logs to stream, whatever --pod-status selects
+			if !ok || pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil || s.l.skipReason(pod) != "" {
+				continue
+			}
+			requests, err := s.l.logRequestsForObject(pod)
+			if err != nil {
+				s.l.logger.Warn("cannot follow pod", "namespace", pod.Namespace, "pod", pod.Name, "error", err)
+				continue
+			}
+			for ref, request := range requests {
+				s.start(ref, request)
+			}
+		}
+	}
+	return nil
+}
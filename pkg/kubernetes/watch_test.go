@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// watchedPod returns a running pod selected by app=api, as sent by the watch
+func watchedPod(name string) *corev1.Pod {
+	pod := testPod(name, corev1.PodRunning, map[string]string{"app": "api"})
+	pod.UID = types.UID(name + "-uid")
+	pod.ResourceVersion = "2"
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "app", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+	return &pod
+}
+
+// deletedPod returns the pod once it is being deleted
+func deletedPod(name string) *corev1.Pod {
+	pod := watchedPod(name)
+	pod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	return pod
+}
+
+// watchEnd ends the watch for good, like a watch from a resource version that is too old
+var watchEnd = watch.Event{Type: watch.Error, Object: &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGone, Reason: metav1.StatusReasonGone}}
+
+// newWatchOptions returns options following the pods selected by app=api, answered by api, and the request of
+// the app container of the pod api-1 listed before the watch
+func newWatchOptions(t *testing.T, api *fakeAPI) (LikeOptions, map[corev1.ObjectReference]rest.ResponseWrapper) {
+	t.Helper()
+	l, _, _ := newFakeOptions(t, api)
+	l.Options = &corev1.PodLogOptions{Container: "app", Follow: true}
+	l.Follow = true
+	l.Selector = "app=api"
+	l.Object = &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: []corev1.Pod{*watchedPod("api-1")}}
+	l.patternRegexp = regexp.MustCompile("ERROR")
+	l.ConsumeRequestFn = l.DefaultConsumeRequest
+	l.PerSourceBuffer = defaultPerSourceBuffer
+	l.MaxFollowConcurrency = 5
+	useFakeLogs(t, &l)
+
+	requests, err := l.logRequestsForObject(watchedPod("api-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, requests
+}
+
+// followSelected follows the selected pods until the watch and the streams end
+func followSelected(t *testing.T, l LikeOptions, requests map[corev1.ObjectReference]rest.ResponseWrapper) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- l.parallelConsumeRequestAndWatch(requests) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("following the selected pods did not end")
+	}
+}
+
+func TestFollowSelectedStreamsPodsCreatedLater(t *testing.T) {
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{
+			"/namespaces/test/pods/api-1": {watchedPod("api-1"), deletedPod("api-1")},
+			"/namespaces/test/pods/api-2": {watchedPod("api-2"), deletedPod("api-2")},
+		},
+		logs: map[string]string{
+			"/namespaces/test/pods/api-1/log": "ERROR one\nINFO skipped\n",
+			"/namespaces/test/pods/api-2/log": "ERROR two\n",
+		},
+		watches: map[string][]watch.Event{"/namespaces/test/pods": {
+			{Type: watch.Added, Object: watchedPod("api-2")},
+			// the pod followed from the start is not streamed twice
+			{Type: watch.Modified, Object: watchedPod("api-1")},
+			watchEnd,
+		}},
+	}
+	l, requests := newWatchOptions(t, api)
+	var out lockedBuffer
+	l.Out = &out
+
+	followSelected(t, l, requests)
+	for _, want := range []string{"ERROR one\n", "ERROR two\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got %q, want %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "INFO") {
+		t.Errorf("a line that does not match was printed: %q", out.String())
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 1 {
+		t.Errorf("got %d log requests of api-1, want 1", got)
+	}
+	if queries := api.requests("/namespaces/test/pods"); len(queries) != 1 || !strings.Contains(queries[0], "labelSelector=app%3Dapi") {
+		t.Errorf("got the watch queries %q, want a watch of the selected pods", queries)
+	}
+}
+
+func TestFollowSelectedEndsStreamOfDeletedPod(t *testing.T) {
+	api := &fakeAPI{
+		sequences: map[string][]runtime.Object{
+			"/namespaces/test/pods/api-1": {watchedPod("api-1"), deletedPod("api-1")},
+		},
+		logs:    map[string]string{"/namespaces/test/pods/api-1/log": "ERROR one\n"},
+		watches: map[string][]watch.Event{"/namespaces/test/pods": {watchEnd}},
+	}
+	l, requests := newWatchOptions(t, api)
+	var out lockedBuffer
+	l.Out = &out
+
+	// the follow ends once the watch ended, so the stream of the deleted pod ended too instead of waiting for
+	// a restart
+	followSelected(t, l, requests)
+	if got, want := out.String(), "ERROR one\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := len(api.requests("/namespaces/test/pods/api-1/log")); got != 1 {
+		t.Errorf("got %d log requests, want the stream of the deleted pod not to be reopened", got)
+	}
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// matchWatchdog matches the lines of a stream in a goroutine of its own, so that a line taking longer than
+// --match-timeout to match can be skipped. A match cannot be interrupted, so the one of a skipped line goes on in
+// the background, and the next lines are skipped without being matched until it is done: at most one match runs
+// behind the stream.
+type matchWatchdog struct {
+	timeout time.Duration
+	errOut  io.Writer
+	lines   chan []byte
+	results chan bool
+	// timer is reused for every line
+	timer *time.Timer
+	// busy tells whether the match of a skipped line still runs, and skipped counts the lines skipped meanwhile
+	busy    bool
+	skipped int
+}
+
+// newMatchWatchdog returns a watchdog matching the lines with match, to close once the stream is read
+func newMatchWatchdog(timeout time.Duration, match func([]byte) bool, errOut io.Writer) *matchWatchdog {
+	w := &matchWatchdog{
+		timeout: timeout,
+		errOut:  errOut,
+		lines:   make(chan []byte),
+		// a skipped match leaves its result without blocking
+		results: make(chan bool, 1),
+		timer:   time.NewTimer(timeout),
+	}
+	if !w.timer.Stop() {
+		<-w.timer.C
+	}
+	go func() {
+		for line := range w.lines {
+			w.results <- match(line)
+		}
+	}()
+	return w
+}
+
+// match reports whether the line matches, or that it was skipped because matching it took longer than the
+// timeout or the match of a skipped line still runs
+func (w *matchWatchdog) match(line []byte) (matched, skipped bool) {
+	if w.busy {
+		select {
+		case <-w.results:
+			w.busy = false
+			w.reportSkipped()
+		default:
+			w.skipped++
+			return false, true
+		}
+	}
+	w.lines <- line
+	w.timer.Reset(w.timeout)
+	select {
+	case matched := <-w.results:
+		if !w.timer.Stop() {
+			<-w.timer.C
+		}
+		return matched, false
+	case <-w.timer.C:
+		fmt.Fprintf(w.errOut, "warning: skipped a line of %d bytes, matching it took longer than --match-timeout=%s\n", len(line), w.timeout)
+		w.busy = true
+		return false, true
+	}
+}
+
+// reportSkipped warns about the lines skipped while the match of a skipped line ran
+func (w *matchWatchdog) reportSkipped() {
+	if w.skipped > 0 {
+		fmt.Fprintf(w.errOut, "warning: skipped %d more line(s) while matching a slow line\n", w.skipped)
+		w.skipped = 0
+	}
+}
+
+// Close stops the goroutine matching the lines once its last match is done. It is a no-op on a nil watchdog.
+func (w *matchWatchdog) Close() {
+	if w == nil {
+		return
+	}
+	close(w.lines)
+	w.timer.Stop()
+	w.reportSkipped()
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+)
+
+// waitForSkippedMatch waits until the match of the skipped line is done, leaving its result to the watchdog
+func waitForSkippedMatch(w *matchWatchdog) {
+	w.results <- <-w.results
+}
+
+func TestMatchWatchdogSkipsSlowLines(t *testing.T) {
+	release := make(chan struct{})
+	var matches []string
+	match := func(line []byte) bool {
+		if string(line) == "slow" {
+			<-release
+		}
+		matches = append(matches, string(line))
+		return bytes.Contains(line, []byte("ERROR"))
+	}
+	var errOut bytes.Buffer
+	w := newMatchWatchdog(time.Millisecond, match, &errOut)
+
+	if matched, skipped := w.match([]byte("slow")); matched || !skipped {
+		t.Errorf("got matched %v and skipped %v for the slow line, want it skipped", matched, skipped)
+	}
+	// the match of the slow line still runs, so the next lines are skipped without another one
+	for _, line := range []string{"ERROR one", "ERROR two"} {
+		if matched, skipped := w.match([]byte(line)); matched || !skipped {
+			t.Errorf("got matched %v and skipped %v for %q, want it skipped", matched, skipped, line)
+		}
+	}
+	close(release)
+	waitForSkippedMatch(w)
+	if matched, skipped := w.match([]byte("ERROR three")); !matched || skipped {
+		t.Errorf("got matched %v and skipped %v once the slow line was matched, want a match", matched, skipped)
+	}
+	w.Close()
+
+	if got, want := matches, []string{"slow", "ERROR three"}; !slices.Equal(got, want) {
+		t.Errorf("got matches of %q, want %q", got, want)
+	}
+	want := "warning: skipped a line of 4 bytes, matching it took longer than --match-timeout=1ms\n" +
+		"warning: skipped 2 more line(s) while matching a slow line\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("got warnings %q, want %q", got, want)
+	}
+}
+
+func TestNilMatchWatchdogMatchesInline(t *testing.T) {
+	l, _ := newOutputOptions(t, outputText, "ERROR", map[string]string{"api-1": ""})
+	var w *matchWatchdog
+	if matched, skipped := l.matchLineWithin(w, []byte("ERROR boom\n")); !matched || skipped {
+		t.Errorf("got matched %v and skipped %v, want a match", matched, skipped)
+	}
+	w.Close()
+}
@@ -0,0 +1,293 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// webhookRetries is the number of times a batch is sent again after a failure before it is dropped
+	webhookRetries = 3
+	// webhookBackoff is the delay before the first retry, doubled before every next one
+	webhookBackoff = time.Second
+	webhookTimeout = 10 * time.Second
+	// defaultWebhookBatch sends every matching line on its own
+	defaultWebhookBatch = "1"
+	// notifyQueueSize is the number of matching lines waiting to be sent before the next ones are dropped
+	notifyQueueSize = 1000
+)
+
+// notifier sends a batch of matching lines somewhere, e.g. to a webhook
+type notifier interface {
+	Notify(ctx context.Context, payload notifyPayload) error
+}
+
+// notification is a matching line sent by --webhook or --loki-url
+type notification struct {
+	Context   string `json:"context,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Line      string `json:"line"`
+	Pattern   string `json:"pattern"`
+	// source counts the lines that could not be sent in --stats
+	source string
+}
+
+// notifyPayload is the body of a --webhook request, and the data of --webhook-template
+type notifyPayload struct {
+	Matches []notification `json:"matches"`
+	// Text is every matching line prefixed with its pod and container, one per line, e.g. for Slack
+	Text string `json:"-"`
+}
+
+func newNotifyPayload(matches []notification) notifyPayload {
+	var text strings.Builder
+	for _, match := range matches {
+		fmt.Fprintf(&text, "%s/%s: %s\n", match.Pod, match.Container, match.Line)
+	}
+	return notifyPayload{Matches: matches, Text: strings.TrimSuffix(text.String(), "\n")}
+}
+
+// webhookNotifier POSTs the matching lines to a URL, as JSON or formatted with a template
+type webhookNotifier struct {
+	url      string
+	template *template.Template
+	client   *http.Client
+}
+
+// newWebhookNotifier checks the URL of --webhook and parses --webhook-template, if any
+func (l LikeOptions) newWebhookNotifier() (*webhookNotifier, error) {
+	u, err := url.Parse(l.Webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --webhook %q, must be an http or https URL", l.Webhook)
+	}
+	n := &webhookNotifier{url: l.Webhook, client: &http.Client{Timeout: webhookTimeout}}
+	if len(l.WebhookTemplate) > 0 {
+		funcs := l.templateFuncs()
+		// json quotes a value for a JSON body, e.g. {"text": {{json .Text}}}
+		funcs["json"] = func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		}
+		n.template, err = template.New("webhook").Funcs(funcs).Option("missingkey=error").Parse(l.WebhookTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --webhook-template: %w", err)
+		}
+	}
+	return n, nil
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, payload notifyPayload) error {
+	var body bytes.Buffer
+	if n.template != nil {
+		if err := n.template.Execute(&body, payload); err != nil {
+			return fmt.Errorf("error executing --webhook-template: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyBatch is the value of --webhook-batch: a batch is sent once it has size lines, or interval after
+// its first line when interval is set
+type notifyBatch struct {
+	size     int
+	interval time.Duration
+}
+
+// parseNotifyBatch parses --webhook-batch, given as SIZE or SIZE/INTERVAL, e.g. 10/5s
+func parseNotifyBatch(s string) (notifyBatch, error) {
+	size, interval, found := strings.Cut(s, "/")
+	var batch notifyBatch
+	var err error
+	batch.size, err = strconv.Atoi(size)
+	if err != nil || batch.size < 1 {
+		return batch, fmt.Errorf("invalid --webhook-batch %q, must be SIZE or SIZE/INTERVAL, e.g. 10/5s", s)
+	}
+	if found {
+		batch.interval, err = time.ParseDuration(interval)
+		if err != nil || batch.interval <= 0 {
+			return batch, fmt.Errorf("invalid --webhook-batch %q, must be SIZE or SIZE/INTERVAL, e.g. 10/5s", s)
+		}
+	}
+	return batch, nil
+}
+
+// notifySink queues the matching lines and sends them in batches with a notifier, so that a slow or failing
+// endpoint does not block the streams. A failed batch is sent again with backoff, then dropped and counted
+// in --stats, like the lines dropped when the queue is full.
+type notifySink struct {
+	notifier notifier
+	// flag names the notifier in the errors and in --stats, e.g. --webhook
+	flag    string
+	batch   notifyBatch
+	backoff time.Duration
+	errOut  io.Writer
+	stats   *matchCounts
+	queue   chan notification
+	done    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	warned bool
+}
+
+func newNotifySink(n notifier, flag string, batch notifyBatch, backoff time.Duration, errOut io.Writer, stats *matchCounts) *notifySink {
+	s := &notifySink{
+		notifier: n,
+		flag:     flag,
+		batch:    batch,
+		backoff:  backoff,
+		errOut:   errOut,
+		stats:    stats,
+		queue:    make(chan notification, notifyQueueSize),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *notifySink) run() {
+	defer close(s.done)
+	var batch []notification
+	var timeout <-chan time.Time
+	for {
+		select {
+		case n, ok := <-s.queue:
+			if !ok {
+				s.send(batch)
+				return
+			}
+			batch = append(batch, n)
+			if len(batch) >= s.batch.size {
+				s.send(batch)
+				batch, timeout = nil, nil
+			} else if len(batch) == 1 && s.batch.interval > 0 {
+				timeout = time.After(s.batch.interval)
+			}
+		case <-timeout:
+			s.send(batch)
+			batch, timeout = nil, nil
+		}
+	}
+}
+
+// send sends the batch, retrying with backoff, and counts its lines in --stats when it is dropped
+func (s *notifySink) send(batch []notification) {
+	if len(batch) == 0 {
+		return
+	}
+	payload := newNotifyPayload(batch)
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		err := s.notifier.Notify(context.Background(), payload)
+		if err == nil {
+			return
+		}
+		if attempt == webhookRetries {
+			fmt.Fprintf(s.errOut, "error: dropped %d matching lines after %d failed %s requests: %v\n", len(batch), attempt+1, s.flag, err)
+			s.drop(batch)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *notifySink) drop(batch []notification) {
+	if s.stats == nil {
+		return
+	}
+	for _, n := range batch {
+		s.stats.dropUnsent(n.source, s.flag, 1)
+	}
+}
+
+// enqueue queues the line, or drops it when the queue is full
+func (s *notifySink) enqueue(n notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- n:
+	default:
+		if !s.warned {
+			s.warned = true
+			fmt.Fprintf(s.errOut, "warning: %s is too slow, dropping the matching lines beyond the %d waiting to be sent\n", s.flag, notifyQueueSize)
+		}
+		s.drop([]notification{n})
+	}
+}
+
+// Close stops accepting lines and waits for the queued ones to be sent
+func (s *notifySink) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// writer returns a writer sending every line written to w, the line without its timestamp
+// if timestamps is set
+func (s *notifySink) writer(source notification, timestamps bool, w io.Writer) io.Writer {
+	return &notifyWriter{sink: s, source: source, timestamps: timestamps, writer: w}
+}
+
+type notifyWriter struct {
+	sink       *notifySink
+	source     notification
+	timestamps bool
+	writer     io.Writer
+}
+
+func (nw *notifyWriter) Write(p []byte) (int, error) {
+	n, err := nw.writer.Write(p)
+	for _, line := range bytes.SplitAfter(p[:n], []byte("\n")) {
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		notification := nw.source
+		if nw.timestamps {
+			if t, rest, ok := splitTimestamp(line); ok {
+				notification.Timestamp = t.Format(time.RFC3339Nano)
+				line = rest
+			}
+		}
+		notification.Line = string(line)
+		nw.sink.enqueue(notification)
+	}
+	return n, err
+}
@@ -0,0 +1,228 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer records the bodies it receives, failing the first failures requests with a 500
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   []string
+	requests int
+	failures int
+}
+
+func newWebhookServer(t *testing.T, failures int) *webhookServer {
+	t.Helper()
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got a %s request with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if s.requests <= s.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.bodies = append(s.bodies, string(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// batches returns the lines of every payload received
+func (s *webhookServer) batches(t *testing.T) [][]string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var batches [][]string
+	for _, body := range s.bodies {
+		var payload notifyPayload
+		if err := json.Unmarshal([]byte(body), &payload); err != nil {
+			t.Fatalf("invalid payload %q: %v", body, err)
+		}
+		var lines []string
+		for _, match := range payload.Matches {
+			lines = append(lines, match.Line)
+		}
+		batches = append(batches, lines)
+	}
+	return batches
+}
+
+func newTestNotifier(t *testing.T, url, template string) notifier {
+	t.Helper()
+	l, _, _ := newFakeOptions(t, newPodAPI("api-1"))
+	l.Webhook = url
+	l.WebhookTemplate = template
+	n, err := l.newWebhookNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWebhookPayload(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	logs := map[string]string{"api-1": "2024-06-12T10:04:05Z INFO starting\n2024-06-12T10:04:06Z ERROR boom\n"}
+	l, requests := newOutputOptions(t, outputText, "ERROR", logs)
+	l.Pattern = "ERROR"
+	l.Timestamps = true
+	sink := newNotifySink(newTestNotifier(t, server.URL, ""), "--webhook", notifyBatch{size: 1}, time.Millisecond, io.Discard, nil)
+	l.notifySinks = []*notifySink{sink}
+	var out bytes.Buffer
+	l.Out = &out
+
+	if err := l.sequentialConsumeRequest(requests); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.bodies) != 1 {
+		t.Fatalf("got %d requests, want 1", len(server.bodies))
+	}
+	want := `{"matches":[{"namespace":"test","pod":"api-1","container":"app","timestamp":"2024-06-12T10:04:06Z","line":"ERROR boom","pattern":"ERROR"}]}` + "\n"
+	if server.bodies[0] != want {
+		t.Errorf("got %s, want %s", server.bodies[0], want)
+	}
+	if out.String() != "2024-06-12T10:04:06Z ERROR boom\n" {
+		t.Errorf("the matching line is not printed: %q", out.String())
+	}
+}
+
+func TestWebhookBatchSize(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	s := newNotifySink(newTestNotifier(t, server.URL, ""), "--webhook", notifyBatch{size: 2, interval: time.Minute}, time.Millisecond, io.Discard, nil)
+	io.WriteString(s.writer(notification{Pod: "api-1"}, false, io.Discard), "one\ntwo\nthree\n")
+	s.Close()
+
+	// the last batch is sent on Close
+	got := server.batches(t)
+	if len(got) != 2 || strings.Join(got[0], ",") != "one,two" || strings.Join(got[1], ",") != "three" {
+		t.Errorf("got batches %q, want [one two] [three]", got)
+	}
+}
+
+func TestWebhookBatchInterval(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	s := newNotifySink(newTestNotifier(t, server.URL, ""), "--webhook", notifyBatch{size: 10, interval: 20 * time.Millisecond}, time.Millisecond, io.Discard, nil)
+	defer s.Close()
+	io.WriteString(s.writer(notification{Pod: "api-1"}, false, io.Discard), "one\ntwo\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.batches(t)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := server.batches(t); len(got) != 1 || strings.Join(got[0], ",") != "one,two" {
+		t.Errorf("got batches %q, want [one two] once the interval elapsed", got)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server := newWebhookServer(t, 2)
+	var errOut bytes.Buffer
+	s := newNotifySink(newTestNotifier(t, server.URL, ""), "--webhook", notifyBatch{size: 1}, time.Millisecond, &errOut, nil)
+	io.WriteString(s.writer(notification{Pod: "api-1"}, false, io.Discard), "ERROR boom\n")
+	s.Close()
+
+	if got := server.batches(t); len(got) != 1 || got[0][0] != "ERROR boom" {
+		t.Errorf("got batches %q, want the line once", got)
+	}
+	if server.requests != 3 {
+		t.Errorf("got %d requests, want 2 failures and a success", server.requests)
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("unexpected stderr %q", errOut.String())
+	}
+}
+
+func TestWebhookDropsAfterRetries(t *testing.T) {
+	server := newWebhookServer(t, 100)
+	var errOut, statsOut bytes.Buffer
+	stats := newMatchCounts("container", &statsOut)
+	s := newNotifySink(newTestNotifier(t, server.URL, ""), "--webhook", notifyBatch{size: 2}, time.Millisecond, &errOut, stats)
+	w := stats.writer("test/api-1/app", s.writer(notification{Pod: "api-1", source: "test/api-1/app"}, false, io.Discard))
+	io.WriteString(w, "one\ntwo\n")
+	s.Close()
+	stats.Print()
+
+	if server.requests != webhookRetries+1 {
+		t.Errorf("got %d requests, want %d", server.requests, webhookRetries+1)
+	}
+	if want := "error: dropped 2 matching lines after 4 failed --webhook requests: the webhook returned 500 Internal Server Error"; !strings.Contains(errOut.String(), want) {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+	if want := "test/api-1/app: 2 (2 not sent to --webhook)"; !strings.Contains(statsOut.String(), want) {
+		t.Errorf("got stats %q, want %q", statsOut.String(), want)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	s := newNotifySink(newTestNotifier(t, server.URL, `{"text": {{json .Text}}}`), "--webhook", notifyBatch{size: 2}, time.Millisecond, io.Discard, nil)
+	io.WriteString(s.writer(notification{Pod: "api-1", Container: "app"}, false, io.Discard), "ERROR \"quoted\"\nERROR again\n")
+	s.Close()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	want := `{"text": "api-1/app: ERROR \"quoted\"\napi-1/app: ERROR again"}`
+	if len(server.bodies) != 1 || server.bodies[0] != want {
+		t.Errorf("got %q, want %q", server.bodies, want)
+	}
+}
+
+func TestParseNotifyBatch(t *testing.T) {
+	for value, want := range map[string]notifyBatch{
+		"1":     {size: 1},
+		"10/5s": {size: 10, interval: 5 * time.Second},
+	} {
+		got, err := parseNotifyBatch(value)
+		if err != nil || got != want {
+			t.Errorf("parseNotifyBatch(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "ten", "10/", "10/0s", "10/soon"} {
+		if _, err := parseNotifyBatch(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestWebhookValidation(t *testing.T) {
+	for _, flags := range [][]string{
+		{"--webhook", "hooks.example.com/like"},
+		{"--webhook", "http://hooks.example.com", "--webhook-batch", "10/never"},
+		{"--webhook", "http://hooks.example.com", "--webhook-template", "{{.Nope"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err == nil {
+			t.Errorf("expected %v to be rejected", flags)
+		}
+	}
+	for _, flags := range [][]string{
+		{"--webhook-batch", "10/5s"},
+		{"--webhook-template", "{{json .Text}}"},
+	} {
+		l, cmd, _, _ := newFakeCommand(t, newPodAPI("api-1"))
+		if err := completeFlags(l, cmd, flags, "api-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Vaildate(); err == nil {
+			t.Errorf("expected %v without --webhook to be rejected", flags)
+		}
+	}
+}